@@ -9,6 +9,7 @@ import (
 // AsyncResultService creates a new async result get service
 type AsyncResultService struct {
 	client *Client
+	extras serviceExtras
 
 	id string
 }
@@ -21,6 +22,8 @@ type AsyncResultVideo struct {
 
 // AsyncResultResponse is the response of the AsyncResultService
 type AsyncResultResponse struct {
+	RawResponse
+
 	Model       string             `json:"model"`
 	TaskStatus  string             `json:"task_status"`
 	RequestID   string             `json:"request_id"`
@@ -41,7 +44,33 @@ func (s *AsyncResultService) SetID(id string) *AsyncResultService {
 	return s
 }
 
-func (s *AsyncResultService) Do(ctx context.Context) (res AsyncResultResponse, err error) {
+// SetExtraBody sets extra fields of the AsyncResult request, see WithCallBody
+func (s *AsyncResultService) SetExtraBody(body M) *AsyncResultService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the AsyncResult request
+func (s *AsyncResultService) SetExtraHeaders(headers map[string]string) *AsyncResultService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the AsyncResult request locally, see ValidationError
+func (s *AsyncResultService) Validate() error {
+	v := newValidator("AsyncResult")
+	v.required("id", s.id)
+	return v.err()
+}
+
+func (s *AsyncResultService) Do(ctx context.Context, opts ...CallOption) (res AsyncResultResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "AsyncResult", Method: "GET", Path: "async-result/" + s.id}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *AsyncResultService) do(ctx context.Context, op *Operation) (res AsyncResultResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -26,6 +26,8 @@ type BatchRequestCounts struct {
 
 // BatchItem represents a batch item.
 type BatchItem struct {
+	RawResponse
+
 	ID               string             `json:"id"`
 	Object           any                `json:"object"`
 	Endpoint         string             `json:"endpoint"`
@@ -50,6 +52,7 @@ type BatchItem struct {
 // BatchCreateService is a service to create a batch.
 type BatchCreateService struct {
 	client *Client
+	extras serviceExtras
 
 	inputFileID      string
 	endpoint         string
@@ -86,20 +89,48 @@ func (s *BatchCreateService) SetMetadata(metadata any) *BatchCreateService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the BatchCreate request, see WithCallBody
+func (s *BatchCreateService) SetExtraBody(body M) *BatchCreateService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the BatchCreate request
+func (s *BatchCreateService) SetExtraHeaders(headers map[string]string) *BatchCreateService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the BatchCreate request locally, see ValidationError
+func (s *BatchCreateService) Validate() error {
+	v := newValidator("BatchCreate")
+	v.required("input_file_id", s.inputFileID)
+	v.oneOf("endpoint", s.endpoint, BatchEndpointV4ChatCompletions, BatchEndpointV4ImagesGenerations, BatchEndpointV4Embeddings, BatchEndpointV4VideosGenerations)
+	v.oneOf("completion_window", s.completionWindow, BatchCompletionWindow24h)
+	return v.err()
+}
+
 // Do executes the batch create service.
-func (s *BatchCreateService) Do(ctx context.Context) (res BatchItem, err error) {
+func (s *BatchCreateService) Do(ctx context.Context, opts ...CallOption) (res BatchItem, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "BatchCreate", Method: "POST", Path: "batches", Body: M{
+		"input_file_id":     s.inputFileID,
+		"endpoint":          s.endpoint,
+		"completion_window": s.completionWindow,
+		"metadata":          s.metadata,
+	}}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *BatchCreateService) do(ctx context.Context, op *Operation) (res BatchItem, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
 	)
 
 	if resp, err = s.client.request(ctx).
-		SetBody(M{
-			"input_file_id":     s.inputFileID,
-			"endpoint":          s.endpoint,
-			"completion_window": s.completionWindow,
-			"metadata":          s.metadata,
-		}).
+		SetBody(op.Body).
 		SetResult(&res).
 		SetError(&apiError).
 		Post("batches"); err != nil {
@@ -116,6 +147,7 @@ func (s *BatchCreateService) Do(ctx context.Context) (res BatchItem, err error)
 // BatchGetService is a service to get a batch.
 type BatchGetService struct {
 	client  *Client
+	extras  serviceExtras
 	batchID string
 }
 
@@ -133,8 +165,34 @@ func (s *BatchGetService) SetBatchID(batchID string) *BatchGetService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the BatchGet request, see WithCallBody
+func (s *BatchGetService) SetExtraBody(body M) *BatchGetService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the BatchGet request
+func (s *BatchGetService) SetExtraHeaders(headers map[string]string) *BatchGetService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the BatchGet request locally, see ValidationError
+func (s *BatchGetService) Validate() error {
+	v := newValidator("BatchGet")
+	v.required("batch_id", s.batchID)
+	return v.err()
+}
+
 // Do executes the batch get service.
-func (s *BatchGetService) Do(ctx context.Context) (res BatchGetResponse, err error) {
+func (s *BatchGetService) Do(ctx context.Context, opts ...CallOption) (res BatchGetResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "BatchGet", Method: "GET", Path: "batches/" + s.batchID}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *BatchGetService) do(ctx context.Context, op *Operation) (res BatchGetResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -158,6 +216,7 @@ func (s *BatchGetService) Do(ctx context.Context) (res BatchGetResponse, err err
 // BatchCancelService is a service to cancel a batch.
 type BatchCancelService struct {
 	client  *Client
+	extras  serviceExtras
 	batchID string
 }
 
@@ -172,8 +231,34 @@ func (s *BatchCancelService) SetBatchID(batchID string) *BatchCancelService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the BatchCancel request, see WithCallBody
+func (s *BatchCancelService) SetExtraBody(body M) *BatchCancelService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the BatchCancel request
+func (s *BatchCancelService) SetExtraHeaders(headers map[string]string) *BatchCancelService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the BatchCancel request locally, see ValidationError
+func (s *BatchCancelService) Validate() error {
+	v := newValidator("BatchCancel")
+	v.required("batch_id", s.batchID)
+	return v.err()
+}
+
 // Do executes the batch cancel service.
-func (s *BatchCancelService) Do(ctx context.Context) (err error) {
+func (s *BatchCancelService) Do(ctx context.Context, opts ...CallOption) (err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invokeNoResult(ctx, s.client, &Operation{Name: "BatchCancel", Method: "POST", Path: "batches/" + s.batchID + "/cancel", Body: M{}}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *BatchCancelService) do(ctx context.Context, op *Operation) (err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -181,7 +266,7 @@ func (s *BatchCancelService) Do(ctx context.Context) (err error) {
 
 	if resp, err = s.client.request(ctx).
 		SetPathParam("batch_id", s.batchID).
-		SetBody(M{}).
+		SetBody(op.Body).
 		SetError(&apiError).
 		Post("batches/{batch_id}/cancel"); err != nil {
 		return
@@ -197,6 +282,7 @@ func (s *BatchCancelService) Do(ctx context.Context) (err error) {
 // BatchListService is a service to list batches.
 type BatchListService struct {
 	client *Client
+	extras serviceExtras
 
 	after *string
 	limit *int
@@ -204,6 +290,8 @@ type BatchListService struct {
 
 // BatchListResponse represents the response of the batch list service.
 type BatchListResponse struct {
+	RawResponse
+
 	Object  string      `json:"object"`
 	Data    []BatchItem `json:"data"`
 	FirstID string      `json:"first_id"`
@@ -228,8 +316,34 @@ func (s *BatchListService) SetLimit(limit int) *BatchListService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the BatchList request, see WithCallBody
+func (s *BatchListService) SetExtraBody(body M) *BatchListService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the BatchList request
+func (s *BatchListService) SetExtraHeaders(headers map[string]string) *BatchListService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the BatchList request locally, see ValidationError
+func (s *BatchListService) Validate() error {
+	v := newValidator("BatchList")
+	v.min("limit", s.limit, 1)
+	return v.err()
+}
+
 // Do executes the batch list service.
-func (s *BatchListService) Do(ctx context.Context) (res BatchListResponse, err error) {
+func (s *BatchListService) Do(ctx context.Context, opts ...CallOption) (res BatchListResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "BatchList", Method: "GET", Path: "batches"}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *BatchListService) do(ctx context.Context, op *Operation) (res BatchListResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
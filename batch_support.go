@@ -2,7 +2,9 @@ package zhipu
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
 )
 
 // BatchSupport is the interface for services with batch support.
@@ -39,6 +41,11 @@ type BatchResultResponse[T any] struct {
 	Body       T   `json:"body"`
 }
 
+// succeeded returns true if the request of the batch result succeeded.
+func (r BatchResultResponse[T]) succeeded() bool {
+	return r.StatusCode >= http.StatusOK && r.StatusCode < http.StatusMultipleChoices
+}
+
 // BatchResult is the result of a batch.
 type BatchResult[T any] struct {
 	ID       string                 `json:"id"`
@@ -50,6 +57,9 @@ type BatchResult[T any] struct {
 type BatchResultReader[T any] struct {
 	r  io.Reader
 	jd *json.Decoder
+
+	usage *UsageTracker
+	tags  []string
 }
 
 // NewBatchResultReader creates a new BatchResultReader.
@@ -57,7 +67,73 @@ func NewBatchResultReader[T any](r io.Reader) *BatchResultReader[T] {
 	return &BatchResultReader[T]{r: r, jd: json.NewDecoder(r)}
 }
 
+// SetUsageTracker records each successful result read in the tracker with the tags, like the UsageTracker of a client,
+// results of ChatCompletionResponse and EmbeddingResponse are recorded, see UsageTracker.RecordResponse
+func (r *BatchResultReader[T]) SetUsageTracker(tracker *UsageTracker, tags ...string) *BatchResultReader[T] {
+	r.usage = tracker
+	r.tags = tags
+	return r
+}
+
 // Read reads a batch result.
 func (r *BatchResultReader[T]) Read(out *BatchResult[T]) error {
+	if err := r.jd.Decode(out); err != nil {
+		return err
+	}
+	if r.usage != nil {
+		r.usage.RecordResponse(out, "", r.tags...)
+	}
+	return nil
+}
+
+// BatchRequest is a line of a batch file.
+type BatchRequest struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// BatchRequestReader reads the lines of a batch file, as written by BatchFileWriter.
+type BatchRequestReader struct {
+	r  io.Reader
+	jd *json.Decoder
+}
+
+// NewBatchRequestReader creates a new BatchRequestReader.
+func NewBatchRequestReader(r io.Reader) *BatchRequestReader {
+	return &BatchRequestReader{r: r, jd: json.NewDecoder(r)}
+}
+
+// Read reads a batch request.
+func (r *BatchRequestReader) Read(out *BatchRequest) error {
 	return r.jd.Decode(out)
 }
+
+// BatchService rebuilds the service of a batch request with FromBody, the service is chosen by the url.
+func (c *Client) BatchService(req BatchRequest) (s BatchSupport, err error) {
+	switch req.URL {
+	case BatchEndpointV4ChatCompletions:
+		svc := c.ChatCompletion("")
+		err = svc.FromBody(req.Body)
+		s = svc
+	case BatchEndpointV4Embeddings:
+		svc := c.Embedding("")
+		err = svc.FromBody(req.Body)
+		s = svc
+	case BatchEndpointV4ImagesGenerations:
+		svc := c.ImageGeneration("")
+		err = svc.FromBody(req.Body)
+		s = svc
+	case BatchEndpointV4VideosGenerations:
+		svc := c.VideoGeneration("")
+		err = svc.FromBody(req.Body)
+		s = svc
+	default:
+		err = fmt.Errorf("zhipu: unknown batch url %q", req.URL)
+	}
+	if err != nil {
+		s = nil
+	}
+	return
+}
@@ -9,7 +9,7 @@ import (
 )
 
 func TestBatchFileWriter(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	buf := &bytes.Buffer{}
@@ -9,7 +9,7 @@ import (
 )
 
 func TestBatchServiceAll(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	buf := &bytes.Buffer{}
@@ -50,7 +50,7 @@ func TestBatchServiceAll(t *testing.T) {
 }
 
 func TestBatchListService(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	res, err := client.BatchList().Do(context.Background())
@@ -0,0 +1,117 @@
+package zhipu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// bodyFields is a json request body being decoded into a service, field by field
+type bodyFields map[string]json.RawMessage
+
+// decodeBodyFields decodes a json request body
+func decodeBodyFields(data []byte) (fields bodyFields, err error) {
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return
+	}
+	if fields == nil {
+		fields = bodyFields{}
+	}
+	return
+}
+
+// take decodes the field into out and removes it, ok is false if the field is missing or null
+func (f bodyFields) take(key string, out any) (ok bool, err error) {
+	raw, found := f[key]
+	if !found {
+		return
+	}
+	delete(f, key)
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return
+	}
+	if err = json.Unmarshal(raw, out); err != nil {
+		err = fmt.Errorf("zhipu: failed to decode field %s: %w", key, err)
+		return
+	}
+	ok = true
+	return
+}
+
+// rest returns the fields not taken, nil if none
+func (f bodyFields) rest() (extra M, err error) {
+	if len(f) == 0 {
+		return
+	}
+	extra = M{}
+	for k, raw := range f {
+		var v any
+		if err = json.Unmarshal(raw, &v); err != nil {
+			return
+		}
+		extra[k] = v
+	}
+	return
+}
+
+// DecodeChatCompletionMessage decodes a json message into ChatCompletionMessage, or ChatCompletionMultiMessage if the content is an array
+func DecodeChatCompletionMessage(data []byte) (msg ChatCompletionMessageType, err error) {
+	var probe struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if err = json.Unmarshal(data, &probe); err != nil {
+		return
+	}
+	if content := bytes.TrimSpace(probe.Content); len(content) > 0 && content[0] == '[' {
+		var multi ChatCompletionMultiMessage
+		err = json.Unmarshal(data, &multi)
+		msg = multi
+		return
+	}
+	var single ChatCompletionMessage
+	err = json.Unmarshal(data, &single)
+	msg = single
+	return
+}
+
+// DecodeChatCompletionTool decodes a json tool like {"type":"function","function":{...}} into its concrete type
+func DecodeChatCompletionTool(data []byte) (tool ChatCompletionTool, err error) {
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return
+	}
+	var typ string
+	if err = json.Unmarshal(fields["type"], &typ); err != nil {
+		err = fmt.Errorf("zhipu: failed to decode tool type: %w", err)
+		return
+	}
+	raw := fields[typ]
+	if raw == nil {
+		raw = json.RawMessage("{}")
+	}
+	switch typ {
+	case ToolTypeFunction:
+		var t ChatCompletionToolFunction
+		err = json.Unmarshal(raw, &t)
+		tool = t
+	case ToolTypeRetrieval:
+		var t ChatCompletionToolRetrieval
+		err = json.Unmarshal(raw, &t)
+		tool = t
+	case ToolTypeWebSearch:
+		var t ChatCompletionToolWebSearch
+		err = json.Unmarshal(raw, &t)
+		tool = t
+	case ToolTypeCodeInterpreter:
+		var t ChatCompletionToolCodeInterpreter
+		err = json.Unmarshal(raw, &t)
+		tool = t
+	case ToolTypeDrawingTool:
+		tool = ChatCompletionToolDrawingTool{}
+	case ToolTypeWebBrowser:
+		tool = ChatCompletionToolWebBrowser{}
+	default:
+		err = fmt.Errorf("zhipu: unknown tool type %q", typ)
+	}
+	return
+}
@@ -0,0 +1,164 @@
+package zhipu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeChatCompletionMessage(t *testing.T) {
+	msg, err := DecodeChatCompletionMessage([]byte(`{"role":"user","content":"hello"}`))
+	require.NoError(t, err)
+	require.Equal(t, NewUserMessage("hello"), msg)
+
+	msg, err = DecodeChatCompletionMessage([]byte(`{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{}"}}]}`))
+	require.NoError(t, err)
+	require.Equal(t, "call_1", msg.(ChatCompletionMessage).ToolCalls[0].ID)
+
+	msg, err = DecodeChatCompletionMessage([]byte(`{"role":"user","content":[{"type":"text","text":"what"},{"type":"image_url","image_url":{"url":"https://a/b.png"}}]}`))
+	require.NoError(t, err)
+	multi, ok := msg.(ChatCompletionMultiMessage)
+	require.True(t, ok)
+	require.Equal(t, "https://a/b.png", multi.Content[1].ImageURL.URL)
+}
+
+func TestDecodeChatCompletionTool(t *testing.T) {
+	tool, err := DecodeChatCompletionTool([]byte(`{"type":"function","function":{"name":"f","description":"d","parameters":{"type":"object"}}}`))
+	require.NoError(t, err)
+	require.Equal(t, ChatCompletionToolFunction{Name: "f", Description: "d", Parameters: map[string]any{"type": "object"}}, tool)
+
+	tool, err = DecodeChatCompletionTool([]byte(`{"type":"retrieval","retrieval":{"knowledge_id":"k"}}`))
+	require.NoError(t, err)
+	require.Equal(t, ChatCompletionToolRetrieval{KnowledgeID: "k"}, tool)
+
+	tool, err = DecodeChatCompletionTool([]byte(`{"type":"web_browser"}`))
+	require.NoError(t, err)
+	require.Equal(t, ChatCompletionToolWebBrowser{}, tool)
+
+	_, err = DecodeChatCompletionTool([]byte(`{"type":"unknown"}`))
+	require.Error(t, err)
+}
+
+func TestChatCompletionServiceFromBody(t *testing.T) {
+	client, err := NewClient(WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	image, err := NewChatCompletionMultiMessageBuilder(RoleUser).Text("what").ImageURL("https://a/b.png").Build()
+	require.NoError(t, err)
+
+	s := client.ChatCompletion("glm-4").
+		SetRequestID("req-1").
+		SetDoSample(true).
+		SetTemperature(0.5).
+		SetTopP(0.7).
+		SetMaxTokens(100).
+		SetStop("a", "b").
+		SetToolChoice("auto").
+		SetUserID("user-1").
+		SetMeta(ChatCompletionMeta{UserName: "u", BotName: "b"}).
+		SetResponseFormat("json_object").
+		SetThinkingMode("disabled").
+		SetSensitiveWordCheck(SensitiveWordCheckDisable).
+		SetExtraBody(M{"custom": "value"}).
+		AddMessage(NewSystemMessage("be nice"), image).
+		AddTool(
+			ChatCompletionToolFunction{Name: "f", Parameters: map[string]any{"type": "object"}},
+			ChatCompletionToolWebSearch{SearchQuery: "q"},
+		)
+
+	buf, err := json.Marshal(s.BatchBody())
+	require.NoError(t, err)
+
+	s2 := client.ChatCompletion("")
+	require.NoError(t, json.Unmarshal(buf, s2))
+
+	buf2, err := json.Marshal(s2.BatchBody())
+	require.NoError(t, err)
+	require.JSONEq(t, string(buf), string(buf2))
+	require.Equal(t, M{"custom": "value"}, s2.extras.body)
+	require.Equal(t, image, s2.messages[1])
+}
+
+func TestChatCompletionServiceFromBodyUnknownTool(t *testing.T) {
+	s := NewChatCompletionService(nil)
+	err := s.FromBody([]byte(`{"model":"glm-4","stream":true,"messages":[{"role":"user","content":"hi"}],"tools":[{"type":"future","future":{"a":1}}]}`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"type": "future", "future": map[string]any{"a": float64(1)}}, s.tools[0])
+	require.Nil(t, s.extras.body)
+
+	require.Error(t, s.FromBody([]byte(`{"model":1}`)))
+}
+
+func TestServicesFromBody(t *testing.T) {
+	client, err := NewClient(WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	for _, item := range []struct {
+		s   BatchSupport
+		out BatchSupport
+	}{
+		{client.Embedding("embedding-2").SetInput("hello"), client.Embedding("")},
+		{client.ImageGeneration("cogview-3").SetPrompt("cat").SetUserID("u"), client.ImageGeneration("")},
+		{client.VideoGeneration("cogvideox").SetPrompt("cat").SetImageURL("https://a/b.png").SetRequestID("r"), client.VideoGeneration("")},
+	} {
+		buf, err := json.Marshal(item.s.BatchBody())
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(buf, item.out))
+		buf2, err := json.Marshal(item.out.BatchBody())
+		require.NoError(t, err)
+		require.JSONEq(t, string(buf), string(buf2))
+	}
+}
+
+func TestBatchRequestReader(t *testing.T) {
+	client, err := NewClient(WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	w := NewBatchFileWriter(buf)
+	require.NoError(t, w.Write("batch-1", client.ChatCompletion("a").AddMessage(NewUserMessage("hello"))))
+	require.NoError(t, w.Write("batch-2", client.Embedding("c").SetInput("whoa")))
+
+	r := NewBatchRequestReader(bytes.NewReader(buf.Bytes()))
+	var out bytes.Buffer
+	w2 := NewBatchFileWriter(&out)
+	for {
+		var req BatchRequest
+		if err := r.Read(&req); err != nil {
+			require.Equal(t, io.EOF, err)
+			break
+		}
+		s, err := client.BatchService(req)
+		require.NoError(t, err)
+		require.NoError(t, w2.Write(req.CustomID, s))
+	}
+	require.Equal(t, buf.String(), out.String())
+
+	_, err = client.BatchService(BatchRequest{URL: "/v4/unknown"})
+	require.Error(t, err)
+}
+
+func TestChatCompletionServiceFromBodyReset(t *testing.T) {
+	client, err := NewClient(WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	s := client.ChatCompletion("glm-4").
+		SetTemperature(0.5).
+		SetExtraHeaders(map[string]string{"X-Trace": "1"}).
+		AddMessage(NewUserMessage("hi")).
+		AddTool(ChatCompletionToolWebSearch{SearchQuery: "q"})
+	require.NoError(t, s.FromBody([]byte(`{"model":"glm-4-flash","messages":[{"role":"user","content":"hello"}]}`)))
+	require.Equal(t, M{"model": "glm-4-flash", "messages": []any{NewUserMessage("hello")}}, s.BatchBody())
+	require.Equal(t, client, s.client)
+	require.Equal(t, map[string]string{"X-Trace": "1"}, s.extras.headers)
+
+	// a zero service decoded with json.Unmarshal has no client
+	var s2 ChatCompletionService
+	require.NoError(t, json.Unmarshal([]byte(`{"model":"glm-4-flash","messages":[{"role":"user","content":"hello"}]}`), &s2))
+	_, err = s2.Do(context.Background())
+	require.ErrorIs(t, err, ErrClientMissing)
+}
@@ -0,0 +1,298 @@
+package zhipu
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// cacheReplayChunkRunes is the number of runes per synthetic chunk when a cached response is replayed as a stream
+	cacheReplayChunkRunes = 16
+)
+
+// Cache is the backend of the response cache
+type Cache interface {
+	// Get returns the value of the key, ok is false if missing or expired
+	Get(key string) (value []byte, ok bool)
+	// Set sets the value of the key, ttl 0 means no expiration
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// WithCache enables the response cache for deterministic calls, embeddings and chat completions with do_sample=false,
+// ttl 0 means no expiration
+func WithCache(cache Cache, ttl time.Duration) ClientOption {
+	return func(opts *clientOptions) {
+		opts.cache = cache
+		opts.cacheTTL = ttl
+	}
+}
+
+type cacheBypassKey struct{}
+
+// BypassCache returns a context skipping the response cache, the fresh response is still stored,
+// the context applies to every call made with it, see WithCallBypassCache for a single call
+func BypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cacheKey is a canonical hash of the endpoint and the body, json encoding sorts the map keys
+func (c *Client) cacheKey(op *Operation) (key string, ok bool) {
+	body := M{}
+	for k, v := range op.Body {
+		// the same response serves stream and non-stream calls, request ids are unique per call
+		if k == "stream" || k == "request_id" {
+			continue
+		}
+		body[k] = v
+	}
+	buf, err := json.Marshal(M{"endpoint": strings.TrimRight(c.client.BaseURL, "/") + "/" + op.Path, "body": body})
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), true
+}
+
+// cacheGet loads the cached response of the operation into out
+func (c *Client) cacheGet(ctx context.Context, op *Operation, out any) bool {
+	if c.cache == nil {
+		return false
+	}
+	if bypass, _ := ctx.Value(cacheBypassKey{}).(bool); bypass {
+		return false
+	}
+	if opts := getCallOptions(ctx); opts != nil && opts.bypassCache {
+		return false
+	}
+	key, ok := c.cacheKey(op)
+	if !ok {
+		return false
+	}
+	buf, ok := c.cache.Get(key)
+	if !ok || json.Unmarshal(buf, out) != nil {
+		return false
+	}
+	if s, ok := out.(rawJSONSetter); ok {
+		s.setRawJSON(buf)
+	}
+	op.Cached = true
+	return true
+}
+
+// cacheSet stores the response of the operation, the raw json body is stored as is if not nil,
+// keeping the fields not modeled by the response
+func (c *Client) cacheSet(op *Operation, res any, raw []byte) {
+	if c.cache == nil {
+		return
+	}
+	key, ok := c.cacheKey(op)
+	if !ok {
+		return
+	}
+	buf := raw
+	if buf == nil {
+		var err error
+		if buf, err = json.Marshal(res); err != nil {
+			return
+		}
+	}
+	c.cache.Set(key, buf, c.cacheTTL)
+}
+
+// lruEntry is an entry of the LRUCache
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// LRUCache is an in-memory Cache evicting the least recently used entries
+type LRUCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+var (
+	_ Cache = &LRUCache{}
+)
+
+// NewLRUCache creates a new LRUCache holding at most capacity entries
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// Get implements Cache
+func (c *LRUCache) Get(key string) (value []byte, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, true
+}
+
+// Set implements Cache
+func (c *LRUCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.entries, el.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of entries, expired ones included
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// diskEntry is the content of a DiskCache file
+type diskEntry struct {
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	Value     []byte `json:"value"`
+}
+
+// DiskCache is a Cache storing one file per entry in a directory, shared across processes
+type DiskCache struct {
+	dir string
+}
+
+var (
+	_ Cache = &DiskCache{}
+)
+
+// NewDiskCache creates a new DiskCache in the directory, the directory is created if missing
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) path(key string) string {
+	if len(key) > 2 {
+		return filepath.Join(c.dir, key[:2], key+".json")
+	}
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get implements Cache
+func (c *DiskCache) Get(key string) (value []byte, ok bool) {
+	buf, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return
+	}
+	var entry diskEntry
+	if json.Unmarshal(buf, &entry) != nil {
+		return
+	}
+	if entry.ExpiresAt != 0 && time.Now().UnixMilli() > entry.ExpiresAt {
+		_ = os.Remove(c.path(key))
+		return
+	}
+	return entry.Value, true
+}
+
+// Set implements Cache, errors are ignored as the cache is best effort
+func (c *DiskCache) Set(key string, value []byte, ttl time.Duration) {
+	entry := diskEntry{Value: value}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl).UnixMilli()
+	}
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	file := c.path(key)
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return
+	}
+	// write and rename, readers never see partial files
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), file) != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// chatCompletionSyntheticChunks splits a cached response into stream chunks
+func chatCompletionSyntheticChunks(res ChatCompletionResponse) (chunks []ChatCompletionResponse) {
+	if len(res.Choices) == 0 {
+		return
+	}
+	choice := res.Choices[0]
+
+	chunk := func(delta ChatCompletionMessage) ChatCompletionResponse {
+		return ChatCompletionResponse{
+			ID:      res.ID,
+			Created: res.Created,
+			Model:   res.Model,
+			Choices: []ChatCompletionChoice{{Index: choice.Index, Delta: delta}},
+		}
+	}
+
+	content := []rune(choice.Message.Content)
+	for len(content) > 0 {
+		n := min(len(content), cacheReplayChunkRunes)
+		chunks = append(chunks, chunk(ChatCompletionMessage{Role: choice.Message.Role, Content: string(content[:n])}))
+		content = content[n:]
+	}
+
+	last := chunk(ChatCompletionMessage{Role: choice.Message.Role, ToolCalls: choice.Message.ToolCalls})
+	last.Choices[0].FinishReason = choice.FinishReason
+	last.Usage = res.Usage
+	last.WebSearch = res.WebSearch
+	chunks = append(chunks, last)
+	return
+}
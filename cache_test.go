@@ -0,0 +1,125 @@
+package zhipu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set("a", []byte("1"), 0)
+	c.Set("b", []byte("2"), 0)
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	c.Set("c", []byte("3"), 0)
+
+	// b is the least recently used
+	_, ok = c.Get("b")
+	require.False(t, ok)
+	require.Equal(t, 2, c.Len())
+
+	c.Set("d", []byte("4"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = c.Get("d")
+	require.False(t, ok)
+}
+
+func TestDiskCache(t *testing.T) {
+	c, err := NewDiskCache(t.TempDir())
+	require.NoError(t, err)
+
+	c.Set("abcdef", []byte("hello"), 0)
+	value, ok := c.Get("abcdef")
+	require.True(t, ok)
+	require.Equal(t, "hello", string(value))
+
+	c.Set("expired", []byte("hello"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = c.Get("expired")
+	require.False(t, ok)
+
+	_, ok = c.Get("missing")
+	require.False(t, ok)
+}
+
+func TestCache(t *testing.T) {
+	var hits int64
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		rw.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "embeddings") {
+			_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[{"embedding":[1]}]}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`{"id":"1","model":"glm-4-flash","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"你好，我是智谱清言，一个由智谱 AI 训练的语言模型。"}}],"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3},"undocumented":{"a":1}}`))
+	}))
+	defer s.Close()
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"), WithCache(NewLRUCache(10), time.Hour))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// embeddings
+	for i := 0; i < 2; i++ {
+		res, err := client.Embedding("embedding-2").SetInput("hello").Do(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []float64{1}, res.Data[0].Embedding)
+	}
+	require.Equal(t, int64(1), atomic.LoadInt64(&hits))
+
+	// bypass
+	_, err = client.Embedding("embedding-2").SetInput("hello").Do(BypassCache(ctx))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), atomic.LoadInt64(&hits))
+
+	// sampled chat completions are not cached
+	for i := 0; i < 2; i++ {
+		_, err = client.ChatCompletion("glm-4-flash").AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).Do(ctx)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int64(4), atomic.LoadInt64(&hits))
+
+	// deterministic chat completions are cached
+	res, err := client.ChatCompletion("glm-4-flash").SetDoSample(false).AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), atomic.LoadInt64(&hits))
+
+	// the raw json body is cached as is, undocumented fields included
+	cached, err := client.ChatCompletion("glm-4-flash").SetDoSample(false).AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), atomic.LoadInt64(&hits))
+	require.JSONEq(t, string(res.RawJSON()), string(cached.RawJSON()))
+	require.Contains(t, string(cached.RawJSON()), `"undocumented":{"a":1}`)
+
+	// replayed as synthetic chunks
+	var chunks []ChatCompletionResponse
+	res2, err := client.ChatCompletion("glm-4-flash").SetDoSample(false).AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).
+		SetStreamHandler(func(chunk ChatCompletionResponse) error {
+			chunks = append(chunks, chunk)
+			return nil
+		}).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), atomic.LoadInt64(&hits))
+	require.Len(t, chunks, 3)
+	require.Equal(t, FinishReasonStop, chunks[2].Choices[0].FinishReason)
+	require.Equal(t, int64(3), chunks[2].Usage.TotalTokens)
+	require.Equal(t, res.Choices[0].Message.Content, res2.Choices[0].Message.Content)
+	require.Equal(t, res.Usage, res2.Usage)
+
+	// bypass with a call option, the fresh response is stored
+	_, err = client.ChatCompletion("glm-4-flash").SetDoSample(false).AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).Do(ctx, WithCallBypassCache())
+	require.NoError(t, err)
+	require.Equal(t, int64(6), atomic.LoadInt64(&hits))
+	_, err = client.ChatCompletion("glm-4-flash").SetDoSample(false).AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), atomic.LoadInt64(&hits))
+}
@@ -0,0 +1,271 @@
+package zhipu
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultHedgeDelay is the hedging delay before enough latencies are observed
+	defaultHedgeDelay = time.Second
+	// hedgeLatencySamples is the number of latencies kept per path to estimate the p95
+	hedgeLatencySamples = 100
+	// hedgeLatencyMinSamples is the minimum number of latencies to use the p95
+	hedgeLatencyMinSamples = 10
+)
+
+// callOptions is the options of a single call
+type callOptions struct {
+	timeout time.Duration
+
+	hedges     int
+	hedgeDelay time.Duration
+
+	headers map[string]string
+	body    M
+	params  map[string]string
+
+	meta *ResponseMeta
+
+	validation *bool
+
+	bypassCache bool
+}
+
+// CallOption is a function that configures a single call, passed to the Do methods of the services
+type CallOption func(opts *callOptions)
+
+// WithCallTimeout set the timeout of the call, independent of the deadline of the context, streams included
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(opts *callOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// WithCallHedging sends up to hedges duplicates of the request, one after each delay without response headers,
+// the first response wins and the others are canceled. delay 0 uses the p95 latency observed on the same path.
+func WithCallHedging(hedges int, delay time.Duration) CallOption {
+	return func(opts *callOptions) {
+		opts.hedges = hedges
+		opts.hedgeDelay = delay
+	}
+}
+
+// WithCallHeader set an extra header of the call
+func WithCallHeader(key, value string) CallOption {
+	return func(opts *callOptions) {
+		if opts.headers == nil {
+			opts.headers = map[string]string{}
+		}
+		opts.headers[key] = value
+	}
+}
+
+// WithCallBody set extra fields of the request body of the call, overriding the fields set by the service,
+// requests without json body carry them in the query, or in the form of a multipart upload
+func WithCallBody(body M) CallOption {
+	return func(opts *callOptions) {
+		if opts.body == nil {
+			opts.body = M{}
+		}
+		for k, v := range body {
+			opts.body[k] = v
+		}
+	}
+}
+
+// WithCallBypassCache skips the response cache for the call, the fresh response is still stored
+func WithCallBypassCache() CallOption {
+	return func(opts *callOptions) {
+		opts.bypassCache = true
+	}
+}
+
+type callOptionsKey struct{}
+
+// withCallOptions applies the call options to the context, the timeout is applied by the caller
+func withCallOptions(ctx context.Context, optFns []CallOption) (context.Context, *callOptions) {
+	if len(optFns) == 0 {
+		return ctx, nil
+	}
+	opts := &callOptions{}
+	for _, optFn := range optFns {
+		optFn(opts)
+	}
+	return context.WithValue(ctx, callOptionsKey{}, opts), opts
+}
+
+// getCallOptions returns the call options of the context, nil if not set
+func getCallOptions(ctx context.Context) *callOptions {
+	opts, _ := ctx.Value(callOptionsKey{}).(*callOptions)
+	return opts
+}
+
+// latencyWindow keeps the recent latencies of a path
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+// hedgeTransport is a http.RoundTripper sending hedged requests for calls with WithCallHedging
+type hedgeTransport struct {
+	next http.RoundTripper
+
+	mu        sync.Mutex
+	latencies map[string]*latencyWindow
+}
+
+func newHedgeTransport(next http.RoundTripper) *hedgeTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &hedgeTransport{next: next, latencies: map[string]*latencyWindow{}}
+}
+
+// observe records the latency of the response headers of a path
+func (t *hedgeTransport) observe(path string, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.latencies[path]
+	if !ok {
+		w = &latencyWindow{}
+		t.latencies[path] = w
+	}
+	if len(w.samples) < hedgeLatencySamples {
+		w.samples = append(w.samples, latency)
+		return
+	}
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % hedgeLatencySamples
+}
+
+// p95 returns the p95 latency of a path, defaultHedgeDelay if not enough samples
+func (t *hedgeTransport) p95(path string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w, ok := t.latencies[path]
+	if !ok || len(w.samples) < hedgeLatencyMinSamples {
+		return defaultHedgeDelay
+	}
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*95-1)/100]
+}
+
+// hedgeAttempt is the outcome of a hedged attempt
+type hedgeAttempt struct {
+	index int
+	res   *http.Response
+	err   error
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *hedgeTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	start := time.Now()
+
+	opts := getCallOptions(req.Context())
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	if opts == nil || opts.hedges <= 0 || !replayable {
+		if res, err = t.next.RoundTrip(req); err == nil {
+			t.observe(req.URL.Path, time.Since(start))
+		}
+		return
+	}
+
+	delay := opts.hedgeDelay
+	if delay <= 0 {
+		delay = t.p95(req.URL.Path)
+	}
+
+	attempts := make(chan hedgeAttempt, opts.hedges+1)
+	var cancels []context.CancelFunc
+
+	launch := func(i int) {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels = append(cancels, cancel)
+		attempt := req.Clone(ctx)
+		if i > 0 && req.GetBody != nil {
+			var err error
+			if attempt.Body, err = req.GetBody(); err != nil {
+				attempts <- hedgeAttempt{index: i, err: err}
+				return
+			}
+		}
+		go func() {
+			res, err := t.next.RoundTrip(attempt)
+			attempts <- hedgeAttempt{index: i, res: res, err: err}
+		}()
+	}
+
+	launch(0)
+	launched, pending := 1, 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case a := <-attempts:
+			pending--
+			// server errors may be transient, wait for the other attempts
+			if a.err == nil && a.res.StatusCode < http.StatusInternalServerError {
+				t.observe(req.URL.Path, time.Since(start))
+				if res != nil {
+					_ = res.Body.Close()
+				}
+				t.abandon(cancels, a.index, attempts, pending)
+				a.res.Body = &releaseBody{ReadCloser: a.res.Body, release: cancels[a.index]}
+				return a.res, nil
+			}
+			if res != nil {
+				_ = res.Body.Close()
+			}
+			res, err = a.res, a.err
+			if pending > 0 {
+				continue
+			}
+			if launched > opts.hedges || req.Context().Err() != nil {
+				// every attempt failed, return the last failure, the body is still readable
+				if res != nil {
+					res.Body = &releaseBody{ReadCloser: res.Body, release: cancels[a.index]}
+				} else {
+					cancels[a.index]()
+				}
+				t.abandon(cancels, a.index, attempts, 0)
+				return
+			}
+			// no attempt in flight, hedge immediately
+			launch(launched)
+			launched++
+			pending++
+		case <-timer.C:
+			if launched > opts.hedges {
+				continue
+			}
+			launch(launched)
+			launched++
+			pending++
+			timer.Reset(delay)
+		}
+	}
+}
+
+// abandon cancels the losing attempts and closes their responses in the background
+func (t *hedgeTransport) abandon(cancels []context.CancelFunc, winner int, attempts chan hedgeAttempt, pending int) {
+	for i, cancel := range cancels {
+		if i != winner {
+			cancel()
+		}
+	}
+	go func() {
+		for ; pending > 0; pending-- {
+			if a := <-attempts; a.res != nil {
+				_ = a.res.Body.Close()
+			}
+		}
+	}()
+}
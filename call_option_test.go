@@ -0,0 +1,71 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallOptions(t *testing.T) {
+	var hits int64
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&hits, 1)
+
+		var body M
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "v", r.Header.Get("X-Extra"))
+		require.Equal(t, "extra", body["extra"])
+
+		if body["input"] == "slow" || n == 1 {
+			// the first attempt hangs until canceled
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[{"embedding":[1]}]}`))
+	}))
+	defer s.Close()
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	opts := []CallOption{
+		WithCallHeader("X-Extra", "v"),
+		WithCallBody(M{"extra": "extra"}),
+	}
+
+	// hedged
+	start := time.Now()
+	res, err := client.Embedding("embedding-2").SetInput("hello").Do(context.Background(), append(opts, WithCallHedging(1, 50*time.Millisecond))...)
+	require.NoError(t, err)
+	require.Equal(t, []float64{1}, res.Data[0].Embedding)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+	require.Equal(t, int64(2), atomic.LoadInt64(&hits))
+
+	// timeout
+	start = time.Now()
+	_, err = client.Embedding("embedding-2").SetInput("slow").Do(context.Background(), append(opts, WithCallTimeout(50*time.Millisecond))...)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestHedgeTransportP95(t *testing.T) {
+	ht := newHedgeTransport(nil)
+	require.Equal(t, defaultHedgeDelay, ht.p95("/embeddings"))
+
+	for i := 1; i <= 200; i++ {
+		ht.observe("/embeddings", time.Duration(i)*time.Millisecond)
+	}
+	// only the last 100 latencies are kept
+	require.Equal(t, 195*time.Millisecond, ht.p95("/embeddings"))
+}
@@ -6,7 +6,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -235,6 +238,8 @@ type ChatCompletionChoice struct {
 
 // ChatCompletionResponse is the response for chat completion
 type ChatCompletionResponse struct {
+	RawResponse
+
 	ID        string                    `json:"id"`
 	Created   int64                     `json:"created"`
 	Model     string                    `json:"model"`
@@ -344,6 +349,7 @@ func chatCompletionDecodeStream(r io.Reader, fn func(chunk ChatCompletionRespons
 // ChatCompletionStreamService is the service for chat completion stream
 type ChatCompletionService struct {
 	client *Client
+	extras serviceExtras
 
 	model              string
 	requestID          *string
@@ -367,6 +373,8 @@ type ChatCompletionService struct {
 
 var (
 	_ BatchSupport = &ChatCompletionService{}
+
+	chatCompletionFunctionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
 )
 
 // NewChatCompletionService creates a new ChatCompletionService.
@@ -385,7 +393,7 @@ func (s *ChatCompletionService) BatchURL() string {
 }
 
 func (s *ChatCompletionService) BatchBody() any {
-	return s.buildBody()
+	return s.extras.mergeBody(s.buildBody())
 }
 
 // SetModel set the model of the chat completion
@@ -406,7 +414,7 @@ func (s *ChatCompletionService) SetRequestID(requestID string) *ChatCompletionSe
 	return s
 }
 
-// SetTemperature set the temperature of the chat completion, optional
+// SetTemperature set the temperature of the chat completion, optional, in (0, 1)
 func (s *ChatCompletionService) SetDoSample(doSample bool) *ChatCompletionService {
 	s.doSample = &doSample
 	return s
@@ -424,13 +432,13 @@ func (s *ChatCompletionService) SetSensitiveWordCheck(sensitiveWordCheck string)
 	return s
 }
 
-// SetTemperature set the temperature of the chat completion, optional
+// SetTemperature set the temperature of the chat completion, optional, in (0, 1)
 func (s *ChatCompletionService) SetTemperature(temperature float64) *ChatCompletionService {
 	s.temperature = &temperature
 	return s
 }
 
-// SetTopP set the top p of the chat completion, optional
+// SetTopP set the top p of the chat completion, optional, in (0, 1)
 func (s *ChatCompletionService) SetTopP(topP float64) *ChatCompletionService {
 	s.topP = &topP
 	return s
@@ -483,42 +491,35 @@ func (s *ChatCompletionService) AddMessage(messages ...ChatCompletionMessageType
 // AddFunction add the function to the chat completion
 func (s *ChatCompletionService) AddTool(tools ...ChatCompletionTool) *ChatCompletionService {
 	for _, tool := range tools {
-		switch tool := tool.(type) {
-		case ChatCompletionToolFunction:
-			s.tools = append(s.tools, map[string]any{
-				"type":           ToolTypeFunction,
-				ToolTypeFunction: tool,
-			})
-		case ChatCompletionToolRetrieval:
-			s.tools = append(s.tools, map[string]any{
-				"type":            ToolTypeRetrieval,
-				ToolTypeRetrieval: tool,
-			})
-		case ChatCompletionToolWebSearch:
-			s.tools = append(s.tools, map[string]any{
-				"type":            ToolTypeWebSearch,
-				ToolTypeWebSearch: tool,
-			})
-		case ChatCompletionToolCodeInterpreter:
-			s.tools = append(s.tools, map[string]any{
-				"type":                  ToolTypeCodeInterpreter,
-				ToolTypeCodeInterpreter: tool,
-			})
-		case ChatCompletionToolDrawingTool:
-			s.tools = append(s.tools, map[string]any{
-				"type":              ToolTypeDrawingTool,
-				ToolTypeDrawingTool: tool,
-			})
-		case ChatCompletionToolWebBrowser:
-			s.tools = append(s.tools, map[string]any{
-				"type":             ToolTypeWebBrowser,
-				ToolTypeWebBrowser: tool,
-			})
+		if body := encodeChatCompletionTool(tool); body != nil {
+			s.tools = append(s.tools, body)
 		}
 	}
 	return s
 }
 
+// encodeChatCompletionTool wraps the tool in the request format, like {"type":"function","function":{...}}, nil if unknown
+func encodeChatCompletionTool(tool ChatCompletionTool) map[string]any {
+	var typ string
+	switch tool.(type) {
+	case ChatCompletionToolFunction:
+		typ = ToolTypeFunction
+	case ChatCompletionToolRetrieval:
+		typ = ToolTypeRetrieval
+	case ChatCompletionToolWebSearch:
+		typ = ToolTypeWebSearch
+	case ChatCompletionToolCodeInterpreter:
+		typ = ToolTypeCodeInterpreter
+	case ChatCompletionToolDrawingTool:
+		typ = ToolTypeDrawingTool
+	case ChatCompletionToolWebBrowser:
+		typ = ToolTypeWebBrowser
+	default:
+		return nil
+	}
+	return map[string]any{"type": typ, typ: tool}
+}
+
 func (s *ChatCompletionService) buildBody() M {
 	body := map[string]any{
 		"model":    s.model,
@@ -566,34 +567,296 @@ func (s *ChatCompletionService) buildBody() M {
 	return body
 }
 
+// SetExtraBody sets extra fields of the ChatCompletion request, see WithCallBody
+func (s *ChatCompletionService) SetExtraBody(body M) *ChatCompletionService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the ChatCompletion request
+func (s *ChatCompletionService) SetExtraHeaders(headers map[string]string) *ChatCompletionService {
+	s.extras.headers = headers
+	return s
+}
+
+// FromBody rebuilds the service from a json request body, like the body of a batch line or a logged request,
+// unknown fields are kept as extra body, the stream field is ignored, fields missing from the body are reset,
+// the client, extra headers and stream handler are kept
+func (s *ChatCompletionService) FromBody(data []byte) (err error) {
+	var fields bodyFields
+	if fields, err = decodeBodyFields(data); err != nil {
+		return
+	}
+	*s = ChatCompletionService{client: s.client, extras: serviceExtras{headers: s.extras.headers}, streamHandler: s.streamHandler}
+	delete(fields, "stream")
+
+	if _, err = fields.take("model", &s.model); err != nil {
+		return
+	}
+	var messages []json.RawMessage
+	if _, err = fields.take("messages", &messages); err != nil {
+		return
+	}
+	for i, raw := range messages {
+		var message ChatCompletionMessageType
+		if message, err = DecodeChatCompletionMessage(raw); err != nil {
+			err = fmt.Errorf("zhipu: failed to decode messages[%d]: %w", i, err)
+			return
+		}
+		s.AddMessage(message)
+	}
+	var tools []json.RawMessage
+	if _, err = fields.take("tools", &tools); err != nil {
+		return
+	}
+	for i, raw := range tools {
+		var tool ChatCompletionTool
+		if tool, err = DecodeChatCompletionTool(raw); err != nil {
+			// tools unknown to the sdk are kept as is
+			var m map[string]any
+			if json.Unmarshal(raw, &m) != nil {
+				err = fmt.Errorf("zhipu: failed to decode tools[%d]: %w", i, err)
+				return
+			}
+			err = nil
+			s.tools = append(s.tools, m)
+			continue
+		}
+		s.AddTool(tool)
+	}
+
+	var (
+		str       string
+		b         bool
+		f         float64
+		n         int
+		ok        bool
+		meta      ChatCompletionMeta
+		thinking  ChatCompletionThinking
+		swc       ChatCompletionSensitiveWordCheck
+		resFormat struct {
+			Type string `json:"type"`
+		}
+	)
+	if ok, err = fields.take("request_id", &str); err != nil {
+		return
+	} else if ok {
+		s.SetRequestID(str)
+	}
+	if ok, err = fields.take("do_sample", &b); err != nil {
+		return
+	} else if ok {
+		s.SetDoSample(b)
+	}
+	if ok, err = fields.take("temperature", &f); err != nil {
+		return
+	} else if ok {
+		s.SetTemperature(f)
+	}
+	if ok, err = fields.take("top_p", &f); err != nil {
+		return
+	} else if ok {
+		s.SetTopP(f)
+	}
+	if ok, err = fields.take("max_tokens", &n); err != nil {
+		return
+	} else if ok {
+		s.SetMaxTokens(n)
+	}
+	if _, err = fields.take("stop", &s.stop); err != nil {
+		return
+	}
+	if ok, err = fields.take("tool_choice", &str); err != nil {
+		return
+	} else if ok {
+		s.SetToolChoice(str)
+	}
+	if ok, err = fields.take("user_id", &str); err != nil {
+		return
+	} else if ok {
+		s.SetUserID(str)
+	}
+	if ok, err = fields.take("meta", &meta); err != nil {
+		return
+	} else if ok {
+		s.SetMeta(meta)
+	}
+	if ok, err = fields.take("response_format", &resFormat); err != nil {
+		return
+	} else if ok {
+		s.SetResponseFormat(resFormat.Type)
+	}
+	if ok, err = fields.take("thinking", &thinking); err != nil {
+		return
+	} else if ok {
+		s.thinking = &thinking
+	}
+	if ok, err = fields.take("sensitive_word_check", &swc); err != nil {
+		return
+	} else if ok {
+		s.sensitiveWordCheck = &swc
+	}
+
+	s.extras.body, err = fields.rest()
+	return
+}
+
+// UnmarshalJSON implements json.Unmarshaler with FromBody
+func (s *ChatCompletionService) UnmarshalJSON(data []byte) error {
+	return s.FromBody(data)
+}
+
+// validateChatCompletionMessage checks a message of the chat completion
+func validateChatCompletionMessage(v *validator, field string, message any) {
+	switch message := message.(type) {
+	case ChatCompletionMessage:
+		v.oneOf(field+".role", message.Role, RoleSystem, RoleUser, RoleAssistant, RoleTool)
+		if message.Role == RoleTool {
+			v.required(field+".tool_call_id", message.ToolCallID)
+		}
+		if message.Role != RoleAssistant && message.Content == "" {
+			v.add(field+".content", "is required")
+		}
+	case ChatCompletionMultiMessage:
+		v.oneOf(field+".role", message.Role, RoleSystem, RoleUser, RoleAssistant)
+		if len(message.Content) == 0 {
+			v.add(field+".content", "is required")
+		}
+		for i, content := range message.Content {
+			path := fmt.Sprintf("%s.content[%d]", field, i)
+			switch content.Type {
+			case MultiContentTypeText:
+				v.required(path+".text", content.Text)
+			case MultiContentTypeImageURL:
+				if content.ImageURL == nil || content.ImageURL.URL == "" {
+					v.add(path+".image_url", "is required")
+				}
+			default:
+				v.oneOf(path+".type", content.Type, MultiContentTypeText, MultiContentTypeImageURL)
+			}
+		}
+	}
+}
+
+// validateChatCompletionTool checks a tool added by AddTool
+func validateChatCompletionTool(v *validator, field string, tool any) {
+	m, ok := tool.(map[string]any)
+	if !ok {
+		return
+	}
+	typ, _ := m["type"].(string)
+	switch tool := m[typ].(type) {
+	case ChatCompletionToolFunction:
+		if !chatCompletionFunctionNamePattern.MatchString(tool.Name) {
+			v.add(field+".function.name", "must match %s", chatCompletionFunctionNamePattern.String())
+		}
+	case ChatCompletionToolRetrieval:
+		v.required(field+".retrieval.knowledge_id", tool.KnowledgeID)
+	}
+}
+
+// Validate checks the ChatCompletion request locally, see ValidationError
+func (s *ChatCompletionService) Validate() error {
+	v := newValidator("ChatCompletion")
+	v.required("model", s.model)
+	if len(s.messages) == 0 {
+		v.add("messages", "is required")
+	}
+	for i, message := range s.messages {
+		validateChatCompletionMessage(v, fmt.Sprintf("messages[%d]", i), message)
+	}
+	if s.temperature != nil && (*s.temperature <= 0 || *s.temperature >= 1) {
+		v.add("temperature", "must be in (0, 1)")
+	}
+	if s.topP != nil && (*s.topP <= 0 || *s.topP >= 1) {
+		v.add("top_p", "must be in (0, 1)")
+	}
+	v.min("max_tokens", s.maxTokens, 1)
+	for i, tool := range s.tools {
+		validateChatCompletionTool(v, fmt.Sprintf("tools[%d]", i), tool)
+	}
+	if s.toolChoice != nil {
+		v.oneOf("tool_choice", *s.toolChoice, ToolChoiceAuto)
+	}
+	if s.resFormat != nil {
+		v.oneOf("response_format.type", *s.resFormat, ResponseFormatText, ResponseFormatJSONObject)
+	}
+	if s.thinking != nil {
+		v.oneOf("thinking.type", s.thinking.Type, ThinkingEnabled, ThinkingDisabled)
+	}
+	return v.err()
+}
+
 // Do send the request of the chat completion and return the response
-func (s *ChatCompletionService) Do(ctx context.Context) (res ChatCompletionResponse, err error) {
-	body := s.buildBody()
+func (s *ChatCompletionService) Do(ctx context.Context, opts ...CallOption) (res ChatCompletionResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	op := &Operation{Name: "ChatCompletion", Model: s.model, Method: "POST", Path: "chat/completions", Body: s.buildBody()}
+	if s.streamHandler != nil {
+		op.Body["stream"] = true
+		op.Stream = true
+	}
+	return invoke(ctx, s.client, op, s.do, s.extras.callOptions(opts)...)
+}
 
+func (s *ChatCompletionService) do(ctx context.Context, op *Operation) (res ChatCompletionResponse, err error) {
 	streamHandler := s.streamHandler
 
+	// only deterministic completions are cached
+	cacheable := op.Body["do_sample"] == false
+
 	if streamHandler == nil {
 		var (
 			resp     *resty.Response
 			apiError APIErrorResponse
 		)
-		if resp, err = s.client.request(ctx).SetBody(body).SetResult(&res).SetError(&apiError).Post("chat/completions"); err != nil {
+		if cacheable && s.client.cacheGet(ctx, op, &res) {
+			return
+		}
+		if resp, err = s.client.request(ctx).SetBody(op.Body).SetResult(&res).SetError(&apiError).Post("chat/completions"); err != nil {
 			return
 		}
 		if resp.IsError() {
 			err = apiError
 			return
 		}
+		if cacheable {
+			s.client.cacheSet(op, res, resp.Body())
+		}
 		return
 	}
 
 	// stream mode
 
-	body["stream"] = true
+	var choice ChatCompletionChoice
+
+	onChunk := func(chunk ChatCompletionResponse) error {
+		if op.FirstChunkAt.IsZero() {
+			op.FirstChunkAt = time.Now()
+		}
+		// reduce the chunk to the response
+		chatCompletionReduceResponse(&res, chunk)
+		// invoke the stream handler
+		return streamHandler(chunk)
+	}
+
+	var cached ChatCompletionResponse
+
+	if cacheable && s.client.cacheGet(ctx, op, &cached) {
+		// replay the cached response as synthetic chunks
+		for _, chunk := range chatCompletionSyntheticChunks(cached) {
+			if err = onChunk(chunk); err != nil {
+				return
+			}
+		}
+		res.Choices = append(res.Choices, choice)
+		return
+	}
 
 	var resp *resty.Response
 
-	if resp, err = s.client.request(ctx).SetBody(body).SetDoNotParseResponse(true).Post("chat/completions"); err != nil {
+	if resp, err = s.client.request(ctx).SetBody(op.Body).SetDoNotParseResponse(true).Post("chat/completions"); err != nil {
 		return
 	}
 	defer resp.RawBody().Close()
@@ -603,17 +866,15 @@ func (s *ChatCompletionService) Do(ctx context.Context) (res ChatCompletionRespo
 		return
 	}
 
-	var choice ChatCompletionChoice
-
-	if err = chatCompletionDecodeStream(resp.RawBody(), func(chunk ChatCompletionResponse) error {
-		// reduce the chunk to the response
-		chatCompletionReduceResponse(&res, chunk)
-		// invoke the stream handler
-		return streamHandler(chunk)
-	}); err != nil {
+	if err = chatCompletionDecodeStream(resp.RawBody(), onChunk); err != nil {
 		return
 	}
 
+	if cacheable {
+		// a stream has no json body, the reduced response is stored
+		s.client.cacheSet(op, res, nil)
+	}
+
 	res.Choices = append(res.Choices, choice)
 
 	return
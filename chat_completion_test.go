@@ -9,7 +9,7 @@ import (
 )
 
 func TestChatCompletionService(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.ChatCompletion("glm-4-flash")
@@ -26,7 +26,7 @@ func TestChatCompletionService(t *testing.T) {
 }
 
 func TestChatCompletionServiceCharGLM(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.ChatCompletion("charglm-3")
@@ -50,7 +50,7 @@ func TestChatCompletionServiceCharGLM(t *testing.T) {
 }
 
 func TestChatCompletionServiceAllToolsCodeInterpreter(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.ChatCompletion("GLM-4-AllTools")
@@ -96,7 +96,7 @@ func TestChatCompletionServiceAllToolsCodeInterpreter(t *testing.T) {
 }
 
 func TestChatCompletionServiceAllToolsDrawingTool(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.ChatCompletion("GLM-4-AllTools")
@@ -148,7 +148,7 @@ func TestChatCompletionServiceAllToolsDrawingTool(t *testing.T) {
 }
 
 func TestChatCompletionServiceAllToolsWebBrowser(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.ChatCompletion("GLM-4-AllTools")
@@ -200,7 +200,7 @@ func TestChatCompletionServiceAllToolsWebBrowser(t *testing.T) {
 }
 
 func TestChatCompletionServiceStream(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	var content string
@@ -222,7 +222,7 @@ func TestChatCompletionServiceStream(t *testing.T) {
 }
 
 func TestChatCompletionServiceVision(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.ChatCompletion("glm-4v")
@@ -3,14 +3,15 @@ package zhipu
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-resty/resty/v2"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 const (
@@ -26,6 +27,8 @@ var (
 	ErrAPIKeyMissing = errors.New("zhipu: api key is missing")
 	// ErrAPIKeyMalformed is the error when the api key is malformed
 	ErrAPIKeyMalformed = errors.New("zhipu: api key is malformed")
+	// ErrClientMissing is the error when a service without a client is called, like a service decoded with json.Unmarshal
+	ErrClientMissing = errors.New("zhipu: client is missing")
 )
 
 type clientOptions struct {
@@ -34,6 +37,34 @@ type clientOptions struct {
 	client  *http.Client
 	resty   *resty.Client
 	debug   *bool
+
+	rateLimits map[string]RateLimit
+	middleware []Middleware
+
+	logger       *slog.Logger
+	logDetail    LogDetail
+	logBodyLimit int
+
+	tokenProvider TokenProvider
+	tokenTTL      time.Duration
+	plainAPIKey   bool
+
+	apiKeys         []string
+	keyPoolStrategy KeyPoolStrategy
+	keyCooldown     time.Duration
+
+	endpoints        []Endpoint
+	endpointCooldown time.Duration
+
+	cache    Cache
+	cacheTTL time.Duration
+
+	usage *UsageTracker
+
+	models          *ModelRegistry
+	modelValidation bool
+
+	validation *bool
 }
 
 // ClientOption is a function that configures the client
@@ -53,7 +84,8 @@ func WithBaseURL(baseURL string) ClientOption {
 	}
 }
 
-// WithRestyClient set the resty client of the client
+// WithRestyClient set the resty client of the client, the resty client is not modified: its http client and
+// settings like headers and retries are copied, its request and response hooks are not
 func WithRestyClient(client *resty.Client) ClientOption {
 	return func(opts *clientOptions) {
 		opts.resty = client
@@ -77,36 +109,62 @@ func WithDebug(debug bool) ClientOption {
 
 // Client is the client for zhipu ai platform
 type Client struct {
-	client    *resty.Client
-	debug     bool
-	keyID     string
-	keySecret []byte
-}
-
-func (c *Client) createJWT() string {
-	timestamp := time.Now().UnixMilli()
-	exp := timestamp + time.Hour.Milliseconds()*24*7
-
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"api_key":   c.keyID,
-		"timestamp": timestamp,
-		"exp":       exp,
-	})
-	t.Header = map[string]interface{}{
-		"alg":       "HS256",
-		"sign_type": "SIGN",
-	}
+	client *resty.Client
+	debug  bool
+	tokens TokenProvider
+	pool   *keyPoolTransport
+	route  *endpointTransport
 
-	token, err := t.SignedString(c.keySecret)
-	if err != nil {
-		panic(err)
-	}
-	return token
+	cache    Cache
+	cacheTTL time.Duration
+	usage    *UsageTracker
+	models   *ModelRegistry
+
+	skipValidation bool
+
+	middleware []Middleware
 }
 
-// request creates a new resty request with the jwt token and context
+// request creates a new resty request with context, the Authorization header is set by the transport
 func (c *Client) request(ctx context.Context) *resty.Request {
-	return c.client.R().SetContext(ctx).SetHeader("Authorization", c.createJWT())
+	r := c.client.R().SetContext(ctx)
+	if opts := getCallOptions(ctx); opts != nil {
+		r.SetHeaders(opts.headers)
+	}
+	return r
+}
+
+// copyRestyClient copies the resty client with a copy of its http client, the transport and the hooks are
+// added to the copy, resty.Client.Clone shares the http client
+func copyRestyClient(rc *resty.Client) *resty.Client {
+	hc := *rc.GetClient()
+	c := resty.NewWithClient(&hc)
+	c.BaseURL = rc.BaseURL
+	c.QueryParam = cloneValues(rc.QueryParam)
+	c.Header = rc.Header.Clone()
+	c.Token = rc.Token
+	c.AuthScheme = rc.AuthScheme
+	c.Debug = rc.Debug
+	c.RetryCount = rc.RetryCount
+	c.RetryWaitTime = rc.RetryWaitTime
+	c.RetryMaxWaitTime = rc.RetryMaxWaitTime
+	c.RetryConditions = append(c.RetryConditions, rc.RetryConditions...)
+	c.RetryHooks = append(c.RetryHooks, rc.RetryHooks...)
+	c.RetryAfter = rc.RetryAfter
+	c.RetryResetReaders = rc.RetryResetReaders
+	c.JSONMarshal = rc.JSONMarshal
+	c.JSONUnmarshal = rc.JSONUnmarshal
+	c.ResponseBodyLimit = rc.ResponseBodyLimit
+	return c
+}
+
+// cloneValues returns a deep copy of the values
+func cloneValues(v url.Values) url.Values {
+	out := url.Values{}
+	for k, vs := range v {
+		out[k] = append([]string(nil), vs...)
+	}
+	return out
 }
 
 // NewClient creates a new client
@@ -118,6 +176,12 @@ func NewClient(optFns ...ClientOption) (client *Client, err error) {
 		optFn(&opts)
 	}
 	// base url
+	if len(opts.endpoints) != 0 {
+		opts.baseURL = opts.endpoints[0].BaseURL
+		if opts.apiKey == "" && opts.tokenProvider == nil && len(opts.apiKeys) == 0 {
+			opts.apiKey, opts.tokenProvider = opts.endpoints[0].APIKey, opts.endpoints[0].TokenProvider
+		}
+	}
 	if opts.baseURL == "" {
 		opts.baseURL = strings.TrimSpace(os.Getenv(envBaseURL))
 	}
@@ -128,7 +192,7 @@ func NewClient(optFns ...ClientOption) (client *Client, err error) {
 	if opts.apiKey == "" {
 		opts.apiKey = strings.TrimSpace(os.Getenv(envAPIKey))
 	}
-	if opts.apiKey == "" {
+	if opts.apiKey == "" && opts.tokenProvider == nil && len(opts.apiKeys) == 0 {
 		err = ErrAPIKeyMissing
 		return
 	}
@@ -141,35 +205,104 @@ func NewClient(optFns ...ClientOption) (client *Client, err error) {
 		}
 	}
 
-	keyComponents := strings.SplitN(opts.apiKey, ".", 2)
-
-	if len(keyComponents) != 2 {
-		err = ErrAPIKeyMalformed
-		return
+	// token provider
+	tokens := opts.tokenProvider
+	if tokens == nil && len(opts.apiKeys) == 0 {
+		if opts.plainAPIKey {
+			tokens = NewAPIKeyTokenProvider(opts.apiKey)
+		} else if tokens, err = NewJWTTokenProvider(opts.apiKey, opts.tokenTTL); err != nil {
+			return
+		}
 	}
 
 	client = &Client{
-		keyID:     keyComponents[0],
-		keySecret: []byte(keyComponents[1]),
+		tokens:   tokens,
+		cache:    opts.cache,
+		cacheTTL: opts.cacheTTL,
+		usage:    opts.usage,
+		models:   opts.models,
+		// validation is enabled by default
+		skipValidation: opts.validation != nil && !*opts.validation,
+		middleware:     opts.middleware[:len(opts.middleware):len(opts.middleware)],
+	}
+
+	if client.models == nil {
+		client.models = DefaultModelRegistry()
+	}
+
+	// the validation and the usage tracker are the innermost middleware, only calls reaching the api are recorded
+	if opts.modelValidation {
+		client.middleware = append(client.middleware, client.validateModel)
+	}
+	if opts.usage != nil {
+		client.middleware = append(client.middleware, opts.usage.middleware)
 	}
 
 	if opts.resty != nil {
-		client.client = opts.resty
+		client.client = copyRestyClient(opts.resty)
 	} else if opts.client != nil {
-		client.client = resty.NewWithClient(opts.client)
+		// copy the http client, the transport may be wrapped below
+		hc := *opts.client
+		client.client = resty.NewWithClient(&hc)
 	} else {
 		client.client = resty.New()
 	}
 
-	client.client = client.client.SetBaseURL(opts.baseURL)
+	client.client = client.client.SetBaseURL(opts.baseURL).OnBeforeRequest(applyExtraParams).OnAfterResponse(captureResponse)
+
+	// transports are wrapped from the wire outwards: logging, endpoints, auth, rate limit, hedging, capture
+	if opts.logger != nil {
+		client.client.SetTransport(newLoggingTransport(client.client.GetClient().Transport, opts.logger, opts.logDetail, opts.logBodyLimit))
+	}
+
+	if len(opts.endpoints) != 0 {
+		if client.route, err = newEndpointTransport(client.client.GetClient().Transport, opts.endpoints, opts.endpointCooldown, opts.plainAPIKey, opts.tokenTTL); err != nil {
+			return
+		}
+		client.client.SetTransport(client.route)
+	}
+
+	if len(opts.apiKeys) != 0 {
+		if client.pool, err = newKeyPoolTransport(client.client.GetClient().Transport, opts.apiKeys, opts.plainAPIKey, opts.tokenTTL, opts.keyPoolStrategy, opts.keyCooldown); err != nil {
+			return
+		}
+		client.client.SetTransport(client.pool)
+	} else {
+		client.client.SetTransport(newAuthTransport(client.client.GetClient().Transport, tokens))
+	}
+
+	if len(opts.rateLimits) != 0 {
+		client.client.SetTransport(newRateLimitTransport(client.client.GetClient().Transport, opts.rateLimits))
+	}
+
+	client.client.SetTransport(newCaptureTransport(newHedgeTransport(client.client.GetClient().Transport)))
 
 	if opts.debug != nil {
 		client.client.SetDebug(*opts.debug)
 		client.debug = *opts.debug
+		if client.debug {
+			client.client.OnRequestLog(redactRequestLog)
+		}
 	}
 	return
 }
 
+// KeyStats returns the usage stats of the keys set by WithAPIKeys, nil if the pool is not used
+func (c *Client) KeyStats() []KeyStats {
+	if c.pool == nil {
+		return nil
+	}
+	return c.pool.Stats()
+}
+
+// EndpointStatus returns the health status of the endpoints set by WithEndpoints, nil if not set
+func (c *Client) EndpointStatus() []EndpointStatus {
+	if c.route == nil {
+		return nil
+	}
+	return c.route.Status()
+}
+
 // BatchCreate creates a new BatchCreateService.
 func (c *Client) BatchCreate() *BatchCreateService {
 	return NewBatchCreateService(c)
@@ -260,6 +393,11 @@ func (c *Client) FineTuneCancel(jobID string) *FineTuneCancelService {
 	return NewFineTuneCancelService(c).SetJobID(jobID)
 }
 
+// FineTuneWatch creates a new fine tune watcher
+func (c *Client) FineTuneWatch(jobID string) *FineTuneWatcher {
+	return NewFineTuneWatcher(c).SetJobID(jobID)
+}
+
 // ImageGeneration creates a new image generation service
 func (c *Client) ImageGeneration(model string) *ImageGenerationService {
 	return NewImageGenerationService(c).SetModel(model)
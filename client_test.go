@@ -2,16 +2,83 @@ package zhipu
 
 import (
 	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/require"
+	"github.com/yankeguo/zhipu/zhipucassette"
 )
 
+// newTestClient creates the client of an integration test, replaying the cassette testdata/cassettes/<test>.json.
+// Without cassette, the test runs against the platform if ZHIPUAI_API_KEY is set, and is skipped otherwise.
+// Run with ZHIPUAI_CASSETTE=record and ZHIPUAI_API_KEY to record the cassettes.
+func newTestClient(t *testing.T) (*Client, error) {
+	path := filepath.Join("testdata", "cassettes", t.Name()+".json")
+	mode := zhipucassette.ModeFromEnv()
+	if mode == zhipucassette.ModeReplay {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if os.Getenv(envAPIKey) == "" {
+				t.Skipf("no cassette %s, and %s is not set", path, envAPIKey)
+			}
+			return NewClient()
+		}
+	}
+	c, err := zhipucassette.New(path, mode)
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { require.NoError(t, c.Save()) })
+
+	opts := []ClientOption{WithHTTPClient(c.Client())}
+	if c.Mode() == zhipucassette.ModeReplay {
+		// the Authorization header is not recorded, any key replays the cassette
+		opts = append(opts, WithAPIKey("cassette.secret"))
+	}
+	return NewClient(opts...)
+}
+
 func TestClientR(t *testing.T) {
-	c, err := NewClient()
+	c, err := newTestClient(t)
 	require.NoError(t, err)
 	// the only free api is to list fine-tuning jobs
 	res, err := c.request(context.Background()).Get("fine_tuning/jobs")
 	require.NoError(t, err)
 	require.True(t, res.IsSuccess())
 }
+
+func TestClientRestyClientNotModified(t *testing.T) {
+	var headers []http.Header
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"data":[]}`))
+	}))
+	defer s.Close()
+
+	rc := resty.New().SetHeader("X-Custom", "1")
+	transport := rc.GetClient().Transport
+
+	for i := 0; i < 2; i++ {
+		client, err := NewClient(WithRestyClient(rc), WithBaseURL(s.URL), WithAPIKey("a.b"), WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))), WithLogDetail(LogDetailPayload))
+		require.NoError(t, err)
+		_, err = client.FileList(FilePurposeBatch).Do(context.Background())
+		require.NoError(t, err)
+	}
+	require.Len(t, headers, 2)
+	for _, header := range headers {
+		require.Equal(t, "1", header.Get("X-Custom"))
+		require.Len(t, header.Values("Authorization"), 1)
+	}
+
+	// the transport of the resty client is not wrapped, requests made with it are not signed
+	require.Equal(t, transport, rc.GetClient().Transport)
+	_, err := rc.R().Get(s.URL + "/files")
+	require.NoError(t, err)
+	require.Empty(t, headers[2].Get("Authorization"))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/yankeguo/zhipu"
+)
+
+// clientCacheEntry is an entry of the clientCache
+type clientCacheEntry struct {
+	key    string
+	client *zhipu.Client
+}
+
+// clientCache keeps the zhipu clients of the recent tenants, evicting the least recently used ones,
+// clients are keyed by the hash of the api key, so the keys of the tenants are not kept in memory
+type clientCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// newClientCache creates a new clientCache holding at most capacity clients
+func newClientCache(capacity int) *clientCache {
+	return &clientCache{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// clientCacheKey hashes the api key
+func clientCacheKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the client of the api key, creating it with create if missing
+func (c *clientCache) get(apiKey string, create func() (*zhipu.Client, error)) (client *zhipu.Client, err error) {
+	key := clientCacheKey(apiKey)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		client = el.Value.(*clientCacheEntry).client
+		return
+	}
+
+	if client, err = create(); err != nil {
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&clientCacheEntry{key: key, client: client})
+
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.entries, el.Value.(*clientCacheEntry).key)
+	}
+	return
+}
+
+// len returns the number of clients
+func (c *clientCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,68 @@
+// Command zhipu-openai-proxy exposes an OpenAI compatible HTTP API backed by the Zhipu AI platform.
+//
+// Each request is forwarded with the API key found in its "Authorization: Bearer" header, so a single proxy
+// can serve many tenants. Requests without bearer token are rejected, unless -allow-env-key is set, in which case
+// they are forwarded with the ZHIPUAI_API_KEY environment variable.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultModels     = "glm-4-plus,glm-4-air,glm-4-airx,glm-4-flash,glm-4-long,glm-4v,glm-4v-plus,embedding-2,embedding-3"
+	defaultMaxClients = 1000
+)
+
+func main() {
+	var (
+		optListen      string
+		optBaseURL     string
+		optModels      string
+		optAllowEnvKey bool
+		optMaxClients  int
+	)
+
+	flag.StringVar(&optListen, "listen", ":8080", "address to listen on")
+	flag.StringVar(&optBaseURL, "base-url", "", "zhipu base url, defaults to ZHIPUAI_BASE_URL or the official endpoint")
+	flag.StringVar(&optModels, "models", defaultModels, "comma separated model ids reported by /v1/models")
+	flag.BoolVar(&optAllowEnvKey, "allow-env-key", false, "forward requests without bearer token with ZHIPUAI_API_KEY, anyone reaching the proxy can use the key")
+	flag.IntVar(&optMaxClients, "max-clients", defaultMaxClients, "max clients kept for the api keys of the tenants, the least recently used are evicted")
+	flag.Parse()
+
+	var models []string
+	for _, m := range strings.Split(optModels, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+
+	var fallbackKey string
+	if optAllowEnvKey {
+		if fallbackKey = strings.TrimSpace(os.Getenv("ZHIPUAI_API_KEY")); fallbackKey == "" {
+			log.Fatal("-allow-env-key is set but ZHIPUAI_API_KEY is empty")
+		}
+	}
+
+	p := newProxy(optBaseURL, fallbackKey, models, optMaxClients)
+
+	log.Println("listening on", optListen)
+
+	// no write timeout, streams last as long as the completion
+	server := &http.Server{
+		Addr:              optListen,
+		Handler:           p.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/yankeguo/zhipu"
+)
+
+// openAIContentPart is a part of a multi-part message content
+type openAIContentPart struct {
+	Type     string         `json:"type"`
+	Text     string         `json:"text,omitempty"`
+	ImageURL *zhipu.URLItem `json:"image_url,omitempty"`
+}
+
+// openAIToolCallFunction is the function of a tool call
+type openAIToolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// openAIToolCall is a tool call issued by the assistant
+type openAIToolCall struct {
+	Index    *int                   `json:"index,omitempty"`
+	ID       string                 `json:"id,omitempty"`
+	Type     string                 `json:"type"`
+	Function openAIToolCallFunction `json:"function"`
+}
+
+// openAIMessage is a message in the OpenAI chat completion format
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    json.RawMessage  `json:"content,omitempty"`
+	Name       string           `json:"name,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAITool is a tool definition in the OpenAI chat completion format
+type openAITool struct {
+	Type     string                           `json:"type"`
+	Function zhipu.ChatCompletionToolFunction `json:"function"`
+}
+
+// openAIResponseFormat is the response format of the chat completion
+type openAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+// openAIChatCompletionRequest is the request of /v1/chat/completions
+type openAIChatCompletionRequest struct {
+	Model               string                `json:"model"`
+	Messages            []openAIMessage       `json:"messages"`
+	Temperature         *float64              `json:"temperature,omitempty"`
+	TopP                *float64              `json:"top_p,omitempty"`
+	MaxTokens           *int                  `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int                  `json:"max_completion_tokens,omitempty"`
+	Stop                json.RawMessage       `json:"stop,omitempty"`
+	Tools               []openAITool          `json:"tools,omitempty"`
+	ToolChoice          json.RawMessage       `json:"tool_choice,omitempty"`
+	User                string                `json:"user,omitempty"`
+	Stream              bool                  `json:"stream,omitempty"`
+	ResponseFormat      *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// openAIChatCompletionMessage is the message of a chat completion choice
+type openAIChatCompletionMessage struct {
+	Role      string           `json:"role,omitempty"`
+	Content   string           `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+// openAIChatCompletionChoice is a choice of the chat completion response
+type openAIChatCompletionChoice struct {
+	Index        int                          `json:"index"`
+	Message      *openAIChatCompletionMessage `json:"message,omitempty"`
+	Delta        *openAIChatCompletionMessage `json:"delta,omitempty"`
+	FinishReason *string                      `json:"finish_reason"`
+}
+
+// openAIUsage is the token usage of a response
+type openAIUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// openAIChatCompletionResponse is the response (or stream chunk) of /v1/chat/completions
+type openAIChatCompletionResponse struct {
+	ID      string                       `json:"id"`
+	Object  string                       `json:"object"`
+	Created int64                        `json:"created"`
+	Model   string                       `json:"model"`
+	Choices []openAIChatCompletionChoice `json:"choices"`
+	Usage   *openAIUsage                 `json:"usage,omitempty"`
+}
+
+// openAIEmbeddingRequest is the request of /v1/embeddings
+type openAIEmbeddingRequest struct {
+	Model string          `json:"model"`
+	Input json.RawMessage `json:"input"`
+}
+
+// openAIEmbeddingData is an embedding in the response of /v1/embeddings
+type openAIEmbeddingData struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// openAIEmbeddingResponse is the response of /v1/embeddings
+type openAIEmbeddingResponse struct {
+	Object string                `json:"object"`
+	Model  string                `json:"model"`
+	Data   []openAIEmbeddingData `json:"data"`
+	Usage  openAIUsage           `json:"usage"`
+}
+
+// openAIModel is a model in the response of /v1/models
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// openAIModelList is the response of /v1/models
+type openAIModelList struct {
+	Object string        `json:"object"`
+	Data   []openAIModel `json:"data"`
+}
+
+// openAIError is the error body in the OpenAI format
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Param   string `json:"param,omitempty"`
+	Code    string `json:"code,omitempty"`
+	// Fields are the invalid fields found by the local validation of the SDK
+	Fields []openAIFieldError `json:"fields,omitempty"`
+}
+
+// openAIFieldError is an invalid field of the request
+type openAIFieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// openAIErrorResponse wraps openAIError
+type openAIErrorResponse struct {
+	Error openAIError `json:"error"`
+}
+
+// decodeStringOrStrings decodes a JSON value that is either a string or an array of strings
+func decodeStringOrStrings(raw json.RawMessage) (out []string, err error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return
+	}
+	var s string
+	if err = json.Unmarshal(raw, &s); err == nil {
+		out = []string{s}
+		return
+	}
+	err = json.Unmarshal(raw, &out)
+	return
+}
+
+// convertMessage converts an OpenAI message to a zhipu message
+func convertMessage(m openAIMessage) (zhipu.ChatCompletionMessageType, error) {
+	role := m.Role
+	if role == "developer" {
+		role = zhipu.RoleSystem
+	}
+
+	var text string
+
+	if len(m.Content) != 0 && string(m.Content) != "null" {
+		if m.Content[0] == '[' {
+			var parts []openAIContentPart
+			if err := json.Unmarshal(m.Content, &parts); err != nil {
+				return nil, err
+			}
+			if role == zhipu.RoleUser {
+				out := zhipu.ChatCompletionMultiMessage{Role: role}
+				for _, p := range parts {
+					switch p.Type {
+					case zhipu.MultiContentTypeText:
+						out.Content = append(out.Content, zhipu.ChatCompletionMultiContent{Type: zhipu.MultiContentTypeText, Text: p.Text})
+					case zhipu.MultiContentTypeImageURL:
+						out.Content = append(out.Content, zhipu.ChatCompletionMultiContent{Type: zhipu.MultiContentTypeImageURL, ImageURL: p.ImageURL})
+					default:
+						return nil, errors.New("unsupported content part type: " + p.Type)
+					}
+				}
+				return out, nil
+			}
+			// non-user roles only carry text, flatten the parts
+			var texts []string
+			for _, p := range parts {
+				if p.Type == zhipu.MultiContentTypeText {
+					texts = append(texts, p.Text)
+				}
+			}
+			text = strings.Join(texts, "\n")
+		} else if err := json.Unmarshal(m.Content, &text); err != nil {
+			return nil, err
+		}
+	}
+
+	out := zhipu.ChatCompletionMessage{
+		Role:       role,
+		Content:    text,
+		ToolCallID: m.ToolCallID,
+	}
+	for _, tc := range m.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, zhipu.ChatCompletionToolCall{
+			ID:   tc.ID,
+			Type: zhipu.ToolTypeFunction,
+			Function: &zhipu.ChatCompletionToolCallFunction{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		})
+	}
+	return out, nil
+}
+
+// applyChatCompletionRequest applies an OpenAI chat completion request to the service
+func applyChatCompletionRequest(s *zhipu.ChatCompletionService, req openAIChatCompletionRequest) (err error) {
+	if len(req.Messages) == 0 {
+		return errors.New("messages is required")
+	}
+	for _, m := range req.Messages {
+		var msg zhipu.ChatCompletionMessageType
+		if msg, err = convertMessage(m); err != nil {
+			return
+		}
+		s.AddMessage(msg)
+	}
+	for _, t := range req.Tools {
+		if t.Type != zhipu.ToolTypeFunction {
+			return errors.New("unsupported tool type: " + t.Type)
+		}
+		s.AddTool(t.Function)
+	}
+	if len(req.ToolChoice) != 0 {
+		// zhipu only supports "auto", object choices are downgraded to it
+		s.SetToolChoice(zhipu.ToolChoiceAuto)
+	}
+	if req.Temperature != nil {
+		s.SetTemperature(*req.Temperature)
+	}
+	if req.TopP != nil {
+		s.SetTopP(*req.TopP)
+	}
+	if req.MaxCompletionTokens != nil {
+		s.SetMaxTokens(*req.MaxCompletionTokens)
+	} else if req.MaxTokens != nil {
+		s.SetMaxTokens(*req.MaxTokens)
+	}
+	var stop []string
+	if stop, err = decodeStringOrStrings(req.Stop); err != nil {
+		return
+	}
+	if len(stop) != 0 {
+		s.SetStop(stop...)
+	}
+	if req.User != "" {
+		s.SetUserID(req.User)
+	}
+	if req.ResponseFormat != nil && req.ResponseFormat.Type != "" {
+		s.SetResponseFormat(req.ResponseFormat.Type)
+	}
+	return
+}
+
+// convertToolCalls converts zhipu tool calls to OpenAI tool calls, non-function calls are dropped
+func convertToolCalls(in []zhipu.ChatCompletionToolCall, indexed bool) (out []openAIToolCall) {
+	for i, tc := range in {
+		if tc.Function == nil {
+			continue
+		}
+		item := openAIToolCall{
+			ID:   tc.ID,
+			Type: zhipu.ToolTypeFunction,
+			Function: openAIToolCallFunction{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		}
+		if indexed {
+			item.Index = zhipu.Ptr(i)
+		}
+		out = append(out, item)
+	}
+	return
+}
+
+// convertUsage converts zhipu usage to OpenAI usage
+func convertUsage(u zhipu.ChatCompletionUsage) *openAIUsage {
+	if u.TotalTokens == 0 && u.PromptTokens == 0 && u.CompletionTokens == 0 {
+		return nil
+	}
+	return &openAIUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
+// convertChatCompletionResponse converts a zhipu response to an OpenAI response
+func convertChatCompletionResponse(res zhipu.ChatCompletionResponse) openAIChatCompletionResponse {
+	out := openAIChatCompletionResponse{
+		ID:      res.ID,
+		Object:  "chat.completion",
+		Created: res.Created,
+		Model:   res.Model,
+		Choices: []openAIChatCompletionChoice{},
+		Usage:   convertUsage(res.Usage),
+	}
+	if out.Usage == nil {
+		out.Usage = &openAIUsage{}
+	}
+	for _, c := range res.Choices {
+		choice := openAIChatCompletionChoice{
+			Index: c.Index,
+			Message: &openAIChatCompletionMessage{
+				Role:      zhipu.RoleAssistant,
+				Content:   c.Message.Content,
+				ToolCalls: convertToolCalls(c.Message.ToolCalls, false),
+			},
+		}
+		if c.FinishReason != "" {
+			choice.FinishReason = zhipu.Ptr(c.FinishReason)
+		}
+		out.Choices = append(out.Choices, choice)
+	}
+	return out
+}
+
+// convertChatCompletionChunk converts a zhipu stream chunk to an OpenAI stream chunk
+func convertChatCompletionChunk(chunk zhipu.ChatCompletionResponse) openAIChatCompletionResponse {
+	out := openAIChatCompletionResponse{
+		ID:      chunk.ID,
+		Object:  "chat.completion.chunk",
+		Created: chunk.Created,
+		Model:   chunk.Model,
+		Choices: []openAIChatCompletionChoice{},
+		Usage:   convertUsage(chunk.Usage),
+	}
+	for _, c := range chunk.Choices {
+		choice := openAIChatCompletionChoice{
+			Index: c.Index,
+			Delta: &openAIChatCompletionMessage{
+				Role:      c.Delta.Role,
+				Content:   c.Delta.Content,
+				ToolCalls: convertToolCalls(c.Delta.ToolCalls, true),
+			},
+		}
+		if c.FinishReason != "" {
+			choice.FinishReason = zhipu.Ptr(c.FinishReason)
+		}
+		out.Choices = append(out.Choices, choice)
+	}
+	return out
+}
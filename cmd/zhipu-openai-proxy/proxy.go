@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yankeguo/zhipu"
+)
+
+// proxy is an OpenAI compatible http handler forwarding to zhipu
+type proxy struct {
+	baseURL     string
+	fallbackKey string
+	models      []string
+
+	clients *clientCache
+}
+
+// newProxy creates a new proxy, fallbackKey is used when a request carries no bearer token, empty to require one,
+// maxClients bounds the clients kept for the tenants
+func newProxy(baseURL, fallbackKey string, models []string, maxClients int) *proxy {
+	return &proxy{baseURL: baseURL, fallbackKey: fallbackKey, models: models, clients: newClientCache(maxClients)}
+}
+
+func (p *proxy) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", p.handleChatCompletions)
+	mux.HandleFunc("POST /v1/embeddings", p.handleEmbeddings)
+	mux.HandleFunc("GET /v1/models", p.handleModels)
+	return mux
+}
+
+// errBearerRequired is the error of an Authorization header with another scheme than Bearer
+var errBearerRequired = errors.New("authorization header must be a bearer token")
+
+// client returns the zhipu client of the tenant identified by the bearer token of the request,
+// the fallback key is only used if the Authorization header is absent
+func (p *proxy) client(r *http.Request) (client *zhipu.Client, err error) {
+	apiKey := p.fallbackKey
+	if header := r.Header.Get("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			err = errBearerRequired
+			return
+		}
+		apiKey = strings.TrimSpace(token)
+	}
+	if apiKey == "" {
+		err = zhipu.ErrAPIKeyMissing
+		return
+	}
+	return p.clients.get(apiKey, func() (*zhipu.Client, error) {
+		opts := []zhipu.ClientOption{zhipu.WithAPIKey(apiKey)}
+		if p.baseURL != "" {
+			opts = append(opts, zhipu.WithBaseURL(p.baseURL))
+		}
+		return zhipu.NewClient(opts...)
+	})
+}
+
+func (p *proxy) handleChatCompletions(rw http.ResponseWriter, r *http.Request) {
+	client, err := p.client(r)
+	if err != nil {
+		writeError(rw, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req openAIChatCompletionRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	s := client.ChatCompletion(req.Model)
+	if err = applyChatCompletionRequest(s, req); err != nil {
+		writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	if !req.Stream {
+		var (
+			res  zhipu.ChatCompletionResponse
+			meta zhipu.ResponseMeta
+		)
+		if res, err = s.Do(r.Context(), zhipu.WithResponseMeta(&meta)); err != nil {
+			writeError(rw, upstreamStatus(err, meta), err)
+			return
+		}
+		writeJSON(rw, http.StatusOK, convertChatCompletionResponse(res))
+		return
+	}
+
+	flusher, _ := rw.(http.Flusher)
+
+	started := false
+
+	s.SetStreamHandler(func(chunk zhipu.ChatCompletionResponse) error {
+		if !started {
+			started = true
+			rw.Header().Set("Content-Type", "text/event-stream")
+			rw.Header().Set("Cache-Control", "no-cache")
+			rw.WriteHeader(http.StatusOK)
+		}
+		if err := writeEvent(rw, convertChatCompletionChunk(chunk)); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	var meta zhipu.ResponseMeta
+	if _, err = s.Do(r.Context(), zhipu.WithResponseMeta(&meta)); err != nil {
+		if !started {
+			writeError(rw, upstreamStatus(err, meta), err)
+			return
+		}
+		_ = writeEvent(rw, openAIErrorResponse{Error: openAIError{Message: zhipu.GetAPIErrorMessage(err), Type: "upstream_error", Code: zhipu.GetAPIErrorCode(err)}})
+	}
+	if !started {
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.WriteHeader(http.StatusOK)
+	}
+	_, _ = rw.Write([]byte("data: [DONE]\n\n"))
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func (p *proxy) handleEmbeddings(rw http.ResponseWriter, r *http.Request) {
+	client, err := p.client(r)
+	if err != nil {
+		writeError(rw, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req openAIEmbeddingRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	var inputs []string
+	if inputs, err = decodeStringOrStrings(req.Input); err != nil {
+		writeError(rw, http.StatusBadRequest, errors.New("input must be a string or an array of strings"))
+		return
+	}
+	if len(inputs) == 0 {
+		writeError(rw, http.StatusBadRequest, errors.New("input is required"))
+		return
+	}
+
+	out := openAIEmbeddingResponse{Object: "list", Model: req.Model}
+
+	// the embedding service accepts a single input, fan out sequentially
+	for i, input := range inputs {
+		var (
+			res  zhipu.EmbeddingResponse
+			meta zhipu.ResponseMeta
+		)
+		if res, err = client.Embedding(req.Model).SetInput(input).Do(r.Context(), zhipu.WithResponseMeta(&meta)); err != nil {
+			writeError(rw, upstreamStatus(err, meta), err)
+			return
+		}
+		for _, d := range res.Data {
+			out.Data = append(out.Data, openAIEmbeddingData{Object: "embedding", Index: i, Embedding: d.Embedding})
+		}
+		if res.Model != "" {
+			out.Model = res.Model
+		}
+		out.Usage.PromptTokens += res.Usage.PromptTokens
+		out.Usage.TotalTokens += res.Usage.TotalTokens
+	}
+
+	writeJSON(rw, http.StatusOK, out)
+}
+
+func (p *proxy) handleModels(rw http.ResponseWriter, r *http.Request) {
+	out := openAIModelList{Object: "list", Data: []openAIModel{}}
+	created := time.Now().Unix()
+	for _, id := range p.models {
+		out.Data = append(out.Data, openAIModel{ID: id, Object: "model", Created: created, OwnedBy: "zhipu"})
+	}
+	writeJSON(rw, http.StatusOK, out)
+}
+
+// upstreamStatus maps a zhipu error to the http status code an OpenAI client expects, with the metadata of the
+// upstream response, requests rejected by the local validation of the SDK are bad requests
+func upstreamStatus(err error, meta zhipu.ResponseMeta) int {
+	var (
+		verr *zhipu.ValidationError
+		merr *zhipu.ModelValidationError
+	)
+	if errors.As(err, &verr) || errors.As(err, &merr) {
+		return http.StatusBadRequest
+	}
+	switch zhipu.GetAPIErrorCode(err) {
+	case "1000", "1001", "1002", "1003", "1004":
+		return http.StatusUnauthorized
+	case "1113", "1302", "1303", "1304", "1305":
+		return http.StatusTooManyRequests
+	case "1211":
+		return http.StatusNotFound
+	case "1210", "1213", "1214", "1301":
+		return http.StatusBadRequest
+	case "":
+		if meta.StatusCode == http.StatusTooManyRequests {
+			return http.StatusTooManyRequests
+		}
+	}
+	return http.StatusBadGateway
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(v)
+}
+
+func writeEvent(rw http.ResponseWriter, v any) (err error) {
+	var buf []byte
+	if buf, err = json.Marshal(v); err != nil {
+		return
+	}
+	buf = append([]byte("data: "), buf...)
+	buf = append(buf, '\n', '\n')
+	_, err = rw.Write(buf)
+	return
+}
+
+func writeError(rw http.ResponseWriter, status int, err error) {
+	typ := "invalid_request_error"
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+		typ = "upstream_error"
+	}
+	if status == http.StatusUnauthorized {
+		typ = "authentication_error"
+	}
+	out := openAIError{
+		Message: zhipu.GetAPIErrorMessage(err),
+		Type:    typ,
+		Code:    zhipu.GetAPIErrorCode(err),
+	}
+	var (
+		verr *zhipu.ValidationError
+		merr *zhipu.ModelValidationError
+	)
+	if errors.As(err, &verr) {
+		out.Code = "invalid_request"
+		for _, f := range verr.Fields {
+			out.Fields = append(out.Fields, openAIFieldError{Field: f.Field, Message: f.Message})
+		}
+		if len(verr.Fields) != 0 {
+			out.Param = verr.Fields[0].Field
+		}
+	} else if errors.As(err, &merr) {
+		out.Code = "model_not_supported"
+		for _, problem := range merr.Problems {
+			out.Fields = append(out.Fields, openAIFieldError{Message: problem})
+		}
+	}
+	writeJSON(rw, status, openAIErrorResponse{Error: out})
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+	"github.com/yankeguo/zhipu"
+)
+
+type fakeUpstream struct {
+	mu     sync.Mutex
+	keyIDs []string
+	bodies []map[string]any
+}
+
+func (f *fakeUpstream) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	token, _, _ := jwt.NewParser().ParseUnverified(r.Header.Get("Authorization"), jwt.MapClaims{})
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	f.mu.Lock()
+	if token != nil {
+		f.keyIDs = append(f.keyIDs, token.Claims.(jwt.MapClaims)["api_key"].(string))
+	}
+	f.bodies = append(f.bodies, body)
+	f.mu.Unlock()
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/chat/completions":
+		if body["model"] == "limited" {
+			rw.Header().Set("Content-Type", "text/plain")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(rw, "too many requests")
+			return
+		}
+		if body["model"] == "missing" {
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte(`{"error":{"code":"1211","message":"模型不存在"}}`))
+			return
+		}
+		if body["stream"] == true {
+			rw.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(rw, `data: {"id":"1","created":1,"model":"glm-4-flash","choices":[{"index":0,"delta":{"role":"assistant","content":"你"}}]}`+"\n\n")
+			_, _ = io.WriteString(rw, `data: {"id":"1","created":1,"model":"glm-4-flash","choices":[{"index":0,"delta":{"role":"assistant","content":"好"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`+"\n\n")
+			_, _ = io.WriteString(rw, "data: [DONE]\n\n")
+			return
+		}
+		_, _ = io.WriteString(rw, `{"id":"1","created":1,"model":"glm-4-flash","choices":[{"index":0,"finish_reason":"tool_calls","message":{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"深圳\"}"}}]}}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+	case "/embeddings":
+		_, _ = io.WriteString(rw, `{"model":"embedding-2","object":"list","data":[{"index":0,"object":"embedding","embedding":[0.1,0.2]}],"usage":{"prompt_tokens":2,"total_tokens":2}}`)
+	default:
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestProxy(t *testing.T) (*fakeUpstream, *httptest.Server) {
+	return newTestProxyWithFallback(t, "fallback.secret")
+}
+
+func newTestProxyWithFallback(t *testing.T, fallbackKey string) (*fakeUpstream, *httptest.Server) {
+	upstream := &fakeUpstream{}
+	us := httptest.NewServer(upstream)
+	t.Cleanup(us.Close)
+	ps := httptest.NewServer(newProxy(us.URL, fallbackKey, []string{"glm-4-flash"}, 10).Handler())
+	t.Cleanup(ps.Close)
+	return upstream, ps
+}
+
+func doProxy(t *testing.T, ps *httptest.Server, method, path, key, body string) *http.Response {
+	req, err := http.NewRequest(method, ps.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func TestProxyChatCompletions(t *testing.T) {
+	upstream, ps := newTestProxy(t)
+
+	res := doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "tenant.secret", `{
+		"model":"glm-4-flash",
+		"messages":[
+			{"role":"developer","content":"be brief"},
+			{"role":"user","content":[{"type":"text","text":"天气"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}
+		],
+		"tools":[{"type":"function","function":{"name":"get_weather","description":"get weather","parameters":{"type":"object"}}}],
+		"stop":"\n",
+		"max_completion_tokens":64
+	}`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var out openAIChatCompletionResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&out))
+	require.Equal(t, "chat.completion", out.Object)
+	require.Len(t, out.Choices, 1)
+	require.Equal(t, "tool_calls", *out.Choices[0].FinishReason)
+	require.Len(t, out.Choices[0].Message.ToolCalls, 1)
+	require.Equal(t, `"{\"city\":\"深圳\"}"`, string(out.Choices[0].Message.ToolCalls[0].Function.Arguments))
+	require.Equal(t, int64(5), out.Usage.TotalTokens)
+
+	require.Equal(t, []string{"tenant"}, upstream.keyIDs)
+	body := upstream.bodies[0]
+	require.Equal(t, []any{"\n"}, body["stop"])
+	require.Equal(t, float64(64), body["max_tokens"])
+	messages := body["messages"].([]any)
+	require.Equal(t, "system", messages[0].(map[string]any)["role"])
+	require.Len(t, messages[1].(map[string]any)["content"], 2)
+	require.Len(t, body["tools"], 1)
+}
+
+func TestProxyChatCompletionsStream(t *testing.T) {
+	upstream, ps := newTestProxy(t)
+
+	res := doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "", `{"model":"glm-4-flash","stream":true,"messages":[{"role":"user","content":"你好"}]}`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	var (
+		content string
+		events  int
+		done    bool
+	)
+	br := bufio.NewScanner(res.Body)
+	for br.Scan() {
+		line := bytes.TrimPrefix(br.Bytes(), []byte("data: "))
+		if len(line) == 0 {
+			continue
+		}
+		if string(line) == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk openAIChatCompletionResponse
+		require.NoError(t, json.Unmarshal(line, &chunk))
+		require.Equal(t, "chat.completion.chunk", chunk.Object)
+		content += chunk.Choices[0].Delta.Content
+		events++
+	}
+	require.True(t, done)
+	require.Equal(t, 2, events)
+	require.Equal(t, "你好", content)
+	require.Equal(t, []string{"fallback"}, upstream.keyIDs)
+}
+
+func TestProxyChatCompletionsError(t *testing.T) {
+	_, ps := newTestProxy(t)
+
+	res := doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "", `{"model":"missing","messages":[{"role":"user","content":"你好"}]}`)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	var out openAIErrorResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&out))
+	require.Equal(t, "1211", out.Error.Code)
+
+	res = doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "", `{"model":"glm-4-flash","messages":[]}`)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "malformed", `{"model":"glm-4-flash","messages":[{"role":"user","content":"你好"}]}`)
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestProxyEmbeddings(t *testing.T) {
+	upstream, ps := newTestProxy(t)
+
+	res := doProxy(t, ps, http.MethodPost, "/v1/embeddings", "", `{"model":"embedding-2","input":["a","b"]}`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var out openAIEmbeddingResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&out))
+	require.Len(t, out.Data, 2)
+	require.Equal(t, 1, out.Data[1].Index)
+	require.Equal(t, int64(4), out.Usage.TotalTokens)
+	require.Len(t, upstream.bodies, 2)
+}
+
+func TestProxyModels(t *testing.T) {
+	_, ps := newTestProxy(t)
+
+	res := doProxy(t, ps, http.MethodGet, "/v1/models", "", "")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var out openAIModelList
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&out))
+	require.Len(t, out.Data, 1)
+	require.Equal(t, "glm-4-flash", out.Data[0].ID)
+}
+
+func TestProxyBearerRequired(t *testing.T) {
+	upstream, ps := newTestProxyWithFallback(t, "")
+
+	res := doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "", `{"model":"glm-4-flash","messages":[{"role":"user","content":"你好"}]}`)
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Empty(t, upstream.bodies)
+
+	res = doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "tenant.secret", `{"model":"glm-4-flash","messages":[{"role":"user","content":"你好"}]}`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, []string{"tenant"}, upstream.keyIDs)
+
+	// other schemes are rejected, even with a fallback key
+	upstream, ps = newTestProxyWithFallback(t, "fallback.secret")
+	for _, header := range []string{"Basic dGVuYW50OnNlY3JldA==", "Token tenant.secret"} {
+		req, err := http.NewRequest(http.MethodPost, ps.URL+"/v1/chat/completions", strings.NewReader(`{"model":"glm-4-flash","messages":[{"role":"user","content":"你好"}]}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", header)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	}
+	require.Empty(t, upstream.bodies)
+
+	// the fallback key is used if the header is absent
+	res = doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "", `{"model":"glm-4-flash","messages":[{"role":"user","content":"你好"}]}`)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, []string{"fallback"}, upstream.keyIDs)
+}
+
+func TestClientCache(t *testing.T) {
+	c := newClientCache(2)
+
+	var created int
+	create := func() (*zhipu.Client, error) {
+		created++
+		return zhipu.NewClient(zhipu.WithAPIKey("a.b"))
+	}
+
+	a, err := c.get("a.secret", create)
+	require.NoError(t, err)
+	_, err = c.get("b.secret", create)
+	require.NoError(t, err)
+
+	// a is the most recently used, b is evicted
+	a2, err := c.get("a.secret", create)
+	require.NoError(t, err)
+	require.Same(t, a, a2)
+	_, err = c.get("c.secret", create)
+	require.NoError(t, err)
+	require.Equal(t, 2, c.len())
+	require.Equal(t, 3, created)
+
+	_, err = c.get("b.secret", create)
+	require.NoError(t, err)
+	require.Equal(t, 4, created)
+
+	// the api keys are not kept, only their hashes
+	require.Equal(t, clientCacheKey("b.secret"), c.order.Front().Value.(*clientCacheEntry).key)
+	for key := range c.entries {
+		require.NotContains(t, key, "secret")
+	}
+}
+
+func TestProxyValidationError(t *testing.T) {
+	upstream, ps := newTestProxy(t)
+
+	res := doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "tenant.secret", `{"model":"glm-4-flash","temperature":0,"messages":[{"role":"user","content":"你好"}]}`)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	var out openAIErrorResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&out))
+	require.Equal(t, "invalid_request_error", out.Error.Type)
+	require.Equal(t, "temperature", out.Error.Param)
+	require.Len(t, out.Error.Fields, 1)
+	require.Equal(t, "temperature", out.Error.Fields[0].Field)
+	require.Empty(t, upstream.bodies)
+
+	require.Equal(t, http.StatusBadRequest, upstreamStatus(fmt.Errorf("wrapped: %w", &zhipu.ModelValidationError{Model: "glm-4-flash", Problems: []string{"thinking: thinking mode not supported"}}), zhipu.ResponseMeta{}))
+}
+
+func TestProxyRateLimited(t *testing.T) {
+	_, ps := newTestProxy(t)
+
+	// the status of the upstream response is kept without an api error code, streams included
+	for _, stream := range []bool{false, true} {
+		res := doProxy(t, ps, http.MethodPost, "/v1/chat/completions", "tenant.secret", fmt.Sprintf(`{"model":"limited","stream":%t,"messages":[{"role":"user","content":"你好"}]}`, stream))
+		require.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	}
+}
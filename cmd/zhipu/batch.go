@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yankeguo/zhipu"
+)
+
+func (a *app) printBatchItems(v any, items []zhipu.BatchItem) error {
+	return a.print(v, []string{"ID", "STATUS", "ENDPOINT", "TOTAL", "COMPLETED", "FAILED", "OUTPUT FILE", "CREATED"}, func(add func(cells ...any)) {
+		for _, item := range items {
+			add(
+				item.ID, item.Status, item.Endpoint,
+				item.RequestCounts.Total, item.RequestCounts.Completed, item.RequestCounts.Failed,
+				item.OutputFileID, unixTime(item.CreatedAt),
+			)
+		}
+	})
+}
+
+func runBatchSubmit(ctx context.Context, a *app, args []string) (err error) {
+	var optEndpoint string
+
+	fs := a.newFlagSet("batch submit")
+	fs.StringVar(&optEndpoint, "endpoint", zhipu.BatchEndpointV4ChatCompletions, "batch endpoint")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: zhipu batch submit [-endpoint e] <file.jsonl>")
+	}
+
+	var file zhipu.FileCreateResponse
+	if file, err = a.client.FileCreate(zhipu.FilePurposeBatch).SetLocalFile(fs.Arg(0)).Do(ctx); err != nil {
+		return
+	}
+
+	var res zhipu.BatchItem
+	if res, err = a.client.BatchCreate().
+		SetInputFileID(file.ID).
+		SetEndpoint(optEndpoint).
+		SetCompletionWindow(zhipu.BatchCompletionWindow24h).
+		Do(ctx); err != nil {
+		return
+	}
+
+	return a.printBatchItems(res, []zhipu.BatchItem{res})
+}
+
+func runBatchStatus(ctx context.Context, a *app, args []string) (err error) {
+	if len(args) == 0 {
+		var res zhipu.BatchListResponse
+		if res, err = a.client.BatchList().Do(ctx); err != nil {
+			return
+		}
+		return a.printBatchItems(res, res.Data)
+	}
+
+	var items []zhipu.BatchItem
+	for _, id := range args {
+		var res zhipu.BatchItem
+		if res, err = a.client.BatchGet(id).Do(ctx); err != nil {
+			return
+		}
+		items = append(items, res)
+	}
+	return a.printBatchItems(items, items)
+}
+
+func runBatchResults(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optOut    string
+		optErrors bool
+	)
+
+	fs := a.newFlagSet("batch results")
+	fs.StringVar(&optOut, "out", "", "output file, defaults to stdout")
+	fs.BoolVar(&optErrors, "errors", false, "download the error file instead of the output file")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: zhipu batch results [-out file] [-errors] <batch-id>")
+	}
+
+	var res zhipu.BatchItem
+	if res, err = a.client.BatchGet(fs.Arg(0)).Do(ctx); err != nil {
+		return
+	}
+
+	fileID := res.OutputFileID
+	if optErrors {
+		fileID = res.ErrorFileID
+	}
+	if fileID == "" {
+		return errors.New("batch has no result file yet, status: " + res.Status)
+	}
+
+	s := a.client.FileDownload(fileID)
+	if optOut != "" {
+		s.SetOutputFile(optOut)
+	} else {
+		s.SetOutput(a.stdout)
+	}
+	return s.Do(ctx)
+}
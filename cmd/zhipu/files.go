@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yankeguo/zhipu"
+)
+
+func runFilesList(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optPurpose     string
+		optKnowledgeID string
+		optLimit       int
+	)
+
+	fs := a.newFlagSet("files ls")
+	fs.StringVar(&optPurpose, "purpose", zhipu.FilePurposeFineTune, "file purpose, fine-tune, batch or retrieval")
+	fs.StringVar(&optKnowledgeID, "knowledge-id", "", "knowledge id, required for retrieval")
+	fs.IntVar(&optLimit, "limit", 0, "max number of files")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	s := a.client.FileList(optPurpose)
+	if optKnowledgeID != "" {
+		s.SetKnowledgeID(optKnowledgeID)
+	}
+	if optLimit > 0 {
+		s.SetLimit(optLimit)
+	}
+
+	var res zhipu.FileListResponse
+	if res, err = s.Do(ctx); err != nil {
+		return
+	}
+
+	if optPurpose == zhipu.FilePurposeRetrieval {
+		return a.print(res.FileListKnowledgeResponse, []string{"ID", "NAME", "LENGTH", "WORDS", "EMBEDDING"}, func(add func(cells ...any)) {
+			for _, item := range res.List {
+				add(item.ID, item.Name, item.Length, item.WordNum, item.EmbeddingStat)
+			}
+		})
+	}
+
+	return a.print(res.FileListFineTuneResponse, []string{"ID", "FILENAME", "PURPOSE", "BYTES", "CREATED"}, func(add func(cells ...any)) {
+		for _, item := range res.Data {
+			add(item.ID, item.Filename, item.Purpose, item.Bytes, unixTime(item.CreatedAt))
+		}
+	})
+}
+
+func runFilesUpload(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optPurpose     string
+		optKnowledgeID string
+	)
+
+	fs := a.newFlagSet("files upload")
+	fs.StringVar(&optPurpose, "purpose", zhipu.FilePurposeFineTune, "file purpose, fine-tune, batch or retrieval")
+	fs.StringVar(&optKnowledgeID, "knowledge-id", "", "knowledge id, required for retrieval")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: zhipu files upload [-purpose p] [-knowledge-id id] <file>...")
+	}
+
+	var results []zhipu.FileCreateResponse
+
+	for _, file := range fs.Args() {
+		s := a.client.FileCreate(optPurpose).SetLocalFile(file)
+		if optKnowledgeID != "" {
+			s.SetKnowledgeID(optKnowledgeID)
+		}
+		var res zhipu.FileCreateResponse
+		if res, err = s.Do(ctx); err != nil {
+			return
+		}
+		results = append(results, res)
+	}
+
+	return a.print(results, []string{"ID", "FILENAME", "ERROR"}, func(add func(cells ...any)) {
+		for _, res := range results {
+			if res.ID != "" {
+				add(res.ID, res.Filename, "")
+			}
+			for _, info := range res.SuccessInfos {
+				add(info.DocumentID, info.Filename, "")
+			}
+			for _, info := range res.FailedInfos {
+				add("", info.Filename, info.FailReason)
+			}
+		}
+	})
+}
+
+func runFilesDelete(ctx context.Context, a *app, args []string) (err error) {
+	if len(args) == 0 {
+		return errors.New("usage: zhipu files rm <file-id>...")
+	}
+	for _, id := range args {
+		if err = a.client.FileDelete(id).Do(ctx); err != nil {
+			return
+		}
+	}
+	return a.print(args, []string{"DELETED"}, func(add func(cells ...any)) {
+		for _, id := range args {
+			add(id)
+		}
+	})
+}
+
+func runFilesDownload(ctx context.Context, a *app, args []string) (err error) {
+	var optOut string
+
+	fs := a.newFlagSet("files download")
+	fs.StringVar(&optOut, "out", "", "output file, defaults to stdout")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: zhipu files download [-out file] <file-id>")
+	}
+
+	s := a.client.FileDownload(fs.Arg(0))
+	if optOut != "" {
+		s.SetOutputFile(optOut)
+	} else {
+		s.SetOutput(a.stdout)
+	}
+	return s.Do(ctx)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yankeguo/zhipu"
+)
+
+func runFineTuneCreate(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optModel          string
+		optTrainingFile   string
+		optValidationFile string
+		optSuffix         string
+		optEpochs         int
+		optBatchSize      int
+		optLearningRate   optionalFloat
+		optMethod         string
+		optTrainingType   string
+		optBeta           optionalFloat
+	)
+
+	fs := a.newFlagSet("ft create")
+	fs.StringVar(&optModel, "model", "", "base model")
+	fs.StringVar(&optTrainingFile, "training-file", "", "training file id")
+	fs.StringVar(&optValidationFile, "validation-file", "", "validation file id")
+	fs.StringVar(&optSuffix, "suffix", "", "suffix of the fine tuned model")
+	fs.IntVar(&optEpochs, "epochs", 0, "number of epochs, auto if not set")
+	fs.IntVar(&optBatchSize, "batch-size", 0, "batch size, auto if not set")
+	fs.Var(&optLearningRate, "learning-rate-multiplier", "learning rate multiplier, auto if not set")
+	fs.StringVar(&optMethod, "method", "", "fine tune method, supervised or dpo")
+	fs.StringVar(&optTrainingType, "training-type", "", "training type, lora or full")
+	fs.Var(&optBeta, "beta", "beta of the dpo method, auto if not set")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if optModel == "" || optTrainingFile == "" {
+		return errors.New("usage: zhipu ft create -model m -training-file id [-validation-file id] [-suffix s]")
+	}
+
+	s := a.client.FineTuneCreate(optModel).SetTrainingFile(optTrainingFile)
+	if optValidationFile != "" {
+		s.SetValidationFile(optValidationFile)
+	}
+	if optSuffix != "" {
+		s.SetSuffix(optSuffix)
+	}
+	if optEpochs > 0 {
+		s.SetNEpochs(optEpochs)
+	}
+	if optBatchSize > 0 {
+		s.SetBatchSize(optBatchSize)
+	}
+	if optLearningRate.value != nil {
+		s.SetLearningRateMultiplier(*optLearningRate.value)
+	}
+	if optMethod != "" {
+		s.SetMethod(optMethod)
+	}
+	if optTrainingType != "" {
+		s.SetTrainingType(optTrainingType)
+	}
+	if optBeta.value != nil {
+		s.SetBeta(*optBeta.value)
+	}
+
+	var res zhipu.FineTuneCreateResponse
+	if res, err = s.Do(ctx); err != nil {
+		return
+	}
+
+	return a.printFineTuneItems(res, []zhipu.FineTuneItem{res})
+}
+
+func (a *app) printFineTuneItems(v any, items []zhipu.FineTuneItem) error {
+	return a.print(v, []string{"ID", "STATUS", "TRAINING FILE", "FINE TUNED MODEL"}, func(add func(cells ...any)) {
+		for _, item := range items {
+			add(item.ID, item.Status, item.TrainingFile, item.FineTunedModel)
+		}
+	})
+}
+
+func runFineTuneList(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optLimit int
+		optAfter string
+	)
+
+	fs := a.newFlagSet("ft ls")
+	fs.IntVar(&optLimit, "limit", 0, "max number of jobs")
+	fs.StringVar(&optAfter, "after", "", "list jobs after this job id")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	s := a.client.FineTuneList()
+	if optLimit > 0 {
+		s.SetLimit(optLimit)
+	}
+	if optAfter != "" {
+		s.SetAfter(optAfter)
+	}
+
+	var res zhipu.FineTuneListResponse
+	if res, err = s.Do(ctx); err != nil {
+		return
+	}
+
+	return a.printFineTuneItems(res, res.Data)
+}
+
+func runFineTuneEvents(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optLimit int
+		optAfter string
+	)
+
+	fs := a.newFlagSet("ft events")
+	fs.IntVar(&optLimit, "limit", 0, "max number of events")
+	fs.StringVar(&optAfter, "after", "", "list events after this event id")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: zhipu ft events [-limit n] [-after id] <job-id>")
+	}
+
+	s := a.client.FineTuneEventList(fs.Arg(0))
+	if optLimit > 0 {
+		s.SetLimit(optLimit)
+	}
+	if optAfter != "" {
+		s.SetAfter(optAfter)
+	}
+
+	var res zhipu.FineTuneEventListResponse
+	if res, err = s.Do(ctx); err != nil {
+		return
+	}
+
+	return a.print(res, []string{"ID", "CREATED", "LEVEL", "TYPE", "MESSAGE"}, func(add func(cells ...any)) {
+		for _, item := range res.Data {
+			add(item.ID, unixTime(item.CreatedAt), item.Level, item.Type, item.Message)
+		}
+	})
+}
+
+func runFineTuneCancel(ctx context.Context, a *app, args []string) (err error) {
+	if len(args) != 1 {
+		return errors.New("usage: zhipu ft cancel <job-id>")
+	}
+
+	var res zhipu.FineTuneItem
+	if res, err = a.client.FineTuneCancel(args[0]).Do(ctx); err != nil {
+		return
+	}
+
+	return a.printFineTuneItems(res, []zhipu.FineTuneItem{res})
+}
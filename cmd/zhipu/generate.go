@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yankeguo/zhipu"
+)
+
+// optionalFloat is a float flag that tracks whether it was set
+type optionalFloat struct {
+	value *float64
+}
+
+func (f *optionalFloat) String() string {
+	if f.value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f.value, 'f', -1, 64)
+}
+
+func (f *optionalFloat) Set(s string) error {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	f.value = &v
+	return nil
+}
+
+// readPrompt joins the positional arguments, or reads stdin when there are none
+func (a *app) readPrompt(args []string) (string, error) {
+	if len(args) != 0 {
+		return strings.Join(args, " "), nil
+	}
+	buf, err := io.ReadAll(a.stdin)
+	if err != nil {
+		return "", err
+	}
+	prompt := strings.TrimSpace(string(buf))
+	if prompt == "" {
+		return "", errors.New("prompt is required, as arguments or from stdin")
+	}
+	return prompt, nil
+}
+
+func runChat(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optModel       string
+		optSystem      string
+		optStream      bool
+		optMaxTokens   int
+		optTemperature optionalFloat
+		optTopP        optionalFloat
+	)
+
+	fs := a.newFlagSet("chat")
+	fs.StringVar(&optModel, "m", "glm-4-flash", "model")
+	fs.StringVar(&optSystem, "system", "", "system prompt")
+	fs.BoolVar(&optStream, "stream", true, "stream the output, ignored with -o json")
+	fs.IntVar(&optMaxTokens, "max-tokens", 0, "max tokens")
+	fs.Var(&optTemperature, "temperature", "temperature")
+	fs.Var(&optTopP, "top-p", "top p")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	var prompt string
+	if prompt, err = a.readPrompt(fs.Args()); err != nil {
+		return
+	}
+
+	s := a.client.ChatCompletion(optModel)
+	if optSystem != "" {
+		s.AddMessage(zhipu.ChatCompletionMessage{Role: zhipu.RoleSystem, Content: optSystem})
+	}
+	s.AddMessage(zhipu.ChatCompletionMessage{Role: zhipu.RoleUser, Content: prompt})
+	if optMaxTokens > 0 {
+		s.SetMaxTokens(optMaxTokens)
+	}
+	if optTemperature.value != nil {
+		s.SetTemperature(*optTemperature.value)
+	}
+	if optTopP.value != nil {
+		s.SetTopP(*optTopP.value)
+	}
+
+	streaming := optStream && a.output == outputTable
+
+	if streaming {
+		s.SetStreamHandler(func(chunk zhipu.ChatCompletionResponse) error {
+			for _, c := range chunk.Choices {
+				if _, err := io.WriteString(a.stdout, c.Delta.Content); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	var res zhipu.ChatCompletionResponse
+	if res, err = s.Do(ctx); err != nil {
+		return
+	}
+
+	if a.output == outputJSON {
+		return a.printJSON(res)
+	}
+	if !streaming && len(res.Choices) != 0 {
+		_, err = io.WriteString(a.stdout, res.Choices[0].Message.Content)
+	}
+	fmt.Fprintln(a.stdout)
+	return
+}
+
+func runEmbed(ctx context.Context, a *app, args []string) (err error) {
+	var optModel string
+
+	fs := a.newFlagSet("embed")
+	fs.StringVar(&optModel, "m", "embedding-2", "model")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	var input string
+	if input, err = a.readPrompt(fs.Args()); err != nil {
+		return
+	}
+
+	var res zhipu.EmbeddingResponse
+	if res, err = a.client.Embedding(optModel).SetInput(input).Do(ctx); err != nil {
+		return
+	}
+
+	return a.print(res, []string{"INDEX", "DIMENSIONS", "TOKENS"}, func(add func(cells ...any)) {
+		for _, d := range res.Data {
+			add(d.Index, len(d.Embedding), res.Usage.TotalTokens)
+		}
+	})
+}
+
+func runImage(ctx context.Context, a *app, args []string) (err error) {
+	var optModel string
+
+	fs := a.newFlagSet("image")
+	fs.StringVar(&optModel, "m", "cogview-3", "model")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	var prompt string
+	if prompt, err = a.readPrompt(fs.Args()); err != nil {
+		return
+	}
+
+	var res zhipu.ImageGenerationResponse
+	if res, err = a.client.ImageGeneration(optModel).SetPrompt(prompt).Do(ctx); err != nil {
+		return
+	}
+
+	return a.print(res, []string{"URL"}, func(add func(cells ...any)) {
+		for _, d := range res.Data {
+			add(d.URL)
+		}
+	})
+}
+
+func runVideo(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optModel    string
+		optImageURL string
+		optWait     bool
+		optInterval time.Duration
+	)
+
+	fs := a.newFlagSet("video")
+	fs.StringVar(&optModel, "m", "cogvideox", "model")
+	fs.StringVar(&optImageURL, "image-url", "", "image url to generate the video from")
+	fs.BoolVar(&optWait, "wait", true, "wait for the result")
+	fs.DurationVar(&optInterval, "interval", 5*time.Second, "polling interval while waiting")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	var prompt string
+	if prompt, err = a.readPrompt(fs.Args()); err != nil {
+		return
+	}
+
+	s := a.client.VideoGeneration(optModel).SetPrompt(prompt)
+	if optImageURL != "" {
+		s.SetImageURL(optImageURL)
+	}
+
+	var task zhipu.VideoGenerationResponse
+	if task, err = s.Do(ctx); err != nil {
+		return
+	}
+
+	res := zhipu.AsyncResultResponse{ID: task.ID, Model: task.Model, TaskStatus: task.TaskStatus, RequestID: task.RequestID}
+
+	for optWait && res.TaskStatus == zhipu.VideoGenerationTaskStatusProcessing {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(optInterval):
+		}
+		if res, err = a.client.AsyncResult(task.ID).Do(ctx); err != nil {
+			return
+		}
+	}
+
+	if res.TaskStatus == zhipu.VideoGenerationTaskStatusFail {
+		err = errors.New("video generation failed: " + res.ID)
+	}
+
+	return errors.Join(a.print(res, []string{"ID", "STATUS", "URL", "COVER"}, func(add func(cells ...any)) {
+		if len(res.VideoResult) == 0 {
+			add(res.ID, res.TaskStatus, "", "")
+		}
+		for _, v := range res.VideoResult {
+			add(res.ID, res.TaskStatus, v.URL, v.CoverImageURL)
+		}
+	}), err)
+}
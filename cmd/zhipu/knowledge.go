@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yankeguo/zhipu"
+)
+
+func runKnowledgeList(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optPage int
+		optSize int
+	)
+
+	fs := a.newFlagSet("kb ls")
+	fs.IntVar(&optPage, "page", 0, "page number")
+	fs.IntVar(&optSize, "size", 0, "page size")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+
+	s := a.client.KnowledgeList()
+	if optPage > 0 {
+		s.SetPage(optPage)
+	}
+	if optSize > 0 {
+		s.SetSize(optSize)
+	}
+
+	var res zhipu.KnowledgeListResponse
+	if res, err = s.Do(ctx); err != nil {
+		return
+	}
+
+	return a.print(res, []string{"ID", "NAME", "DOCUMENTS", "WORDS", "LENGTH", "DESCRIPTION"}, func(add func(cells ...any)) {
+		for _, item := range res.List {
+			add(item.ID, item.Name, item.DocumentSize, item.WordNum, item.Length, item.Description)
+		}
+	})
+}
+
+func runKnowledgeCreate(ctx context.Context, a *app, args []string) (err error) {
+	var (
+		optName        string
+		optDescription string
+		optEmbeddingID int
+	)
+
+	fs := a.newFlagSet("kb create")
+	fs.StringVar(&optName, "name", "", "name of the knowledge")
+	fs.StringVar(&optDescription, "description", "", "description of the knowledge")
+	fs.IntVar(&optEmbeddingID, "embedding-id", zhipu.KnowledgeEmbeddingIDEmbedding2, "embedding id")
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if optName == "" {
+		return errors.New("usage: zhipu kb create -name name [-description d] [-embedding-id id]")
+	}
+
+	s := a.client.KnowledgeCreate().SetName(optName).SetEmbeddingID(optEmbeddingID)
+	if optDescription != "" {
+		s.SetDescription(optDescription)
+	}
+
+	var res zhipu.KnowledgeCreateResponse
+	if res, err = s.Do(ctx); err != nil {
+		return
+	}
+
+	return a.print(res, []string{"ID"}, func(add func(cells ...any)) {
+		add(res.ID)
+	})
+}
+
+func runKnowledgeDelete(ctx context.Context, a *app, args []string) (err error) {
+	if len(args) == 0 {
+		return errors.New("usage: zhipu kb rm <knowledge-id>...")
+	}
+	for _, id := range args {
+		if err = a.client.KnowledgeDelete(id).Do(ctx); err != nil {
+			return
+		}
+	}
+	return a.print(args, []string{"DELETED"}, func(add func(cells ...any)) {
+		for _, id := range args {
+			add(id)
+		}
+	})
+}
+
+func runKnowledgeCapacity(ctx context.Context, a *app, args []string) (err error) {
+	var res zhipu.KnowledgeCapacityResponse
+	if res, err = a.client.KnowledgeCapacity().Do(ctx); err != nil {
+		return
+	}
+
+	return a.print(res, []string{"", "WORDS", "LENGTH"}, func(add func(cells ...any)) {
+		add("used", res.Used.WordNum, res.Used.Length)
+		add("total", res.Total.WordNum, res.Total.Length)
+	})
+}
@@ -0,0 +1,127 @@
+// Command zhipu is a command-line tool for everyday operations on the Zhipu AI platform.
+//
+// Credentials are read the same way as zhipu.NewClient, from ZHIPUAI_API_KEY and ZHIPUAI_BASE_URL.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/yankeguo/zhipu"
+)
+
+// app holds the shared state of a command invocation
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	output string
+	client *zhipu.Client
+}
+
+// command is a sub command of the tool
+type command func(ctx context.Context, a *app, args []string) error
+
+// group dispatches to nested sub commands
+func group(name string, cmds map[string]command) command {
+	return func(ctx context.Context, a *app, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("usage: zhipu %s <%s>", name, strings.Join(commandNames(cmds), "|"))
+		}
+		cmd, ok := cmds[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown command: zhipu %s %s", name, args[0])
+		}
+		return cmd(ctx, a, args[1:])
+	}
+}
+
+func commandNames(cmds map[string]command) (names []string) {
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+var commands = map[string]command{
+	"chat":  runChat,
+	"embed": runEmbed,
+	"image": runImage,
+	"video": runVideo,
+	"files": group("files", map[string]command{
+		"ls":       runFilesList,
+		"upload":   runFilesUpload,
+		"rm":       runFilesDelete,
+		"download": runFilesDownload,
+	}),
+	"kb": group("kb", map[string]command{
+		"ls":       runKnowledgeList,
+		"create":   runKnowledgeCreate,
+		"rm":       runKnowledgeDelete,
+		"capacity": runKnowledgeCapacity,
+	}),
+	"ft": group("ft", map[string]command{
+		"create": runFineTuneCreate,
+		"ls":     runFineTuneList,
+		"events": runFineTuneEvents,
+		"cancel": runFineTuneCancel,
+	}),
+	"batch": group("batch", map[string]command{
+		"submit":  runBatchSubmit,
+		"status":  runBatchStatus,
+		"results": runBatchResults,
+	}),
+}
+
+// run parses the global flags and dispatches to the sub command
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) (err error) {
+	a := &app{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("zhipu", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&a.output, "o", outputTable, "output format, table or json")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: zhipu [-o table|json] <%s> ...\n", strings.Join(commandNames(commands), "|"))
+		fs.PrintDefaults()
+	}
+	if err = fs.Parse(args); err != nil {
+		return
+	}
+	if a.output != outputTable && a.output != outputJSON {
+		return errors.New("invalid output format: " + a.output)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		return errors.New("unknown command: " + fs.Arg(0))
+	}
+	if a.client, err = zhipu.NewClient(); err != nil {
+		return
+	}
+	return cmd(ctx, a, fs.Args()[1:])
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "zhipu:", err)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yankeguo/zhipu"
+)
+
+func newTestServer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /chat/completions", func(rw http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] == true {
+			rw.Header().Set("Content-Type", "text/event-stream")
+			_, _ = io.WriteString(rw, "data: {\"choices\":[{\"delta\":{\"content\":\"你\"}}]}\n\n")
+			_, _ = io.WriteString(rw, "data: {\"choices\":[{\"delta\":{\"content\":\"好\"},\"finish_reason\":\"stop\"}]}\n\n")
+			_, _ = io.WriteString(rw, "data: [DONE]\n\n")
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(rw, `{"id":"1","model":"glm-4-flash","choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"你好"}}]}`)
+	})
+	mux.HandleFunc("GET /fine_tuning/jobs", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(rw, `{"object":"list","data":[{"id":"ftjob-1","status":"running","training_file":"file-1"}]}`)
+	})
+	mux.HandleFunc("GET /knowledge/capacity", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(rw, `{"used":{"word_num":10,"length":20},"total":{"word_num":100,"length":200}}`)
+	})
+	mux.HandleFunc("GET /batches/{batch_id}", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(rw, `{"id":"`+r.PathValue("batch_id")+`","status":"completed","output_file_id":"file-out"}`)
+	})
+	mux.HandleFunc("GET /files/{file_id}/content", func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(rw, "content of "+r.PathValue("file_id"))
+	})
+
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	t.Setenv("ZHIPUAI_API_KEY", "test.secret")
+	t.Setenv("ZHIPUAI_BASE_URL", s.URL)
+}
+
+func runTest(t *testing.T, stdin string, args ...string) (string, error) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	err := run(context.Background(), args, strings.NewReader(stdin), stdout, stderr)
+	return stdout.String(), err
+}
+
+func TestRunChat(t *testing.T) {
+	newTestServer(t)
+
+	out, err := runTest(t, "", "chat", "-m", "glm-4-flash", "你好")
+	require.NoError(t, err)
+	require.Equal(t, "你好\n", out)
+
+	out, err = runTest(t, "你好", "-o", "json", "chat")
+	require.NoError(t, err)
+	var res zhipu.ChatCompletionResponse
+	require.NoError(t, json.Unmarshal([]byte(out), &res))
+	require.Equal(t, "你好", res.Choices[0].Message.Content)
+}
+
+func TestRunFineTuneList(t *testing.T) {
+	newTestServer(t)
+
+	out, err := runTest(t, "", "ft", "ls")
+	require.NoError(t, err)
+	require.Contains(t, out, "ftjob-1")
+	require.Contains(t, out, "running")
+
+	out, err = runTest(t, "", "-o", "json", "ft", "ls")
+	require.NoError(t, err)
+	var res zhipu.FineTuneListResponse
+	require.NoError(t, json.Unmarshal([]byte(out), &res))
+	require.Len(t, res.Data, 1)
+}
+
+func TestRunKnowledgeCapacity(t *testing.T) {
+	newTestServer(t)
+
+	out, err := runTest(t, "", "kb", "capacity")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, []string{"used", "10", "20"}, strings.Fields(lines[1]))
+}
+
+func TestRunBatchResults(t *testing.T) {
+	newTestServer(t)
+
+	out, err := runTest(t, "", "batch", "results", "batch-1")
+	require.NoError(t, err)
+	require.Equal(t, "content of file-out", out)
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	newTestServer(t)
+
+	_, err := runTest(t, "", "nope")
+	require.Error(t, err)
+
+	_, err = runTest(t, "", "files", "nope")
+	require.Error(t, err)
+
+	_, err = runTest(t, "", "-o", "yaml", "ft", "ls")
+	require.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// newFlagSet creates a flag set for a sub command, writing usage to stderr
+func (a *app) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+// printJSON prints v as indented json
+func (a *app) printJSON(v any) error {
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// print prints v as json, or as a table built by rows in table mode
+func (a *app) print(v any, header []string, rows func(add func(cells ...any))) error {
+	if a.output == outputJSON {
+		return a.printJSON(v)
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	rows(func(cells ...any) {
+		items := make([]string, 0, len(cells))
+		for _, cell := range cells {
+			items = append(items, formatCell(cell))
+		}
+		fmt.Fprintln(tw, strings.Join(items, "\t"))
+	})
+	return tw.Flush()
+}
+
+// formatCell formats a table cell, unix timestamps are rendered as local time
+func formatCell(v any) string {
+	switch v := v.(type) {
+	case unixTime:
+		if v == 0 {
+			return "-"
+		}
+		return time.Unix(int64(v), 0).Format(time.DateTime)
+	case unixMilliTime:
+		if v == 0 {
+			return "-"
+		}
+		return time.UnixMilli(int64(v)).Format(time.DateTime)
+	case string:
+		if v == "" {
+			return "-"
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// unixTime is a timestamp in seconds
+type unixTime int64
+
+// unixMilliTime is a timestamp in milliseconds
+type unixMilliTime int64
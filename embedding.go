@@ -15,6 +15,8 @@ type EmbeddingData struct {
 
 // EmbeddingResponse is the response from the embedding service.
 type EmbeddingResponse struct {
+	RawResponse
+
 	Model  string              `json:"model"`
 	Data   []EmbeddingData     `json:"data"`
 	Object string              `json:"object"`
@@ -24,6 +26,7 @@ type EmbeddingResponse struct {
 // EmbeddingService embeds a list of text into a vector space.
 type EmbeddingService struct {
 	client *Client
+	extras serviceExtras
 
 	model string
 	input string
@@ -47,7 +50,7 @@ func (s *EmbeddingService) BatchURL() string {
 }
 
 func (s *EmbeddingService) BatchBody() any {
-	return s.buildBody()
+	return s.extras.mergeBody(s.buildBody())
 }
 
 // SetModel sets the model to use for the embedding.
@@ -66,14 +69,68 @@ func (s *EmbeddingService) buildBody() M {
 	return M{"model": s.model, "input": s.input}
 }
 
-func (s *EmbeddingService) Do(ctx context.Context) (res EmbeddingResponse, err error) {
+// SetExtraBody sets extra fields of the Embedding request, see WithCallBody
+func (s *EmbeddingService) SetExtraBody(body M) *EmbeddingService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the Embedding request
+func (s *EmbeddingService) SetExtraHeaders(headers map[string]string) *EmbeddingService {
+	s.extras.headers = headers
+	return s
+}
+
+// FromBody rebuilds the service from a json request body, like the body of a batch line or a logged request,
+// unknown fields are kept as extra body, fields missing from the body are reset, the client and extra headers are kept
+func (s *EmbeddingService) FromBody(data []byte) (err error) {
+	var fields bodyFields
+	if fields, err = decodeBodyFields(data); err != nil {
+		return
+	}
+	*s = EmbeddingService{client: s.client, extras: serviceExtras{headers: s.extras.headers}}
+	if _, err = fields.take("model", &s.model); err != nil {
+		return
+	}
+	if _, err = fields.take("input", &s.input); err != nil {
+		return
+	}
+	s.extras.body, err = fields.rest()
+	return
+}
+
+// UnmarshalJSON implements json.Unmarshaler with FromBody
+func (s *EmbeddingService) UnmarshalJSON(data []byte) error {
+	return s.FromBody(data)
+}
+
+// Validate checks the Embedding request locally, see ValidationError
+func (s *EmbeddingService) Validate() error {
+	v := newValidator("Embedding")
+	v.required("model", s.model)
+	v.required("input", s.input)
+	return v.err()
+}
+
+func (s *EmbeddingService) Do(ctx context.Context, opts ...CallOption) (res EmbeddingResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "Embedding", Model: s.model, Method: "POST", Path: "embeddings", Body: s.buildBody()}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *EmbeddingService) do(ctx context.Context, op *Operation) (res EmbeddingResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
 	)
 
+	if s.client.cacheGet(ctx, op, &res) {
+		return
+	}
+
 	if resp, err = s.client.request(ctx).
-		SetBody(s.buildBody()).
+		SetBody(op.Body).
 		SetResult(&res).
 		SetError(&apiError).
 		Post("embeddings"); err != nil {
@@ -83,5 +140,7 @@ func (s *EmbeddingService) Do(ctx context.Context) (res EmbeddingResponse, err e
 		err = apiError
 		return
 	}
+
+	s.client.cacheSet(op, res, resp.Body())
 	return
 }
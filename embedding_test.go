@@ -8,7 +8,7 @@ import (
 )
 
 func TestEmbeddingService(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	service := client.Embedding("embedding-2")
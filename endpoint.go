@@ -0,0 +1,268 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// BaseURLBigModel is the base url of the platform in mainland China
+	BaseURLBigModel = defaultBaseURL
+	// BaseURLZAI is the base url of the international platform
+	BaseURLZAI = "https://api.z.ai/api/paas/v4"
+
+	defaultEndpointCooldown = 30 * time.Second
+)
+
+// Endpoint is a base url of the platform
+type Endpoint struct {
+	// BaseURL is the base url, like BaseURLBigModel
+	BaseURL string
+	// Timeout bounds the wait for the response headers, streams are not cut once started, 0 means no timeout
+	Timeout time.Duration
+	// APIKey is the api key of the endpoint, platforms like BaseURLBigModel and BaseURLZAI have separate accounts,
+	// empty means the credentials of the client
+	APIKey string
+	// TokenProvider provides the Authorization header of the endpoint, overriding APIKey
+	TokenProvider TokenProvider
+}
+
+// WithEndpoints set an ordered list of endpoints, overriding the base url, the credentials of the first endpoint
+// are used if the client has none.
+// Requests go to the first healthy endpoint, an endpoint failing with network errors, timeouts or 502/503/504
+// is marked unhealthy for a cooldown period, and the request fails over to the next one. A fallback endpoint
+// rejecting the credentials with 401/403 is a failure as well, while the first endpoint returns it as is.
+func WithEndpoints(endpoints ...Endpoint) ClientOption {
+	return func(opts *clientOptions) {
+		opts.endpoints = append(opts.endpoints, endpoints...)
+	}
+}
+
+// WithEndpointCooldown set the period an endpoint stays unhealthy after a failure, default to 30 seconds
+func WithEndpointCooldown(cooldown time.Duration) ClientOption {
+	return func(opts *clientOptions) {
+		opts.endpointCooldown = cooldown
+	}
+}
+
+// EndpointStatus is the health status of an endpoint
+type EndpointStatus struct {
+	BaseURL string
+	// Healthy is false during the cooldown period after a failure
+	Healthy bool
+	// Failures is the number of failures
+	Failures int64
+	// LastError is the last failure
+	LastError error
+}
+
+// endpointState is the state of an endpoint
+type endpointState struct {
+	Endpoint
+
+	// tokens overrides the Authorization header, nil to keep the credentials of the client
+	tokens TokenProvider
+
+	failures  int64
+	lastError error
+	unhealthy time.Time
+}
+
+// endpointTransport is a http.RoundTripper failing over a list of endpoints
+type endpointTransport struct {
+	next     http.RoundTripper
+	primary  string
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	endpoints []*endpointState
+}
+
+func newEndpointTransport(next http.RoundTripper, endpoints []Endpoint, cooldown time.Duration, plain bool, ttl time.Duration) (t *endpointTransport, err error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if cooldown <= 0 {
+		cooldown = defaultEndpointCooldown
+	}
+	t = &endpointTransport{next: next, cooldown: cooldown}
+	for _, endpoint := range endpoints {
+		endpoint.BaseURL = strings.TrimRight(endpoint.BaseURL, "/")
+		e := &endpointState{Endpoint: endpoint, tokens: endpoint.TokenProvider}
+		if e.tokens == nil && endpoint.APIKey != "" {
+			if plain {
+				e.tokens = NewAPIKeyTokenProvider(endpoint.APIKey)
+			} else if e.tokens, err = NewJWTTokenProvider(endpoint.APIKey, ttl); err != nil {
+				err = fmt.Errorf("%w: endpoint %s", err, endpoint.BaseURL)
+				return
+			}
+		}
+		t.endpoints = append(t.endpoints, e)
+	}
+	t.primary = t.endpoints[0].BaseURL
+	return
+}
+
+// order returns the healthy endpoints in order, followed by the unhealthy ones by recovery time
+func (t *endpointTransport) order() []*endpointState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+
+	var healthy, unhealthy []*endpointState
+	for _, e := range t.endpoints {
+		if now.Before(e.unhealthy) {
+			unhealthy = append(unhealthy, e)
+		} else {
+			healthy = append(healthy, e)
+		}
+	}
+	sort.SliceStable(unhealthy, func(i, j int) bool {
+		return unhealthy[i].unhealthy.Before(unhealthy[j].unhealthy)
+	})
+	return append(healthy, unhealthy...)
+}
+
+func (t *endpointTransport) report(e *endpointState, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil {
+		e.unhealthy = time.Time{}
+		return
+	}
+	e.failures++
+	e.lastError = err
+	e.unhealthy = time.Now().Add(t.cooldown)
+}
+
+// Status returns the health status of all endpoints
+func (t *endpointTransport) Status() (out []EndpointStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for _, e := range t.endpoints {
+		out = append(out, EndpointStatus{
+			BaseURL:   e.BaseURL,
+			Healthy:   !now.Before(e.unhealthy),
+			Failures:  e.failures,
+			LastError: e.lastError,
+		})
+	}
+	return
+}
+
+// isEndpointFailure returns true if the status indicates the endpoint is unavailable
+func isEndpointFailure(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isCredentialFailure returns true if the status indicates the credentials are rejected
+func isCredentialFailure(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *endpointTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	rel, ok := strings.CutPrefix(req.URL.String(), t.primary)
+	if !ok {
+		// absolute urls of other hosts are not rewritten
+		return t.next.RoundTrip(req)
+	}
+
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for i, e := range t.order() {
+		if i > 0 && !replayable {
+			return
+		}
+
+		attempt := req.Clone(req.Context())
+		if attempt.URL, err = url.Parse(e.BaseURL + rel); err != nil {
+			return
+		}
+		attempt.Host = ""
+		if i > 0 && req.GetBody != nil {
+			if attempt.Body, err = req.GetBody(); err != nil {
+				return
+			}
+		}
+
+		if res != nil {
+			_ = res.Body.Close()
+			res = nil
+		}
+
+		if e.tokens != nil {
+			var token string
+			if token, err = e.tokens.Token(req.Context()); err != nil {
+				if attempt.Body != nil {
+					_ = attempt.Body.Close()
+				}
+				t.report(e, err)
+				continue
+			}
+			attempt.Header.Set("Authorization", token)
+		}
+
+		res, err = t.roundTrip(attempt, e.Timeout)
+
+		// the caller gave up, the endpoint is not to blame
+		if req.Context().Err() != nil {
+			return
+		}
+
+		if err != nil {
+			t.report(e, err)
+			continue
+		}
+		if isEndpointFailure(res.StatusCode) || (e.BaseURL != t.primary && isCredentialFailure(res.StatusCode)) {
+			t.report(e, fmt.Errorf("zhipu: endpoint %s responded %s", e.BaseURL, res.Status))
+			continue
+		}
+		t.report(e, nil)
+		return
+	}
+	return
+}
+
+// errEndpointTimeout is the error when an endpoint does not respond in time
+var errEndpointTimeout = errors.New("zhipu: endpoint timeout")
+
+// roundTrip sends the request, canceling it if the response headers do not arrive in time
+func (t *endpointTransport) roundTrip(req *http.Request, timeout time.Duration) (res *http.Response, err error) {
+	if timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(timeout, func() { cancel(errEndpointTimeout) })
+
+	if res, err = t.next.RoundTrip(req.WithContext(ctx)); err != nil {
+		timer.Stop()
+		if context.Cause(ctx) == errEndpointTimeout {
+			err = fmt.Errorf("%w after %s: %s", errEndpointTimeout, timeout, req.URL.Host)
+		}
+		cancel(nil)
+		return
+	}
+	if !timer.Stop() {
+		// the timer fired just as the headers arrived, the body is canceled already
+		_ = res.Body.Close()
+		cancel(nil)
+		return nil, fmt.Errorf("%w after %s: %s", errEndpointTimeout, timeout, req.URL.Host)
+	}
+	res.Body = &releaseBody{ReadCloser: res.Body, release: func() { cancel(nil) }}
+	return
+}
@@ -0,0 +1,121 @@
+package zhipu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpoints(t *testing.T) {
+	var hits [3]int64
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits[0], 1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits[1], 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits[2], 1)
+		require.Equal(t, "/api/paas/v4/embeddings", r.URL.Path)
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusOK)
+		rw.(http.Flusher).Flush()
+		// the body is slower than the timeout, which only bounds the headers
+		time.Sleep(100 * time.Millisecond)
+		_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[{"embedding":[1]}]}`))
+	}))
+	defer healthy.Close()
+
+	client, err := NewClient(WithAPIKey("a.b"), WithEndpoints(
+		Endpoint{BaseURL: unavailable.URL + "/api/paas/v4"},
+		Endpoint{BaseURL: slow.URL + "/api/paas/v4/", Timeout: 50 * time.Millisecond},
+		Endpoint{BaseURL: healthy.URL + "/api/paas/v4", Timeout: 50 * time.Millisecond},
+	))
+	require.NoError(t, err)
+
+	res, err := client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []float64{1}, res.Data[0].Embedding)
+
+	status := client.EndpointStatus()
+	require.Len(t, status, 3)
+	require.False(t, status[0].Healthy)
+	require.ErrorContains(t, status[0].LastError, "503")
+	require.False(t, status[1].Healthy)
+	require.ErrorIs(t, status[1].LastError, errEndpointTimeout)
+	require.True(t, status[2].Healthy)
+
+	// unhealthy endpoints are skipped
+	_, err = client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, [3]int64{1, 1, 2}, [3]int64{atomic.LoadInt64(&hits[0]), atomic.LoadInt64(&hits[1]), atomic.LoadInt64(&hits[2])})
+}
+
+func TestEndpointCredentials(t *testing.T) {
+	var rejected int64
+
+	newServer := func(token string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != token {
+				atomic.AddInt64(&rejected, 1)
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[{"embedding":[1]}]}`))
+		}))
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	other := newServer("Bearer other-key")
+	defer other.Close()
+	zai := newServer("Bearer zai-key")
+	defer zai.Close()
+
+	// the credentials of the first endpoint are the credentials of the client
+	client, err := NewClient(WithPlainAPIKey(), WithEndpoints(
+		Endpoint{BaseURL: down.URL, APIKey: "bigmodel-key"},
+		Endpoint{BaseURL: other.URL},
+		Endpoint{BaseURL: zai.URL, APIKey: "zai-key"},
+	))
+	require.NoError(t, err)
+
+	// the fallback with the credentials of the client is rejected, and failed over
+	res, err := client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []float64{1}, res.Data[0].Embedding)
+	require.Equal(t, int64(1), atomic.LoadInt64(&rejected))
+
+	status := client.EndpointStatus()
+	require.False(t, status[1].Healthy)
+	require.ErrorContains(t, status[1].LastError, "401")
+	require.True(t, status[2].Healthy)
+
+	// the first endpoint returns 401 as is
+	client, err = NewClient(WithPlainAPIKey(), WithEndpoints(
+		Endpoint{BaseURL: zai.URL, APIKey: "bigmodel-key"},
+		Endpoint{BaseURL: other.URL, APIKey: "other-key"},
+	))
+	require.NoError(t, err)
+	_, err = client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.Error(t, err)
+	require.True(t, client.EndpointStatus()[0].Healthy)
+}
@@ -0,0 +1,84 @@
+package zhipu
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// serviceExtras is the extra body fields and headers of a service, set by SetExtraBody and SetExtraHeaders.
+// They are sent as call options, so the extra fields override the fields set by the service, requests without
+// json body carry them in the query or in the multipart form, and the options of a call take precedence.
+type serviceExtras struct {
+	body    M
+	headers map[string]string
+}
+
+// callOptions prepends the extras to the call options, call options take precedence
+func (e serviceExtras) callOptions(opts []CallOption) []CallOption {
+	if e.body == nil && e.headers == nil {
+		return opts
+	}
+	out := make([]CallOption, 0, len(opts)+len(e.headers)+1)
+	if e.body != nil {
+		out = append(out, WithCallBody(e.body))
+	}
+	for k, v := range e.headers {
+		out = append(out, WithCallHeader(k, v))
+	}
+	return append(out, opts...)
+}
+
+// mergeBody merges the extra body fields into the body
+func (e serviceExtras) mergeBody(body M) M {
+	for k, v := range e.body {
+		body[k] = v
+	}
+	return body
+}
+
+// RawResponse is embedded in the responses, keeping the raw json body for undocumented fields
+type RawResponse struct {
+	rawJSON json.RawMessage
+}
+
+// RawJSON returns the raw json body of the response, nil for streams and nested items
+func (r RawResponse) RawJSON() json.RawMessage {
+	return r.rawJSON
+}
+
+func (r *RawResponse) setRawJSON(buf []byte) {
+	r.rawJSON = buf
+}
+
+// rawJSONSetter is implemented by the responses embedding RawResponse
+type rawJSONSetter interface {
+	setRawJSON(buf []byte)
+}
+
+// formatExtraParams formats the extra body fields as query or form values, non-string values are json encoded
+func formatExtraParams(body M) map[string]string {
+	params := make(map[string]string, len(body))
+	for k, v := range body {
+		if str, ok := v.(string); ok {
+			params[k] = str
+			continue
+		}
+		buf, _ := json.Marshal(v)
+		params[k] = string(buf)
+	}
+	return params
+}
+
+// applyExtraParams sets the extra body fields of a GET or DELETE request in the query, overriding the service
+func applyExtraParams(_ *resty.Client, r *resty.Request) error {
+	opts := getCallOptions(r.Context())
+	if opts == nil || (r.Method != http.MethodGet && r.Method != http.MethodDelete) {
+		return nil
+	}
+	for k, v := range opts.params {
+		r.QueryParam.Set(k, v)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceExtras(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body M
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		require.Equal(t, "v", r.Header.Get("X-Extra"))
+		require.Equal(t, M{"type": "enabled"}, body["thinking"])
+		require.Equal(t, true, body["tool_stream"])
+		require.Equal(t, "glm-4-flash", body["model"])
+
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"id":"1","model":"glm-4-flash","choices":[],"undocumented":{"a":1}}`))
+	}))
+	defer s.Close()
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	svc := client.ChatCompletion("glm-4-flash").
+		AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).
+		SetThinkingMode(ThinkingDisabled).
+		SetExtraBody(M{"thinking": M{"type": "enabled"}, "tool_stream": false}).
+		SetExtraHeaders(map[string]string{"X-Extra": "v"})
+
+	// call options take precedence
+	res, err := svc.Do(context.Background(), WithCallBody(M{"tool_stream": true}))
+	require.NoError(t, err)
+	require.Equal(t, "1", res.ID)
+
+	var raw struct {
+		Undocumented M `json:"undocumented"`
+	}
+	require.NoError(t, json.Unmarshal(res.RawJSON(), &raw))
+	require.Equal(t, M{"a": float64(1)}, raw.Undocumented)
+
+	body := svc.BatchBody().(M)
+	require.Equal(t, M{"type": "enabled"}, body["thinking"])
+}
+
+func TestServiceExtrasWithoutJSONBody(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/files":
+			if r.Method == http.MethodGet {
+				require.Equal(t, "1", r.URL.Query().Get("x_extra"))
+				require.Equal(t, "batch", r.URL.Query().Get("purpose"))
+				require.Equal(t, "true", r.URL.Query().Get("x_flag"))
+				_, _ = rw.Write([]byte(`{"object":"list","data":[]}`))
+				return
+			}
+			require.NoError(t, r.ParseMultipartForm(1<<20))
+			require.Equal(t, "1", r.FormValue("x_extra"))
+			require.Equal(t, "batch", r.FormValue("purpose"))
+			require.Empty(t, r.URL.Query().Get("x_extra"))
+			_, _ = rw.Write([]byte(`{"id":"file-1","object":"file","purpose":"batch"}`))
+		case "/fine_tuning/jobs/job-1":
+			require.Equal(t, "1", r.URL.Query().Get("x_extra"))
+			_, _ = rw.Write([]byte(`{"id":"job-1","status":"running"}`))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	_, err = client.FileList(FilePurposeBatch).
+		SetExtraBody(M{"x_extra": "1", "purpose": "fine-tune"}).
+		Do(context.Background(), WithCallBody(M{"purpose": "batch", "x_flag": true}))
+	require.NoError(t, err)
+
+	job, err := client.FineTuneGet("job-1").SetExtraBody(M{"x_extra": 1}).Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "job-1", job.ID)
+
+	file, err := client.FileCreate(FilePurposeFineTune).
+		SetFile(strings.NewReader(`{}`), "batch.jsonl").
+		SetExtraBody(M{"x_extra": "1", "purpose": FilePurposeBatch}).
+		Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "file-1", file.ID)
+}
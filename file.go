@@ -3,6 +3,7 @@ package zhipu
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -26,6 +27,7 @@ const (
 // FileCreateService is a service to create a file.
 type FileCreateService struct {
 	client *Client
+	extras serviceExtras
 
 	purpose string
 
@@ -68,6 +70,8 @@ type FileCreateFineTuneResponse struct {
 
 // FileCreateResponse is the response of the FileCreateService.
 type FileCreateResponse struct {
+	RawResponse
+
 	FileCreateFineTuneResponse
 	FileCreateKnowledgeResponse
 }
@@ -114,8 +118,48 @@ func (s *FileCreateService) SetKnowledgeID(knowledgeID string) *FileCreateServic
 	return s
 }
 
+// SetExtraBody sets extra fields of the FileCreate request, see WithCallBody
+func (s *FileCreateService) SetExtraBody(body M) *FileCreateService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FileCreate request
+func (s *FileCreateService) SetExtraHeaders(headers map[string]string) *FileCreateService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FileCreate request locally, see ValidationError
+func (s *FileCreateService) Validate() error {
+	v := newValidator("FileCreate")
+	v.oneOf("purpose", s.purpose, FilePurposeFineTune, FilePurposeRetrieval, FilePurposeBatch)
+	if s.file == nil {
+		if s.localFile == "" {
+			v.add("file", "is required")
+		} else if _, err := os.Stat(s.localFile); err != nil {
+			v.add("file", "%s", err.Error())
+		}
+	}
+	if s.purpose == FilePurposeRetrieval && (s.knowledgeID == nil || *s.knowledgeID == "") {
+		v.add("knowledge_id", "is required for purpose %s", FilePurposeRetrieval)
+	}
+	if s.customSeparator != nil && *s.customSeparator == "" {
+		v.add("custom_separator", "must not be empty")
+	}
+	v.between("sentence_size", s.sentenceSize, 20, 2000)
+	return v.err()
+}
+
 // Do makes the request.
-func (s *FileCreateService) Do(ctx context.Context) (res FileCreateResponse, err error) {
+func (s *FileCreateService) Do(ctx context.Context, opts ...CallOption) (res FileCreateResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FileCreate", Method: "POST", Path: "files"}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FileCreateService) do(ctx context.Context, op *Operation) (res FileCreateResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -132,6 +176,11 @@ func (s *FileCreateService) Do(ctx context.Context) (res FileCreateResponse, err
 	if s.knowledgeID != nil {
 		body["knowledge_id"] = *s.knowledgeID
 	}
+	if opts := getCallOptions(ctx); opts != nil {
+		for k, v := range opts.params {
+			body[k] = v
+		}
+	}
 
 	file, filename := s.file, s.filename
 
@@ -171,6 +220,7 @@ func (s *FileCreateService) Do(ctx context.Context) (res FileCreateResponse, err
 // FileEditService is a service to edit a file.
 type FileEditService struct {
 	client *Client
+	extras serviceExtras
 
 	documentID string
 
@@ -208,13 +258,41 @@ func (s *FileEditService) SetSentenceSize(sentenceSize int) *FileEditService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the FileEdit request, see WithCallBody
+func (s *FileEditService) SetExtraBody(body M) *FileEditService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FileEdit request
+func (s *FileEditService) SetExtraHeaders(headers map[string]string) *FileEditService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FileEdit request locally, see ValidationError
+func (s *FileEditService) Validate() error {
+	v := newValidator("FileEdit")
+	v.required("document_id", s.documentID)
+	v.between("knowledge_type", s.knowledgeType, KnowledgeTypeArticle, KnowledgeTypeCustom)
+	for i, separator := range s.customSeparator {
+		if separator == "" {
+			v.add(fmt.Sprintf("custom_separator[%d]", i), "must not be empty")
+		}
+	}
+	v.between("sentence_size", s.sentenceSize, 20, 2000)
+	return v.err()
+}
+
 // Do makes the request.
-func (s *FileEditService) Do(ctx context.Context) (err error) {
-	var (
-		resp     *resty.Response
-		apiError APIErrorResponse
-	)
+func (s *FileEditService) Do(ctx context.Context, opts ...CallOption) (err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invokeNoResult(ctx, s.client, &Operation{Name: "FileEdit", Method: "PUT", Path: "document/" + s.documentID, Body: s.buildBody()}, s.do, s.extras.callOptions(opts)...)
+}
 
+func (s *FileEditService) buildBody() M {
 	body := M{}
 
 	if s.knowledgeType != nil {
@@ -226,10 +304,18 @@ func (s *FileEditService) Do(ctx context.Context) (err error) {
 	if s.sentenceSize != nil {
 		body["sentence_size"] = strconv.Itoa(*s.sentenceSize)
 	}
+	return body
+}
+
+func (s *FileEditService) do(ctx context.Context, op *Operation) (err error) {
+	var (
+		resp     *resty.Response
+		apiError APIErrorResponse
+	)
 
 	if resp, err = s.client.request(ctx).
 		SetPathParam("document_id", s.documentID).
-		SetBody(body).
+		SetBody(op.Body).
 		SetError(&apiError).
 		Put("document/{document_id}"); err != nil {
 		return
@@ -246,6 +332,7 @@ func (s *FileEditService) Do(ctx context.Context) (err error) {
 // FileListService is a service to list files.
 type FileListService struct {
 	client *Client
+	extras serviceExtras
 
 	purpose string
 
@@ -300,6 +387,8 @@ type FileListFineTuneResponse struct {
 
 // FileListResponse is the response of the FileListService.
 type FileListResponse struct {
+	RawResponse
+
 	FileListKnowledgeResponse
 	FileListFineTuneResponse
 }
@@ -345,8 +434,39 @@ func (s *FileListService) SetOrder(asc bool) *FileListService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the FileList request, see WithCallBody
+func (s *FileListService) SetExtraBody(body M) *FileListService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FileList request
+func (s *FileListService) SetExtraHeaders(headers map[string]string) *FileListService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FileList request locally, see ValidationError
+func (s *FileListService) Validate() error {
+	v := newValidator("FileList")
+	v.oneOf("purpose", s.purpose, FilePurposeFineTune, FilePurposeRetrieval, FilePurposeBatch)
+	if s.purpose == FilePurposeRetrieval && (s.knowledgeID == nil || *s.knowledgeID == "") {
+		v.add("knowledge_id", "is required for purpose %s", FilePurposeRetrieval)
+	}
+	v.min("page", s.page, 1)
+	v.min("limit", s.limit, 1)
+	return v.err()
+}
+
 // Do makes the request.
-func (s *FileListService) Do(ctx context.Context) (res FileListResponse, err error) {
+func (s *FileListService) Do(ctx context.Context, opts ...CallOption) (res FileListResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FileList", Method: "GET", Path: "files"}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FileListService) do(ctx context.Context, op *Operation) (res FileListResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -395,6 +515,7 @@ func (s *FileListService) Do(ctx context.Context) (res FileListResponse, err err
 // FileDeleteService is a service to delete a file.
 type FileDeleteService struct {
 	client *Client
+	extras serviceExtras
 	fileID string
 }
 
@@ -415,8 +536,34 @@ func (s *FileDeleteService) SetFileID(fileID string) *FileDeleteService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the FileDelete request, see WithCallBody
+func (s *FileDeleteService) SetExtraBody(body M) *FileDeleteService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FileDelete request
+func (s *FileDeleteService) SetExtraHeaders(headers map[string]string) *FileDeleteService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FileDelete request locally, see ValidationError
+func (s *FileDeleteService) Validate() error {
+	v := newValidator("FileDelete")
+	v.required("file_id", s.fileID)
+	return v.err()
+}
+
 // Do makes the request.
-func (s *FileDeleteService) Do(ctx context.Context) (err error) {
+func (s *FileDeleteService) Do(ctx context.Context, opts ...CallOption) (err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invokeNoResult(ctx, s.client, &Operation{Name: "FileDelete", Method: "DELETE", Path: "files/" + s.fileID}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FileDeleteService) do(ctx context.Context, op *Operation) (err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -440,6 +587,7 @@ func (s *FileDeleteService) Do(ctx context.Context) (err error) {
 // FileGetService is a service to get a file.
 type FileGetService struct {
 	client     *Client
+	extras     serviceExtras
 	documentID string
 }
 
@@ -457,8 +605,34 @@ func (s *FileGetService) SetDocumentID(documentID string) *FileGetService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the FileGet request, see WithCallBody
+func (s *FileGetService) SetExtraBody(body M) *FileGetService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FileGet request
+func (s *FileGetService) SetExtraHeaders(headers map[string]string) *FileGetService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FileGet request locally, see ValidationError
+func (s *FileGetService) Validate() error {
+	v := newValidator("FileGet")
+	v.required("document_id", s.documentID)
+	return v.err()
+}
+
 // Do makes the request.
-func (s *FileGetService) Do(ctx context.Context) (res FileGetResponse, err error) {
+func (s *FileGetService) Do(ctx context.Context, opts ...CallOption) (res FileGetResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FileGet", Method: "GET", Path: "document/" + s.documentID}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FileGetService) do(ctx context.Context, op *Operation) (res FileGetResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -483,6 +657,7 @@ func (s *FileGetService) Do(ctx context.Context) (res FileGetResponse, err error
 // FileDownloadService is a service to download a file.
 type FileDownloadService struct {
 	client *Client
+	extras serviceExtras
 
 	fileID string
 
@@ -513,8 +688,37 @@ func (s *FileDownloadService) SetOutputFile(filename string) *FileDownloadServic
 	return s
 }
 
+// SetExtraBody sets extra fields of the FileDownload request, see WithCallBody
+func (s *FileDownloadService) SetExtraBody(body M) *FileDownloadService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FileDownload request
+func (s *FileDownloadService) SetExtraHeaders(headers map[string]string) *FileDownloadService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FileDownload request locally, see ValidationError
+func (s *FileDownloadService) Validate() error {
+	v := newValidator("FileDownload")
+	v.required("file_id", s.fileID)
+	if s.writer == nil && s.filename == "" {
+		v.add("output", "is required")
+	}
+	return v.err()
+}
+
 // Do makes the request.
-func (s *FileDownloadService) Do(ctx context.Context) (err error) {
+func (s *FileDownloadService) Do(ctx context.Context, opts ...CallOption) (err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invokeNoResult(ctx, s.client, &Operation{Name: "FileDownload", Method: "GET", Path: "files/" + s.fileID + "/content"}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FileDownloadService) do(ctx context.Context, op *Operation) (err error) {
 	var resp *resty.Response
 
 	writer := s.writer
@@ -10,7 +10,7 @@ import (
 )
 
 func TestFileServiceFineTune(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.FileCreate(FilePurposeFineTune)
@@ -24,7 +24,7 @@ func TestFileServiceFineTune(t *testing.T) {
 }
 
 func TestFileServiceKnowledge(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.FileCreate(FilePurposeRetrieval)
@@ -51,7 +51,7 @@ func TestFileServiceKnowledge(t *testing.T) {
 }
 
 func TestFileListServiceKnowledge(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.FileList(FilePurposeRetrieval).SetKnowledgeID(os.Getenv("TEST_KNOWLEDGE_ID"))
@@ -61,7 +61,7 @@ func TestFileListServiceKnowledge(t *testing.T) {
 }
 
 func TestFileListServiceFineTune(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.FileList(FilePurposeFineTune)
@@ -71,7 +71,7 @@ func TestFileListServiceFineTune(t *testing.T) {
 }
 
 func TestFileDeleteService(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.FileCreate(FilePurposeFineTune)
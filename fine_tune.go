@@ -10,38 +10,102 @@ import (
 const (
 	HyperParameterAuto = "auto"
 
-	FineTuneStatusCreate          = "create"
-	FineTuneStatusValidatingFiles = "validating_files"
-	FineTuneStatusQueued          = "queued"
-	FineTuneStatusRunning         = "running"
-	FineTuneStatusSucceeded       = "succeeded"
-	FineTuneStatusFailed          = "failed"
-	FineTuneStatusCancelled       = "cancelled"
+	FineTuneMethodSupervised = "supervised"
+	FineTuneMethodDPO        = "dpo"
+
+	FineTuneTrainingTypeLoRA = "lora"
+	FineTuneTrainingTypeFull = "full"
+)
+
+// FineTuneStatus is the status of a fine tune job
+type FineTuneStatus string
+
+const (
+	FineTuneStatusCreate          FineTuneStatus = "create"
+	FineTuneStatusValidatingFiles FineTuneStatus = "validating_files"
+	FineTuneStatusQueued          FineTuneStatus = "queued"
+	FineTuneStatusRunning         FineTuneStatus = "running"
+	FineTuneStatusSucceeded       FineTuneStatus = "succeeded"
+	FineTuneStatusFailed          FineTuneStatus = "failed"
+	FineTuneStatusCancelled       FineTuneStatus = "cancelled"
 )
 
+// IsTerminal returns true if the job will not change anymore
+func (s FineTuneStatus) IsTerminal() bool {
+	switch s {
+	case FineTuneStatusSucceeded, FineTuneStatusFailed, FineTuneStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// FineTuneHyperparameters is the hyperparameters of a fine tune job, values may be "auto"
+type FineTuneHyperparameters struct {
+	LearningRateMultiplier *StringOr[float64] `json:"learning_rate_multiplier,omitempty"`
+	BatchSize              *StringOr[int]     `json:"batch_size,omitempty"`
+	NEpochs                *StringOr[int]     `json:"n_epochs,omitempty"`
+	// Beta is the weight of the penalty of the dpo method
+	Beta *StringOr[float64] `json:"beta,omitempty"`
+}
+
+// isZero returns true if no hyperparameter is set
+func (h FineTuneHyperparameters) isZero() bool {
+	return h.LearningRateMultiplier == nil && h.BatchSize == nil && h.NEpochs == nil && h.Beta == nil
+}
+
+// FineTuneMethodConfig is the config of a fine tune method
+type FineTuneMethodConfig struct {
+	Hyperparameters FineTuneHyperparameters `json:"hyperparameters"`
+}
+
+// FineTuneMethod is the method of a fine tune job, like supervised or dpo
+type FineTuneMethod struct {
+	Type       string                `json:"type"`
+	Supervised *FineTuneMethodConfig `json:"supervised,omitempty"`
+	DPO        *FineTuneMethodConfig `json:"dpo,omitempty"`
+}
+
+// FineTuneExtraHyperparameters is the platform specific hyperparameters of a fine tune job
+type FineTuneExtraHyperparameters struct {
+	// FineTuningMethod is the training type, lora or full
+	FineTuningMethod string `json:"fine_tuning_method,omitempty"`
+}
+
 // FineTuneItem is the item of the FineTune
 type FineTuneItem struct {
-	ID             string   `json:"id"`
-	RequestID      string   `json:"request_id"`
-	FineTunedModel string   `json:"fine_tuned_model"`
-	Status         string   `json:"status"`
-	Object         string   `json:"object"`
-	TrainingFile   string   `json:"training_file"`
-	ValidationFile string   `json:"validation_file"`
-	Error          APIError `json:"error"`
+	RawResponse
+
+	ID                   string                        `json:"id"`
+	RequestID            string                        `json:"request_id"`
+	Model                string                        `json:"model"`
+	FineTunedModel       string                        `json:"fine_tuned_model"`
+	Status               FineTuneStatus                `json:"status"`
+	Object               string                        `json:"object"`
+	TrainingFile         string                        `json:"training_file"`
+	ValidationFile       string                        `json:"validation_file"`
+	ResultFiles          []string                      `json:"result_files"`
+	CreatedAt            int64                         `json:"created_at"`
+	FinishedAt           int64                         `json:"finished_at"`
+	EstimatedFinish      int64                         `json:"estimated_finish"`
+	TrainedTokens        int64                         `json:"trained_tokens"`
+	Hyperparameters      FineTuneHyperparameters       `json:"hyperparameters"`
+	Method               *FineTuneMethod               `json:"method,omitempty"`
+	ExtraHyperparameters *FineTuneExtraHyperparameters `json:"extra_hyperparameters,omitempty"`
+	Error                APIError                      `json:"error"`
 }
 
 // FineTuneCreateService creates a new fine tune
 type FineTuneCreateService struct {
 	client *Client
+	extras serviceExtras
 
 	model          string
 	trainingFile   string
 	validationFile *string
 
-	learningRateMultiplier *StringOr[float64]
-	batchSize              *StringOr[int]
-	nEpochs                *StringOr[int]
+	method          *string
+	trainingType    *string
+	hyperparameters FineTuneHyperparameters
 
 	suffix    *string
 	requestID *string
@@ -75,45 +139,72 @@ func (s *FineTuneCreateService) SetValidationFile(validationFile string) *FineTu
 	return s
 }
 
+// SetMethod sets the fine tune method, FineTuneMethodSupervised or FineTuneMethodDPO,
+// the hyperparameters are sent within the method once set
+func (s *FineTuneCreateService) SetMethod(method string) *FineTuneCreateService {
+	s.method = &method
+	return s
+}
+
+// SetTrainingType sets the training type, FineTuneTrainingTypeLoRA or FineTuneTrainingTypeFull
+func (s *FineTuneCreateService) SetTrainingType(trainingType string) *FineTuneCreateService {
+	s.trainingType = &trainingType
+	return s
+}
+
 // SetLearningRateMultiplier sets the learningRateMultiplier parameter
 func (s *FineTuneCreateService) SetLearningRateMultiplier(learningRateMultiplier float64) *FineTuneCreateService {
-	s.learningRateMultiplier = &StringOr[float64]{}
-	s.learningRateMultiplier.SetValue(learningRateMultiplier)
+	s.hyperparameters.LearningRateMultiplier = &StringOr[float64]{}
+	s.hyperparameters.LearningRateMultiplier.SetValue(learningRateMultiplier)
 	return s
 }
 
 // SetLearningRateMultiplierAuto sets the learningRateMultiplier parameter to auto
 func (s *FineTuneCreateService) SetLearningRateMultiplierAuto() *FineTuneCreateService {
-	s.learningRateMultiplier = &StringOr[float64]{}
-	s.learningRateMultiplier.SetString(HyperParameterAuto)
+	s.hyperparameters.LearningRateMultiplier = &StringOr[float64]{}
+	s.hyperparameters.LearningRateMultiplier.SetString(HyperParameterAuto)
 	return s
 }
 
 // SetBatchSize sets the batchSize parameter
 func (s *FineTuneCreateService) SetBatchSize(batchSize int) *FineTuneCreateService {
-	s.batchSize = &StringOr[int]{}
-	s.batchSize.SetValue(batchSize)
+	s.hyperparameters.BatchSize = &StringOr[int]{}
+	s.hyperparameters.BatchSize.SetValue(batchSize)
 	return s
 }
 
 // SetBatchSizeAuto sets the batchSize parameter to auto
 func (s *FineTuneCreateService) SetBatchSizeAuto() *FineTuneCreateService {
-	s.batchSize = &StringOr[int]{}
-	s.batchSize.SetString(HyperParameterAuto)
+	s.hyperparameters.BatchSize = &StringOr[int]{}
+	s.hyperparameters.BatchSize.SetString(HyperParameterAuto)
 	return s
 }
 
 // SetNEpochs sets the nEpochs parameter
 func (s *FineTuneCreateService) SetNEpochs(nEpochs int) *FineTuneCreateService {
-	s.nEpochs = &StringOr[int]{}
-	s.nEpochs.SetValue(nEpochs)
+	s.hyperparameters.NEpochs = &StringOr[int]{}
+	s.hyperparameters.NEpochs.SetValue(nEpochs)
 	return s
 }
 
 // SetNEpochsAuto sets the nEpochs parameter to auto
 func (s *FineTuneCreateService) SetNEpochsAuto() *FineTuneCreateService {
-	s.nEpochs = &StringOr[int]{}
-	s.nEpochs.SetString(HyperParameterAuto)
+	s.hyperparameters.NEpochs = &StringOr[int]{}
+	s.hyperparameters.NEpochs.SetString(HyperParameterAuto)
+	return s
+}
+
+// SetBeta sets the beta parameter of the dpo method
+func (s *FineTuneCreateService) SetBeta(beta float64) *FineTuneCreateService {
+	s.hyperparameters.Beta = &StringOr[float64]{}
+	s.hyperparameters.Beta.SetValue(beta)
+	return s
+}
+
+// SetBetaAuto sets the beta parameter of the dpo method to auto
+func (s *FineTuneCreateService) SetBetaAuto() *FineTuneCreateService {
+	s.hyperparameters.Beta = &StringOr[float64]{}
+	s.hyperparameters.Beta.SetString(HyperParameterAuto)
 	return s
 }
 
@@ -129,13 +220,63 @@ func (s *FineTuneCreateService) SetRequestID(requestID string) *FineTuneCreateSe
 	return s
 }
 
+// SetExtraBody sets extra fields of the FineTuneCreate request, see WithCallBody
+func (s *FineTuneCreateService) SetExtraBody(body M) *FineTuneCreateService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FineTuneCreate request
+func (s *FineTuneCreateService) SetExtraHeaders(headers map[string]string) *FineTuneCreateService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FineTuneCreate request locally, see ValidationError
+func (s *FineTuneCreateService) Validate() error {
+	v := newValidator("FineTuneCreate")
+	v.required("model", s.model)
+	v.required("training_file", s.trainingFile)
+	if s.method != nil {
+		v.oneOf("method.type", *s.method, FineTuneMethodSupervised, FineTuneMethodDPO)
+	}
+	if s.trainingType != nil {
+		v.oneOf("extra_hyperparameters.fine_tuning_method", *s.trainingType, FineTuneTrainingTypeLoRA, FineTuneTrainingTypeFull)
+	}
+
+	prefix := "hyperparameters"
+	if s.method != nil {
+		prefix = "method." + *s.method + ".hyperparameters"
+	}
+	hp := s.hyperparameters
+	if hp.LearningRateMultiplier != nil && hp.LearningRateMultiplier.Value != nil && *hp.LearningRateMultiplier.Value <= 0 {
+		v.add(prefix+".learning_rate_multiplier", "must be positive")
+	}
+	if hp.BatchSize != nil {
+		v.min(prefix+".batch_size", hp.BatchSize.Value, 1)
+	}
+	if hp.NEpochs != nil {
+		v.min(prefix+".n_epochs", hp.NEpochs.Value, 1)
+	}
+	if hp.Beta != nil {
+		if s.method == nil || *s.method != FineTuneMethodDPO {
+			v.add(prefix+".beta", "is only for method %s", FineTuneMethodDPO)
+		} else if hp.Beta.Value != nil && *hp.Beta.Value <= 0 {
+			v.add(prefix+".beta", "must be positive")
+		}
+	}
+	return v.err()
+}
+
 // Do makes the request
-func (s *FineTuneCreateService) Do(ctx context.Context) (res FineTuneCreateResponse, err error) {
-	var (
-		resp     *resty.Response
-		apiError APIErrorResponse
-	)
+func (s *FineTuneCreateService) Do(ctx context.Context, opts ...CallOption) (res FineTuneCreateResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FineTuneCreate", Model: s.model, Method: "POST", Path: "fine_tuning/jobs", Body: s.buildBody()}, s.do, s.extras.callOptions(opts)...)
+}
 
+func (s *FineTuneCreateService) buildBody() M {
 	body := M{
 		"model":         s.model,
 		"training_file": s.trainingFile,
@@ -150,22 +291,33 @@ func (s *FineTuneCreateService) Do(ctx context.Context) (res FineTuneCreateRespo
 	if s.requestID != nil {
 		body["request_id"] = *s.requestID
 	}
-	if s.learningRateMultiplier != nil || s.batchSize != nil || s.nEpochs != nil {
-		hp := M{}
-		if s.learningRateMultiplier != nil {
-			hp["learning_rate_multiplier"] = s.learningRateMultiplier
-		}
-		if s.batchSize != nil {
-			hp["batch_size"] = s.batchSize
-		}
-		if s.nEpochs != nil {
-			hp["n_epochs"] = s.nEpochs
+	if s.method != nil {
+		method := FineTuneMethod{Type: *s.method}
+		config := &FineTuneMethodConfig{Hyperparameters: s.hyperparameters}
+		switch *s.method {
+		case FineTuneMethodSupervised:
+			method.Supervised = config
+		case FineTuneMethodDPO:
+			method.DPO = config
 		}
-		body["hyperparameters"] = hp
+		body["method"] = method
+	} else if !s.hyperparameters.isZero() {
+		body["hyperparameters"] = s.hyperparameters
 	}
+	if s.trainingType != nil {
+		body["extra_hyperparameters"] = FineTuneExtraHyperparameters{FineTuningMethod: *s.trainingType}
+	}
+	return body
+}
+
+func (s *FineTuneCreateService) do(ctx context.Context, op *Operation) (res FineTuneCreateResponse, err error) {
+	var (
+		resp     *resty.Response
+		apiError APIErrorResponse
+	)
 
 	if resp, err = s.client.request(ctx).
-		SetBody(body).
+		SetBody(op.Body).
 		SetResult(&res).
 		SetError(&apiError).
 		Post("fine_tuning/jobs"); err != nil {
@@ -181,6 +333,7 @@ func (s *FineTuneCreateService) Do(ctx context.Context) (res FineTuneCreateRespo
 // FineTuneEventListService creates a new fine tune event list
 type FineTuneEventListService struct {
 	client *Client
+	extras serviceExtras
 
 	jobID string
 
@@ -214,6 +367,8 @@ type FineTuneEventItem struct {
 
 // FineTuneEventListResponse is the response of the FineTuneEventListService
 type FineTuneEventListResponse struct {
+	RawResponse
+
 	Data    []FineTuneEventItem `json:"data"`
 	HasMore bool                `json:"has_more"`
 	Object  string              `json:"object"`
@@ -244,8 +399,35 @@ func (s *FineTuneEventListService) SetAfter(after string) *FineTuneEventListServ
 	return s
 }
 
+// SetExtraBody sets extra fields of the FineTuneEventList request, see WithCallBody
+func (s *FineTuneEventListService) SetExtraBody(body M) *FineTuneEventListService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FineTuneEventList request
+func (s *FineTuneEventListService) SetExtraHeaders(headers map[string]string) *FineTuneEventListService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FineTuneEventList request locally, see ValidationError
+func (s *FineTuneEventListService) Validate() error {
+	v := newValidator("FineTuneEventList")
+	v.required("job_id", s.jobID)
+	v.min("limit", s.limit, 1)
+	return v.err()
+}
+
 // Do makes the request
-func (s *FineTuneEventListService) Do(ctx context.Context) (res FineTuneEventListResponse, err error) {
+func (s *FineTuneEventListService) Do(ctx context.Context, opts ...CallOption) (res FineTuneEventListResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FineTuneEventList", Method: "GET", Path: "fine_tuning/jobs/" + s.jobID + "/events"}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FineTuneEventListService) do(ctx context.Context, op *Operation) (res FineTuneEventListResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -277,6 +459,7 @@ func (s *FineTuneEventListService) Do(ctx context.Context) (res FineTuneEventLis
 // FineTuneGetService creates a new fine tune get
 type FineTuneGetService struct {
 	client *Client
+	extras serviceExtras
 	jobID  string
 }
 
@@ -293,8 +476,34 @@ func (s *FineTuneGetService) SetJobID(jobID string) *FineTuneGetService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the FineTuneGet request, see WithCallBody
+func (s *FineTuneGetService) SetExtraBody(body M) *FineTuneGetService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FineTuneGet request
+func (s *FineTuneGetService) SetExtraHeaders(headers map[string]string) *FineTuneGetService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FineTuneGet request locally, see ValidationError
+func (s *FineTuneGetService) Validate() error {
+	v := newValidator("FineTuneGet")
+	v.required("job_id", s.jobID)
+	return v.err()
+}
+
 // Do makes the request
-func (s *FineTuneGetService) Do(ctx context.Context) (res FineTuneItem, err error) {
+func (s *FineTuneGetService) Do(ctx context.Context, opts ...CallOption) (res FineTuneItem, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FineTuneGet", Method: "GET", Path: "fine_tuning/jobs/" + s.jobID}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FineTuneGetService) do(ctx context.Context, op *Operation) (res FineTuneItem, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -317,6 +526,7 @@ func (s *FineTuneGetService) Do(ctx context.Context) (res FineTuneItem, err erro
 // FineTuneListService creates a new fine tune list
 type FineTuneListService struct {
 	client *Client
+	extras serviceExtras
 
 	limit *int
 	after *string
@@ -324,6 +534,8 @@ type FineTuneListService struct {
 
 // FineTuneListResponse is the response of the FineTuneListService
 type FineTuneListResponse struct {
+	RawResponse
+
 	Data   []FineTuneItem `json:"data"`
 	Object string         `json:"object"`
 }
@@ -347,8 +559,34 @@ func (s *FineTuneListService) SetAfter(after string) *FineTuneListService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the FineTuneList request, see WithCallBody
+func (s *FineTuneListService) SetExtraBody(body M) *FineTuneListService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FineTuneList request
+func (s *FineTuneListService) SetExtraHeaders(headers map[string]string) *FineTuneListService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FineTuneList request locally, see ValidationError
+func (s *FineTuneListService) Validate() error {
+	v := newValidator("FineTuneList")
+	v.min("limit", s.limit, 1)
+	return v.err()
+}
+
 // Do makes the request
-func (s *FineTuneListService) Do(ctx context.Context) (res FineTuneListResponse, err error) {
+func (s *FineTuneListService) Do(ctx context.Context, opts ...CallOption) (res FineTuneListResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FineTuneList", Method: "GET", Path: "fine_tuning/jobs"}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FineTuneListService) do(ctx context.Context, op *Operation) (res FineTuneListResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -378,6 +616,7 @@ func (s *FineTuneListService) Do(ctx context.Context) (res FineTuneListResponse,
 // FineTuneDeleteService creates a new fine tune delete
 type FineTuneDeleteService struct {
 	client *Client
+	extras serviceExtras
 	jobID  string
 }
 
@@ -394,8 +633,34 @@ func (s *FineTuneDeleteService) SetJobID(jobID string) *FineTuneDeleteService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the FineTuneDelete request, see WithCallBody
+func (s *FineTuneDeleteService) SetExtraBody(body M) *FineTuneDeleteService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FineTuneDelete request
+func (s *FineTuneDeleteService) SetExtraHeaders(headers map[string]string) *FineTuneDeleteService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FineTuneDelete request locally, see ValidationError
+func (s *FineTuneDeleteService) Validate() error {
+	v := newValidator("FineTuneDelete")
+	v.required("job_id", s.jobID)
+	return v.err()
+}
+
 // Do makes the request
-func (s *FineTuneDeleteService) Do(ctx context.Context) (res FineTuneItem, err error) {
+func (s *FineTuneDeleteService) Do(ctx context.Context, opts ...CallOption) (res FineTuneItem, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FineTuneDelete", Method: "DELETE", Path: "fine_tuning/jobs/" + s.jobID}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FineTuneDeleteService) do(ctx context.Context, op *Operation) (res FineTuneItem, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -418,6 +683,7 @@ func (s *FineTuneDeleteService) Do(ctx context.Context) (res FineTuneItem, err e
 // FineTuneCancelService creates a new fine tune cancel
 type FineTuneCancelService struct {
 	client *Client
+	extras serviceExtras
 	jobID  string
 }
 
@@ -434,8 +700,34 @@ func (s *FineTuneCancelService) SetJobID(jobID string) *FineTuneCancelService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the FineTuneCancel request, see WithCallBody
+func (s *FineTuneCancelService) SetExtraBody(body M) *FineTuneCancelService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the FineTuneCancel request
+func (s *FineTuneCancelService) SetExtraHeaders(headers map[string]string) *FineTuneCancelService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the FineTuneCancel request locally, see ValidationError
+func (s *FineTuneCancelService) Validate() error {
+	v := newValidator("FineTuneCancel")
+	v.required("job_id", s.jobID)
+	return v.err()
+}
+
 // Do makes the request
-func (s *FineTuneCancelService) Do(ctx context.Context) (res FineTuneItem, err error) {
+func (s *FineTuneCancelService) Do(ctx context.Context, opts ...CallOption) (res FineTuneItem, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "FineTuneCancel", Method: "POST", Path: "fine_tuning/jobs/" + s.jobID + "/cancel", Body: M{}}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *FineTuneCancelService) do(ctx context.Context, op *Operation) (res FineTuneItem, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -443,6 +735,7 @@ func (s *FineTuneCancelService) Do(ctx context.Context) (res FineTuneItem, err e
 
 	if resp, err = s.client.request(ctx).
 		SetPathParam("job_id", s.jobID).
+		SetBody(op.Body).
 		SetResult(&res).
 		SetError(&apiError).
 		Post("fine_tuning/jobs/{job_id}/cancel"); err != nil {
@@ -0,0 +1,326 @@
+package zhipu
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultFineTuneMaxTokens is the default max estimated tokens of a fine-tuning example
+	defaultFineTuneMaxTokens = 8192
+	// defaultFineTuneEpochs is the default epochs of the estimated training cost
+	defaultFineTuneEpochs = 3
+)
+
+// FineTuneExample is a line of a fine-tuning dataset
+type FineTuneExample struct {
+	Messages []ChatCompletionMessage `json:"messages"`
+	Tools    []any                   `json:"tools,omitempty"`
+}
+
+// FineTuneDatasetWriter writes a fine-tuning dataset in JSONL
+type FineTuneDatasetWriter struct {
+	w  io.Writer
+	je *json.Encoder
+}
+
+// NewFineTuneDatasetWriter creates a new FineTuneDatasetWriter
+func NewFineTuneDatasetWriter(w io.Writer) *FineTuneDatasetWriter {
+	je := json.NewEncoder(w)
+	je.SetEscapeHTML(false)
+	return &FineTuneDatasetWriter{w: w, je: je}
+}
+
+// Write writes an example with the messages, and the tools available in the example
+func (w *FineTuneDatasetWriter) Write(messages []ChatCompletionMessage, tools ...ChatCompletionTool) error {
+	ex := FineTuneExample{Messages: messages}
+	for _, tool := range tools {
+		if body := encodeChatCompletionTool(tool); body != nil {
+			ex.Tools = append(ex.Tools, body)
+		}
+	}
+	return w.je.Encode(ex)
+}
+
+// FineTuneDatasetIssue is a problem of a line of a fine-tuning dataset
+type FineTuneDatasetIssue struct {
+	// Line is the line number, starting from 1
+	Line int `json:"line"`
+	// Field is the path of the field in the line, like "messages[1].content"
+	Field string `json:"field,omitempty"`
+	// Message is the problem
+	Message string `json:"message"`
+}
+
+// Error implements error
+func (i FineTuneDatasetIssue) Error() string {
+	if i.Field == "" {
+		return fmt.Sprintf("line %d: %s", i.Line, i.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", i.Line, i.Field, i.Message)
+}
+
+// FineTuneDatasetStats is the result of FineTuneDatasetValidator, tokens are estimated locally
+type FineTuneDatasetStats struct {
+	Examples        int   `json:"examples"`
+	InvalidExamples int   `json:"invalid_examples"`
+	Messages        int   `json:"messages"`
+	Tokens          int64 `json:"tokens"`
+	MinTokens       int64 `json:"min_tokens"`
+	MaxTokens       int64 `json:"max_tokens"`
+	// AssistantTokens are the tokens of the assistant messages
+	AssistantTokens int64 `json:"assistant_tokens"`
+	// TrainedTokens are the tokens of the valid examples multiplied by the epochs
+	TrainedTokens int64 `json:"trained_tokens"`
+	// EstimatedCost is the cost of the trained tokens with the training price
+	EstimatedCost float64                `json:"estimated_cost"`
+	Issues        []FineTuneDatasetIssue `json:"issues,omitempty"`
+}
+
+// Valid returns true if there is no issue
+func (s FineTuneDatasetStats) Valid() bool {
+	return len(s.Issues) == 0 && s.Examples > 0
+}
+
+// Err returns an error with all issues, nil if valid
+func (s FineTuneDatasetStats) Err() error {
+	if s.Examples == 0 {
+		return fmt.Errorf("zhipu: fine-tuning dataset is empty")
+	}
+	if len(s.Issues) == 0 {
+		return nil
+	}
+	items := make([]string, 0, len(s.Issues))
+	for _, issue := range s.Issues {
+		items = append(items, issue.Error())
+	}
+	return fmt.Errorf("zhipu: invalid fine-tuning dataset: %s", strings.Join(items, "; "))
+}
+
+// FineTuneDatasetValidator checks a fine-tuning dataset locally, before FileCreate(FilePurposeFineTune)
+type FineTuneDatasetValidator struct {
+	maxTokens int64
+	epochs    int
+	price     ModelPrice
+}
+
+// NewFineTuneDatasetValidator creates a new FineTuneDatasetValidator
+func NewFineTuneDatasetValidator() *FineTuneDatasetValidator {
+	return &FineTuneDatasetValidator{
+		maxTokens: defaultFineTuneMaxTokens,
+		epochs:    defaultFineTuneEpochs,
+	}
+}
+
+// SetMaxTokens sets the max estimated tokens of an example, default to 8192
+func (v *FineTuneDatasetValidator) SetMaxTokens(maxTokens int64) *FineTuneDatasetValidator {
+	v.maxTokens = maxTokens
+	return v
+}
+
+// SetEpochs sets the epochs of the estimated cost, default to 3
+func (v *FineTuneDatasetValidator) SetEpochs(epochs int) *FineTuneDatasetValidator {
+	v.epochs = epochs
+	return v
+}
+
+// SetPrice sets the price of the estimated cost, only Training is used
+func (v *FineTuneDatasetValidator) SetPrice(price ModelPrice) *FineTuneDatasetValidator {
+	v.price = price
+	return v
+}
+
+// ValidateFile checks a local fine-tuning dataset
+func (v *FineTuneDatasetValidator) ValidateFile(file string) (stats FineTuneDatasetStats, err error) {
+	var f *os.File
+	if f, err = os.Open(file); err != nil {
+		return
+	}
+	defer f.Close()
+	return v.Validate(f)
+}
+
+// Validate checks a fine-tuning dataset, problems are reported as issues, err is only for read errors
+func (v *FineTuneDatasetValidator) Validate(r io.Reader) (stats FineTuneDatasetStats, err error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 16<<20)
+
+	var line int
+	for sc.Scan() {
+		line++
+		buf := bytes.TrimSpace(sc.Bytes())
+		if len(buf) == 0 {
+			continue
+		}
+		stats.Examples++
+
+		issues := len(stats.Issues)
+		add := func(field string, format string, args ...any) {
+			stats.Issues = append(stats.Issues, FineTuneDatasetIssue{Line: line, Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+
+		tokens, assistantTokens, messages := v.validateExample(buf, add)
+		stats.Messages += messages
+
+		if tokens > v.maxTokens && v.maxTokens > 0 {
+			add("", "estimated %d tokens, more than %d", tokens, v.maxTokens)
+		}
+		if len(stats.Issues) != issues {
+			stats.InvalidExamples++
+			continue
+		}
+
+		stats.Tokens += tokens
+		stats.AssistantTokens += assistantTokens
+		if stats.MinTokens == 0 || tokens < stats.MinTokens {
+			stats.MinTokens = tokens
+		}
+		if tokens > stats.MaxTokens {
+			stats.MaxTokens = tokens
+		}
+	}
+	if err = sc.Err(); err != nil {
+		return
+	}
+
+	stats.TrainedTokens = stats.Tokens * int64(v.epochs)
+	stats.EstimatedCost = float64(stats.TrainedTokens) * v.price.Training / 1_000_000
+	return
+}
+
+// validateExample checks a line and returns the estimated tokens
+func (v *FineTuneDatasetValidator) validateExample(buf []byte, add func(field string, format string, args ...any)) (tokens, assistantTokens int64, count int) {
+	var ex struct {
+		Messages []json.RawMessage `json:"messages"`
+		Tools    []json.RawMessage `json:"tools"`
+	}
+	if err := json.Unmarshal(buf, &ex); err != nil {
+		add("", "invalid json: %s", err.Error())
+		return
+	}
+	if len(ex.Messages) == 0 {
+		add("messages", "is required")
+		return
+	}
+	count = len(ex.Messages)
+
+	for i, raw := range ex.Tools {
+		field := fmt.Sprintf("tools[%d]", i)
+		tool, err := DecodeChatCompletionTool(raw)
+		if err != nil {
+			add(field, "%s", err.Error())
+			continue
+		}
+		if fn, ok := tool.(ChatCompletionToolFunction); ok && !chatCompletionFunctionNamePattern.MatchString(fn.Name) {
+			add(field+".function.name", "must match %s", chatCompletionFunctionNamePattern.String())
+		}
+		tokens += estimateTextTokens(string(raw))
+	}
+
+	var prev string
+	for i, raw := range ex.Messages {
+		field := fmt.Sprintf("messages[%d]", i)
+
+		var msg ChatCompletionMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			add(field, "invalid message: %s", err.Error())
+			prev = ""
+			continue
+		}
+
+		n := estimateTextTokens(msg.Content)
+		for _, call := range msg.ToolCalls {
+			if call.Function != nil {
+				n += estimateTextTokens(call.Function.Name) + estimateTextTokens(string(call.Function.Arguments))
+			}
+		}
+		tokens += n
+		if msg.Role == RoleAssistant {
+			assistantTokens += n
+		}
+
+		switch msg.Role {
+		case RoleSystem:
+			if i != 0 {
+				add(field+".role", "system message must be the first")
+			}
+		case RoleUser:
+			if prev == RoleUser {
+				add(field+".role", "user message must not follow a user message")
+			}
+		case RoleAssistant:
+			if prev != RoleUser && prev != RoleTool {
+				add(field+".role", "assistant message must follow a user or tool message")
+			}
+		case RoleTool:
+			if prev != RoleTool && !(prev == RoleAssistant && hasToolCalls(ex.Messages[i-1])) {
+				add(field+".role", "tool message must follow an assistant message with tool calls")
+			}
+		default:
+			add(field+".role", "must be one of %s, %s, %s, %s", RoleSystem, RoleUser, RoleAssistant, RoleTool)
+		}
+
+		if strings.TrimSpace(msg.Content) == "" && !(msg.Role == RoleAssistant && len(msg.ToolCalls) != 0) {
+			add(field+".content", "is required")
+		}
+		if msg.Role != RoleAssistant && len(msg.ToolCalls) != 0 {
+			add(field+".tool_calls", "only allowed in assistant messages")
+		}
+		for j, call := range msg.ToolCalls {
+			validateFineTuneToolCall(fmt.Sprintf("%s.tool_calls[%d]", field, j), call, add)
+		}
+
+		prev = msg.Role
+	}
+
+	if prev != RoleAssistant {
+		add(fmt.Sprintf("messages[%d].role", len(ex.Messages)-1), "last message must be an assistant message")
+	}
+	return
+}
+
+// hasToolCalls returns true if the raw message has tool calls
+func hasToolCalls(raw json.RawMessage) bool {
+	var msg ChatCompletionMessage
+	return json.Unmarshal(raw, &msg) == nil && len(msg.ToolCalls) != 0
+}
+
+// validateFineTuneToolCall checks a tool call of an assistant message
+func validateFineTuneToolCall(field string, call ChatCompletionToolCall, add func(field string, format string, args ...any)) {
+	if call.Type != ToolTypeFunction {
+		add(field+".type", "must be %s", ToolTypeFunction)
+		return
+	}
+	if call.Function == nil {
+		add(field+".function", "is required")
+		return
+	}
+	if !chatCompletionFunctionNamePattern.MatchString(call.Function.Name) {
+		add(field+".function.name", "must match %s", chatCompletionFunctionNamePattern.String())
+	}
+	// arguments are either a json object, or a string of a json object
+	args := call.Function.Arguments
+	var s string
+	if json.Unmarshal(args, &s) == nil {
+		args = json.RawMessage(s)
+	}
+	var obj map[string]any
+	if json.Unmarshal(args, &obj) != nil {
+		add(field+".function.arguments", "must be a json object")
+	}
+}
+
+// estimateTextTokens roughly estimates the tokens of a text, like estimateTokens
+func estimateTextTokens(text string) int64 {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return 0
+	}
+	return int64(n+1) / 2
+}
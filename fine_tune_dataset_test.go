@@ -0,0 +1,88 @@
+package zhipu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFineTuneDatasetValidatorFile(t *testing.T) {
+	stats, err := NewFineTuneDatasetValidator().
+		SetPrice(ModelPrice{Training: 1000}).
+		ValidateFile("testdata/test-file.jsonl")
+	require.NoError(t, err)
+	require.NoError(t, stats.Err())
+	require.True(t, stats.Valid())
+	require.Equal(t, 3, stats.Examples)
+	require.Equal(t, 9, stats.Messages)
+	require.Greater(t, stats.MinTokens, int64(0))
+	require.GreaterOrEqual(t, stats.MaxTokens, stats.MinTokens)
+	require.Equal(t, stats.Tokens*3, stats.TrainedTokens)
+	require.InDelta(t, float64(stats.TrainedTokens)*1000/1_000_000, stats.EstimatedCost, 1e-9)
+}
+
+func TestFineTuneDatasetValidatorIssues(t *testing.T) {
+	dataset := strings.Join([]string{
+		`{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}`,
+		`not json`,
+		`{"messages":[]}`,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"system","content":"sys"},{"role":"assistant","content":""}]}`,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"bot","content":"hello"}]}`,
+		`{"messages":[{"role":"user","content":"weather?"},{"role":"assistant","tool_calls":[{"type":"function","function":{"name":"get weather","arguments":"{bad"}}]},{"role":"tool","content":"sunny"},{"role":"assistant","content":"sunny"}]}`,
+		`{"messages":[{"role":"user","content":"hi"},{"role":"tool","content":"x"},{"role":"assistant","content":"ok"}]}`,
+		`{"messages":[{"role":"user","content":"a very long message"},{"role":"assistant","content":"ok"}]}`,
+		``,
+		`{"tools":[{"type":"unknown"}],"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"ok"}]}`,
+	}, "\n")
+
+	stats, err := NewFineTuneDatasetValidator().SetMaxTokens(8).Validate(strings.NewReader(dataset))
+	require.NoError(t, err)
+	require.Equal(t, 9, stats.Examples)
+	require.Equal(t, 8, stats.InvalidExamples)
+	require.False(t, stats.Valid())
+	require.Error(t, stats.Err())
+
+	fields := map[string]bool{}
+	for _, issue := range stats.Issues {
+		fields[issue.Error()] = true
+	}
+	for _, want := range []string{
+		"line 3: messages: is required",
+		"line 4: messages[1].role: system message must be the first",
+		"line 4: messages[2].content: is required",
+		"line 5: messages[1].role: must be one of system, user, assistant, tool",
+		"line 5: messages[1].role: last message must be an assistant message",
+		"line 6: messages[1].tool_calls[0].function.name: must match ^[a-zA-Z0-9_-]{1,64}$",
+		"line 6: messages[1].tool_calls[0].function.arguments: must be a json object",
+		"line 7: messages[1].role: tool message must follow an assistant message with tool calls",
+		"line 8: estimated 11 tokens, more than 8",
+		`line 10: tools[0]: zhipu: unknown tool type "unknown"`,
+	} {
+		require.True(t, fields[want], want)
+	}
+	require.True(t, strings.HasPrefix(stats.Issues[0].Error(), "line 2: invalid json"))
+}
+
+func TestFineTuneDatasetWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewFineTuneDatasetWriter(buf)
+	require.NoError(t, w.Write([]ChatCompletionMessage{
+		NewSystemMessage("你是助手"),
+		NewUserMessage("<天气>"),
+		{Role: RoleAssistant, ToolCalls: []ChatCompletionToolCall{{Type: ToolTypeFunction, Function: &ChatCompletionToolCallFunction{Name: "weather", Arguments: []byte(`"{}"`)}}}},
+		NewToolMessage("", "晴"),
+		NewAssistantMessage("晴天"),
+	}, ChatCompletionToolFunction{Name: "weather", Parameters: M{"type": "object"}}))
+	require.NoError(t, w.Write([]ChatCompletionMessage{NewUserMessage("hi"), NewAssistantMessage("hello")}))
+
+	require.Equal(t, `{"messages":[{"role":"system","content":"你是助手"},{"role":"user","content":"<天气>"},{"role":"assistant","tool_calls":[{"id":"","type":"function","function":{"name":"weather","arguments":"{}"}}]},{"role":"tool","content":"晴"},{"role":"assistant","content":"晴天"}],"tools":[{"function":{"name":"weather","description":"","parameters":{"type":"object"}},"type":"function"}]}
+{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}]}
+`, buf.String())
+
+	stats, err := NewFineTuneDatasetValidator().Validate(buf)
+	require.NoError(t, err)
+	require.NoError(t, stats.Err())
+	require.Equal(t, 2, stats.Examples)
+}
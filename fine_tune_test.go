@@ -1,3 +1,68 @@
 package zhipu
 
-// tests not available since lack of budget to test it
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// live tests not available since lack of budget to test it, only local ones
+
+func TestFineTuneStatusIsTerminal(t *testing.T) {
+	for status, terminal := range map[FineTuneStatus]bool{
+		FineTuneStatusCreate:          false,
+		FineTuneStatusValidatingFiles: false,
+		FineTuneStatusQueued:          false,
+		FineTuneStatusRunning:         false,
+		FineTuneStatusSucceeded:       true,
+		FineTuneStatusFailed:          true,
+		FineTuneStatusCancelled:       true,
+	} {
+		require.Equal(t, terminal, status.IsTerminal(), status)
+	}
+}
+
+func TestFineTuneCreateServiceBody(t *testing.T) {
+	s := NewFineTuneCreateService(nil).
+		SetModel("chatglm3-6b").
+		SetTrainingFile("file-1").
+		SetNEpochs(3).
+		SetLearningRateMultiplierAuto()
+	buf, err := json.Marshal(s.buildBody())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"model":"chatglm3-6b","training_file":"file-1","hyperparameters":{"n_epochs":3,"learning_rate_multiplier":"auto"}}`, string(buf))
+	require.NoError(t, s.Validate())
+
+	s.SetMethod(FineTuneMethodDPO).SetBetaAuto().SetTrainingType(FineTuneTrainingTypeLoRA)
+	buf, err = json.Marshal(s.buildBody())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"model":"chatglm3-6b","training_file":"file-1",
+		"method":{"type":"dpo","dpo":{"hyperparameters":{"n_epochs":3,"learning_rate_multiplier":"auto","beta":"auto"}}},
+		"extra_hyperparameters":{"fine_tuning_method":"lora"}}`, string(buf))
+	require.NoError(t, s.Validate())
+
+	s.SetMethod(FineTuneMethodSupervised).SetBeta(0.1).SetTrainingType("half")
+	err = s.Validate()
+	require.ErrorContains(t, err, "method.supervised.hyperparameters.beta: is only for method dpo")
+	require.ErrorContains(t, err, "extra_hyperparameters.fine_tuning_method: must be one of lora, full")
+}
+
+func TestFineTuneItemUnmarshal(t *testing.T) {
+	var item FineTuneItem
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id":"ftjob-1","model":"glm-4-flash","status":"succeeded","fine_tuned_model":"glm-4-flash-ft",
+		"created_at":1700000000,"finished_at":1700003600,"trained_tokens":12345,"result_files":["file-2"],
+		"hyperparameters":{"n_epochs":"auto","batch_size":8,"learning_rate_multiplier":1.5},
+		"method":{"type":"supervised","supervised":{"hyperparameters":{"n_epochs":3}}},
+		"extra_hyperparameters":{"fine_tuning_method":"full"}
+	}`), &item))
+	require.True(t, item.Status.IsTerminal())
+	require.Equal(t, int64(3600), item.FinishedAt-item.CreatedAt)
+	require.Equal(t, int64(12345), item.TrainedTokens)
+	require.Equal(t, []string{"file-2"}, item.ResultFiles)
+	require.Equal(t, HyperParameterAuto, *item.Hyperparameters.NEpochs.String)
+	require.Equal(t, 8, *item.Hyperparameters.BatchSize.Value)
+	require.Equal(t, 3, *item.Method.Supervised.Hyperparameters.NEpochs.Value)
+	require.Equal(t, FineTuneTrainingTypeFull, item.ExtraHyperparameters.FineTuningMethod)
+}
@@ -0,0 +1,217 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFineTuneWatchInterval = 30 * time.Second
+	defaultFineTuneWatchPageSize = 100
+)
+
+var (
+	// ErrFineTuneNotSucceeded is returned by FineTuneWatcher.Watch when the job ends failed or cancelled
+	ErrFineTuneNotSucceeded = errors.New("zhipu: fine-tuning job did not succeed")
+)
+
+// FineTuneEventHandler is called for each new event of the watched job, returning an error stops the watch
+type FineTuneEventHandler func(event FineTuneEventItem) error
+
+// FineTuneMetric is a point of the training metrics, collected from the data of the events
+type FineTuneMetric struct {
+	Time          time.Time
+	Epoch         int64
+	Step          int64
+	TotalSteps    int64
+	Loss          float64
+	Acc           float64
+	LearningRate  float64
+	TrainedTokens int64
+}
+
+// FineTuneWatcher follows a fine-tuning job, polling the job and its events until a terminal status
+type FineTuneWatcher struct {
+	client *Client
+
+	jobID    string
+	interval time.Duration
+	pageSize int
+	handler  FineTuneEventHandler
+
+	mu      sync.Mutex
+	seen    map[string]bool
+	after   string
+	metrics []FineTuneMetric
+}
+
+// NewFineTuneWatcher creates a new FineTuneWatcher
+func NewFineTuneWatcher(client *Client) *FineTuneWatcher {
+	return &FineTuneWatcher{
+		client:   client,
+		interval: defaultFineTuneWatchInterval,
+		pageSize: defaultFineTuneWatchPageSize,
+		seen:     map[string]bool{},
+	}
+}
+
+// SetJobID sets the id of the job to watch
+func (w *FineTuneWatcher) SetJobID(jobID string) *FineTuneWatcher {
+	w.jobID = jobID
+	return w
+}
+
+// SetInterval sets the interval of polling, default to 30s
+func (w *FineTuneWatcher) SetInterval(interval time.Duration) *FineTuneWatcher {
+	w.interval = interval
+	return w
+}
+
+// SetPageSize sets the limit of each event list request, default to 100
+func (w *FineTuneWatcher) SetPageSize(pageSize int) *FineTuneWatcher {
+	w.pageSize = pageSize
+	return w
+}
+
+// SetEventHandler sets the handler of new events, each event is handled once, in chronological order
+func (w *FineTuneWatcher) SetEventHandler(handler FineTuneEventHandler) *FineTuneWatcher {
+	w.handler = handler
+	return w
+}
+
+// Watch polls until the job reaches a terminal status and returns the final job, FineTunedModel included,
+// ErrFineTuneNotSucceeded is wrapped if the job is failed or cancelled, the trained tokens of a succeeded job
+// are recorded once in the UsageTracker of the client, with the usage tags of the context
+func (w *FineTuneWatcher) Watch(ctx context.Context) (res FineTuneItem, err error) {
+	for {
+		// the job is fetched before the events, so events of a terminal job are all fetched
+		if res, err = w.client.FineTuneGet(w.jobID).Do(ctx); err != nil {
+			return
+		}
+		if err = w.poll(ctx); err != nil {
+			return
+		}
+		if res.Status.IsTerminal() {
+			if w.client.usage != nil {
+				w.client.usage.RecordFineTuneJob(res, UsageTags(ctx)...)
+			}
+			if res.Status != FineTuneStatusSucceeded {
+				err = fmt.Errorf("%w: %s is %s", ErrFineTuneNotSucceeded, res.ID, res.Status)
+				if res.Error.Message != "" {
+					err = fmt.Errorf("%w: %s", err, res.Error.Message)
+				}
+			}
+			return
+		}
+
+		timer := time.NewTimer(w.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// poll fetches the new events, page by page, "after" is the id of the newest event already seen,
+// pages are in chronological order like the API, events are sorted by time in case they are not
+func (w *FineTuneWatcher) poll(ctx context.Context) (err error) {
+	for {
+		s := w.client.FineTuneEventList(w.jobID).SetLimit(w.pageSize)
+		if w.after != "" {
+			s.SetAfter(w.after)
+		}
+		var res FineTuneEventListResponse
+		if res, err = s.Do(ctx); err != nil {
+			return
+		}
+
+		page := append([]FineTuneEventItem(nil), res.Data...)
+		sort.SliceStable(page, func(i, j int) bool {
+			return page[i].CreatedAt < page[j].CreatedAt
+		})
+		if len(page) != 0 {
+			w.after = page[len(page)-1].ID
+		}
+
+		var fresh int
+		for _, event := range page {
+			if w.seen[event.ID] {
+				continue
+			}
+			fresh++
+			w.seen[event.ID] = true
+			w.record(event)
+			if w.handler != nil {
+				if err = w.handler(event); err != nil {
+					return
+				}
+			}
+		}
+
+		// stop if no progress, in case has_more is stuck
+		if !res.HasMore || fresh == 0 {
+			return
+		}
+	}
+}
+
+// record collects the metrics of the event, if any
+func (w *FineTuneWatcher) record(event FineTuneEventItem) {
+	d := event.Data
+	if d == (FineTuneEventData{}) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.metrics = append(w.metrics, FineTuneMetric{
+		Time:          time.Unix(event.CreatedAt, 0),
+		Epoch:         d.Epoch,
+		Step:          d.CurrentSteps,
+		TotalSteps:    d.TotalSteps,
+		Loss:          d.Loss,
+		Acc:           d.Acc,
+		LearningRate:  d.LearningRate,
+		TrainedTokens: d.TrainedTokens,
+	})
+}
+
+// Metrics returns the metrics collected so far, in chronological order
+func (w *FineTuneWatcher) Metrics() []FineTuneMetric {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]FineTuneMetric(nil), w.metrics...)
+}
+
+// WriteMetricsCSV writes the metrics collected so far as csv, with a header line
+func (w *FineTuneWatcher) WriteMetricsCSV(out io.Writer) error {
+	cw := csv.NewWriter(out)
+	if err := cw.Write([]string{"time", "epoch", "step", "total_steps", "loss", "acc", "learning_rate", "trained_tokens"}); err != nil {
+		return err
+	}
+	for _, m := range w.Metrics() {
+		if err := cw.Write([]string{
+			m.Time.UTC().Format(time.RFC3339),
+			strconv.FormatInt(m.Epoch, 10),
+			strconv.FormatInt(m.Step, 10),
+			strconv.FormatInt(m.TotalSteps, 10),
+			strconv.FormatFloat(m.Loss, 'g', -1, 64),
+			strconv.FormatFloat(m.Acc, 'g', -1, 64),
+			strconv.FormatFloat(m.LearningRate, 'g', -1, 64),
+			strconv.FormatInt(m.TrainedTokens, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
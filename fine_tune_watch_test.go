@@ -0,0 +1,153 @@
+package zhipu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFineTuneServer serves the job with the statuses, one per get, and the events logged so far,
+// stages[i] is the event log at the i-th get, events are listed in chronological order after the "after" cursor,
+// each page is reversed if newestFirst
+func newTestFineTuneServer(t *testing.T, statuses []FineTuneStatus, stages [][]FineTuneEventItem, newestFirst bool) (*httptest.Server, *[]string) {
+	var (
+		mu     sync.Mutex
+		gets   int
+		cursor []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		rw.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/events") {
+			events := stages[min(gets-1, len(stages)-1)]
+			after := r.URL.Query().Get("after")
+			cursor = append(cursor, after)
+			if after != "" {
+				for i, e := range events {
+					if e.ID == after {
+						events = events[i+1:]
+						break
+					}
+				}
+			}
+			res := FineTuneEventListResponse{Data: []FineTuneEventItem{}}
+			if limit, _ := strconv.Atoi(r.URL.Query().Get("limit")); limit > 0 && len(events) > limit {
+				events, res.HasMore = events[:limit], true
+			}
+			res.Data = append(res.Data, events...)
+			if newestFirst {
+				slices.Reverse(res.Data)
+			}
+			_ = json.NewEncoder(rw).Encode(res)
+			return
+		}
+		status := statuses[min(gets, len(statuses)-1)]
+		gets++
+		item := FineTuneItem{ID: "job-1", Status: status}
+		if status == FineTuneStatusSucceeded {
+			item.Model, item.FineTunedModel, item.TrainedTokens = "chatglm3-6b", "chatglm3-6b-ft-1", 1000
+		}
+		if status == FineTuneStatusFailed {
+			item.Error = APIError{Code: "1", Message: "oom"}
+		}
+		_ = json.NewEncoder(rw).Encode(item)
+	}))
+	t.Cleanup(s.Close)
+	return s, &cursor
+}
+
+func TestFineTuneWatcher(t *testing.T) {
+	e1 := FineTuneEventItem{ID: "e1", CreatedAt: 100, Message: "started"}
+	e2 := FineTuneEventItem{ID: "e2", CreatedAt: 160, Data: FineTuneEventData{Epoch: 1, CurrentSteps: 10, TotalSteps: 20, Loss: 0.5, Acc: 0.8, LearningRate: 1e-4}}
+	e3 := FineTuneEventItem{ID: "e3", CreatedAt: 220, Data: FineTuneEventData{Epoch: 2, CurrentSteps: 20, TotalSteps: 20, Loss: 0.25, Acc: 0.9}}
+	e4 := FineTuneEventItem{ID: "e4", CreatedAt: 230, Message: "succeeded"}
+
+	for _, newestFirst := range []bool{false, true} {
+		s, cursor := newTestFineTuneServer(t,
+			[]FineTuneStatus{FineTuneStatusQueued, FineTuneStatusRunning, FineTuneStatusSucceeded},
+			[][]FineTuneEventItem{{e1}, {e1, e2, e3}, {e1, e2, e3, e4}},
+			newestFirst,
+		)
+		testFineTuneWatcher(t, s, cursor)
+	}
+}
+
+func testFineTuneWatcher(t *testing.T, s *httptest.Server, cursor *[]string) {
+	tracker := NewUsageTracker().SetPrice("chatglm3-6b", ModelPrice{Training: 10})
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"), WithUsageTracker(tracker))
+	require.NoError(t, err)
+
+	var ids []string
+	w := client.FineTuneWatch("job-1").
+		SetInterval(time.Millisecond).
+		SetPageSize(2).
+		SetEventHandler(func(event FineTuneEventItem) error {
+			ids = append(ids, event.ID)
+			return nil
+		})
+
+	res, err := w.Watch(WithUsageTags(context.Background(), "ft"))
+	require.NoError(t, err)
+	require.Equal(t, "chatglm3-6b-ft-1", res.FineTunedModel)
+
+	// the trained tokens are recorded once, on success
+	snap := tracker.Snapshot()
+	require.Equal(t, UsageStats{Requests: 1, TrainedTokens: 1000, Cost: 0.01}, snap.Models["chatglm3-6b"])
+	require.Equal(t, int64(1), snap.Tags["ft"].Requests)
+	require.Equal(t, []string{"e1", "e2", "e3", "e4"}, ids)
+	// the cursor moves forward to the newest event seen
+	require.Equal(t, []string{"", "e1", "e3"}, *cursor)
+
+	metrics := w.Metrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, int64(10), metrics[0].Step)
+	require.Equal(t, 0.25, metrics[1].Loss)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, w.WriteMetricsCSV(buf))
+	require.Equal(t, `time,epoch,step,total_steps,loss,acc,learning_rate,trained_tokens
+1970-01-01T00:02:40Z,1,10,20,0.5,0.8,0.0001,0
+1970-01-01T00:03:40Z,2,20,20,0.25,0.9,0,0
+`, buf.String())
+	// watching the job again does not record it twice
+	_, err = client.FineTuneWatch("job-1").SetInterval(time.Millisecond).Watch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), tracker.Snapshot().Total.TrainedTokens)
+}
+
+func TestFineTuneWatcherErrors(t *testing.T) {
+	s, _ := newTestFineTuneServer(t, []FineTuneStatus{FineTuneStatusFailed}, [][]FineTuneEventItem{{{ID: "e1"}}}, false)
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	res, err := client.FineTuneWatch("job-1").Watch(context.Background())
+	require.ErrorIs(t, err, ErrFineTuneNotSucceeded)
+	require.Contains(t, err.Error(), "oom")
+	require.Equal(t, FineTuneStatusFailed, res.Status)
+
+	errStop := errors.New("stop")
+	_, err = client.FineTuneWatch("job-1").SetEventHandler(func(event FineTuneEventItem) error {
+		return errStop
+	}).Watch(context.Background())
+	require.ErrorIs(t, err, errStop)
+
+	s, _ = newTestFineTuneServer(t, []FineTuneStatus{FineTuneStatusRunning}, [][]FineTuneEventItem{{}}, false)
+	client, err = NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"))
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.FineTuneWatch("job-1").SetInterval(10 * time.Millisecond).Watch(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -9,6 +9,7 @@ import (
 // ImageGenerationService creates a new image generation
 type ImageGenerationService struct {
 	client *Client
+	extras serviceExtras
 
 	model  string
 	prompt string
@@ -21,6 +22,8 @@ var (
 
 // ImageGenerationResponse is the response of the ImageGenerationService
 type ImageGenerationResponse struct {
+	RawResponse
+
 	Created int64     `json:"created"`
 	Data    []URLItem `json:"data"`
 }
@@ -41,7 +44,7 @@ func (s *ImageGenerationService) BatchURL() string {
 }
 
 func (s *ImageGenerationService) BatchBody() any {
-	return s.buildBody()
+	return s.extras.mergeBody(s.buildBody())
 }
 
 // SetModel sets the model parameter
@@ -75,16 +78,67 @@ func (s *ImageGenerationService) buildBody() M {
 	return body
 }
 
-func (s *ImageGenerationService) Do(ctx context.Context) (res ImageGenerationResponse, err error) {
+// SetExtraBody sets extra fields of the ImageGeneration request, see WithCallBody
+func (s *ImageGenerationService) SetExtraBody(body M) *ImageGenerationService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the ImageGeneration request
+func (s *ImageGenerationService) SetExtraHeaders(headers map[string]string) *ImageGenerationService {
+	s.extras.headers = headers
+	return s
+}
+
+// FromBody rebuilds the service from a json request body, like the body of a batch line or a logged request,
+// unknown fields are kept as extra body, fields missing from the body are reset, the client and extra headers are kept
+func (s *ImageGenerationService) FromBody(data []byte) (err error) {
+	var fields bodyFields
+	if fields, err = decodeBodyFields(data); err != nil {
+		return
+	}
+	*s = ImageGenerationService{client: s.client, extras: serviceExtras{headers: s.extras.headers}}
+	if _, err = fields.take("model", &s.model); err != nil {
+		return
+	}
+	if _, err = fields.take("prompt", &s.prompt); err != nil {
+		return
+	}
+	if _, err = fields.take("user_id", &s.userID); err != nil {
+		return
+	}
+	s.extras.body, err = fields.rest()
+	return
+}
+
+// UnmarshalJSON implements json.Unmarshaler with FromBody
+func (s *ImageGenerationService) UnmarshalJSON(data []byte) error {
+	return s.FromBody(data)
+}
+
+// Validate checks the ImageGeneration request locally, see ValidationError
+func (s *ImageGenerationService) Validate() error {
+	v := newValidator("ImageGeneration")
+	v.required("model", s.model)
+	v.required("prompt", s.prompt)
+	return v.err()
+}
+
+func (s *ImageGenerationService) Do(ctx context.Context, opts ...CallOption) (res ImageGenerationResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "ImageGeneration", Model: s.model, Method: "POST", Path: "images/generations", Body: s.buildBody()}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *ImageGenerationService) do(ctx context.Context, op *Operation) (res ImageGenerationResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
 	)
 
-	body := s.buildBody()
-
 	if resp, err = s.client.request(ctx).
-		SetBody(body).
+		SetBody(op.Body).
 		SetResult(&res).
 		SetError(&apiError).
 		Post("images/generations"); err != nil {
@@ -8,7 +8,7 @@ import (
 )
 
 func TestImageGenerationService(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.ImageGeneration("cogview-3")
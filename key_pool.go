@@ -0,0 +1,265 @@
+package zhipu
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultKeyCooldown = time.Minute
+)
+
+// KeyPoolStrategy is the strategy to pick a key from the pool
+type KeyPoolStrategy int
+
+const (
+	// KeyPoolRoundRobin picks the keys in turn
+	KeyPoolRoundRobin KeyPoolStrategy = iota
+	// KeyPoolLeastLoad picks the key with the fewest in-flight requests
+	KeyPoolLeastLoad
+)
+
+// WithAPIKeys set a pool of api keys, requests are spread over the keys,
+// a key responding auth, quota or rate limit errors is skipped for a cooldown period, and the request is retried with another key
+func WithAPIKeys(apiKeys ...string) ClientOption {
+	return func(opts *clientOptions) {
+		opts.apiKeys = append(opts.apiKeys, apiKeys...)
+	}
+}
+
+// WithKeyPoolStrategy set the strategy of the key pool, default to KeyPoolRoundRobin
+func WithKeyPoolStrategy(strategy KeyPoolStrategy) ClientOption {
+	return func(opts *clientOptions) {
+		opts.keyPoolStrategy = strategy
+	}
+}
+
+// WithKeyCooldown set the cooldown period of a failing key in the pool, default to 1 minute
+func WithKeyCooldown(cooldown time.Duration) ClientOption {
+	return func(opts *clientOptions) {
+		opts.keyCooldown = cooldown
+	}
+}
+
+// KeyStats is the usage stats of a key in the pool
+type KeyStats struct {
+	// KeyID is the id part of the api key, the secret is never exposed
+	KeyID string
+	// Requests is the number of requests sent with the key
+	Requests int64
+	// Failures is the number of requests failed with auth, quota or rate limit errors
+	Failures int64
+	// InFlight is the number of requests in flight
+	InFlight int64
+	// TotalTokens is the total tokens reported by the non-stream responses
+	TotalTokens int64
+	// CooldownUntil is the end of the cooldown period, zero if the key is available
+	CooldownUntil time.Time
+}
+
+// poolKey is a key of the pool
+type poolKey struct {
+	provider TokenProvider
+	stats    KeyStats
+}
+
+// keyPoolTransport is a http.RoundTripper spreading requests over a pool of keys
+type keyPoolTransport struct {
+	next     http.RoundTripper
+	strategy KeyPoolStrategy
+	cooldown time.Duration
+
+	mu     sync.Mutex
+	keys   []*poolKey
+	cursor int
+}
+
+func newKeyPoolTransport(next http.RoundTripper, apiKeys []string, plain bool, ttl time.Duration, strategy KeyPoolStrategy, cooldown time.Duration) (t *keyPoolTransport, err error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if cooldown <= 0 {
+		cooldown = defaultKeyCooldown
+	}
+	t = &keyPoolTransport{next: next, strategy: strategy, cooldown: cooldown}
+	for _, apiKey := range apiKeys {
+		apiKey = strings.TrimSpace(apiKey)
+		keyID, _, ok := strings.Cut(apiKey, ".")
+		if !ok {
+			err = ErrAPIKeyMalformed
+			return
+		}
+		key := &poolKey{stats: KeyStats{KeyID: keyID}}
+		if plain {
+			key.provider = NewAPIKeyTokenProvider(apiKey)
+		} else if key.provider, err = NewJWTTokenProvider(apiKey, ttl); err != nil {
+			return
+		}
+		t.keys = append(t.keys, key)
+	}
+	return
+}
+
+// pick picks an available key not tried yet, all keys cooling down are skipped unless none is left
+func (t *keyPoolTransport) pick(tried map[*poolKey]bool) (key *poolKey) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+
+	var (
+		fallback *poolKey
+		picked   int
+	)
+
+	for i := range t.keys {
+		idx := (t.cursor + i) % len(t.keys)
+		k := t.keys[idx]
+		if tried[k] {
+			continue
+		}
+		if now.Before(k.stats.CooldownUntil) {
+			if fallback == nil || k.stats.CooldownUntil.Before(fallback.stats.CooldownUntil) {
+				fallback = k
+			}
+			continue
+		}
+		if t.strategy == KeyPoolLeastLoad {
+			if key == nil || k.stats.InFlight < key.stats.InFlight {
+				key, picked = k, idx
+			}
+			continue
+		}
+		key, picked = k, idx
+		break
+	}
+
+	// ties of least load rotate as well
+	if key != nil {
+		t.cursor = picked + 1
+	}
+
+	if key == nil {
+		// tried keys are not retried, cooling keys are only used when all keys are cooling
+		if len(tried) != 0 {
+			return nil
+		}
+		key = fallback
+	}
+
+	key.stats.Requests++
+	key.stats.InFlight++
+	return
+}
+
+// done updates the stats of the key after the request
+func (t *keyPoolTransport) done(key *poolKey, failed bool, tokens int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key.stats.InFlight--
+	key.stats.TotalTokens += tokens
+	if failed {
+		key.stats.Failures++
+		key.stats.CooldownUntil = time.Now().Add(t.cooldown)
+	}
+}
+
+// Stats returns the stats of all keys
+func (t *keyPoolTransport) Stats() (out []KeyStats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, k := range t.keys {
+		out = append(out, k.stats)
+	}
+	return
+}
+
+// isKeyFailure returns true if the response indicates the key is unusable for now
+func isKeyFailure(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *keyPoolTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	tried := map[*poolKey]bool{}
+
+	for {
+		key := t.pick(tried)
+		if key == nil {
+			// every key failed, return the last response
+			return
+		}
+		tried[key] = true
+
+		if res != nil {
+			// discard the failed response of the previous key
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+
+		attempt := req.Clone(req.Context())
+		if len(tried) > 1 && req.GetBody != nil {
+			if attempt.Body, err = req.GetBody(); err != nil {
+				t.done(key, false, 0)
+				return
+			}
+		}
+
+		var token string
+		if token, err = key.provider.Token(req.Context()); err != nil {
+			t.done(key, false, 0)
+			return
+		}
+		attempt.Header.Set("Authorization", token)
+
+		if res, err = t.next.RoundTrip(attempt); err != nil {
+			t.done(key, false, 0)
+			return
+		}
+
+		if isKeyFailure(res) {
+			t.done(key, true, 0)
+			if !replayable {
+				return
+			}
+			continue
+		}
+
+		if res.StatusCode == http.StatusOK && strings.Contains(res.Header.Get("Content-Type"), "json") {
+			var buf []byte
+			buf, err = io.ReadAll(res.Body)
+			_ = res.Body.Close()
+			if err != nil {
+				t.done(key, false, 0)
+				return
+			}
+			res.Body = io.NopCloser(bytes.NewReader(buf))
+			var usage struct {
+				Usage struct {
+					TotalTokens int64 `json:"total_tokens"`
+				} `json:"usage"`
+			}
+			_ = json.Unmarshal(buf, &usage)
+			t.done(key, false, usage.Usage.TotalTokens)
+			return
+		}
+
+		// streams hold the in-flight count until the body is closed
+		var once sync.Once
+		res.Body = &releaseBody{ReadCloser: res.Body, release: func() {
+			once.Do(func() { t.done(key, false, 0) })
+		}}
+		return
+	}
+}
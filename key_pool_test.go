@@ -0,0 +1,68 @@
+package zhipu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyPool(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		used []string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		token, _, err := jwt.NewParser().ParseUnverified(r.Header.Get("Authorization"), jwt.MapClaims{})
+		require.NoError(t, err)
+		keyID := token.Claims.(jwt.MapClaims)["api_key"].(string)
+
+		mu.Lock()
+		used = append(used, keyID)
+		mu.Unlock()
+
+		rw.Header().Set("Content-Type", "application/json")
+		if keyID == "b" {
+			rw.WriteHeader(http.StatusTooManyRequests)
+			_, _ = rw.Write([]byte(`{"error":{"code":"1113","message":"insufficient balance"}}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[],"usage":{"total_tokens":3}}`))
+	}))
+	defer s.Close()
+
+	t.Setenv(envAPIKey, "")
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKeys("a.secret", "b.secret", "c.secret"), WithKeyCooldown(time.Hour))
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err = client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+		require.NoError(t, err)
+	}
+
+	// b fails once and is retried with c, then skipped
+	require.Equal(t, "a,b,c,a,c", strings.Join(used, ","))
+
+	stats := client.KeyStats()
+	require.Len(t, stats, 3)
+	require.Equal(t, KeyStats{KeyID: "a", Requests: 2, TotalTokens: 6}, stats[0])
+	require.Equal(t, int64(1), stats[1].Failures)
+	require.True(t, stats[1].CooldownUntil.After(time.Now()))
+	require.Equal(t, KeyStats{KeyID: "c", Requests: 2, TotalTokens: 6}, stats[2])
+
+	// all keys failing returns the error
+	client, err = NewClient(WithBaseURL(s.URL), WithAPIKeys("b.secret"), WithKeyPoolStrategy(KeyPoolLeastLoad))
+	require.NoError(t, err)
+	_, err = client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.Equal(t, "1113", GetAPIErrorCode(err))
+
+	_, err = NewClient(WithAPIKeys("malformed"))
+	require.ErrorIs(t, err, ErrAPIKeyMalformed)
+}
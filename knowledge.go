@@ -14,6 +14,7 @@ const (
 // KnowledgeCreateService creates a new knowledge
 type KnowledgeCreateService struct {
 	client *Client
+	extras serviceExtras
 
 	embeddingID int
 	name        string
@@ -48,12 +49,37 @@ func (s *KnowledgeCreateService) SetDescription(description string) *KnowledgeCr
 	return s
 }
 
+// SetExtraBody sets extra fields of the KnowledgeCreate request, see WithCallBody
+func (s *KnowledgeCreateService) SetExtraBody(body M) *KnowledgeCreateService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the KnowledgeCreate request
+func (s *KnowledgeCreateService) SetExtraHeaders(headers map[string]string) *KnowledgeCreateService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the KnowledgeCreate request locally, see ValidationError
+func (s *KnowledgeCreateService) Validate() error {
+	v := newValidator("KnowledgeCreate")
+	v.required("name", s.name)
+	if s.embeddingID <= 0 {
+		v.add("embedding_id", "is required")
+	}
+	return v.err()
+}
+
 // Do creates the knowledge
-func (s *KnowledgeCreateService) Do(ctx context.Context) (res KnowledgeCreateResponse, err error) {
-	var (
-		resp     *resty.Response
-		apiError APIErrorResponse
-	)
+func (s *KnowledgeCreateService) Do(ctx context.Context, opts ...CallOption) (res KnowledgeCreateResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "KnowledgeCreate", Method: "POST", Path: "knowledge", Body: s.buildBody()}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *KnowledgeCreateService) buildBody() M {
 	body := M{
 		"name":         s.name,
 		"embedding_id": s.embeddingID,
@@ -61,8 +87,16 @@ func (s *KnowledgeCreateService) Do(ctx context.Context) (res KnowledgeCreateRes
 	if s.description != nil {
 		body["description"] = *s.description
 	}
+	return body
+}
+
+func (s *KnowledgeCreateService) do(ctx context.Context, op *Operation) (res KnowledgeCreateResponse, err error) {
+	var (
+		resp     *resty.Response
+		apiError APIErrorResponse
+	)
 	if resp, err = s.client.request(ctx).
-		SetBody(body).
+		SetBody(op.Body).
 		SetResult(&res).
 		SetError(&apiError).
 		Post("knowledge"); err != nil {
@@ -78,6 +112,7 @@ func (s *KnowledgeCreateService) Do(ctx context.Context) (res KnowledgeCreateRes
 // KnowledgeEditService edits a knowledge
 type KnowledgeEditService struct {
 	client *Client
+	extras serviceExtras
 
 	knowledgeID string
 
@@ -117,12 +152,37 @@ func (s *KnowledgeEditService) SetDescription(description string) *KnowledgeEdit
 	return s
 }
 
+// SetExtraBody sets extra fields of the KnowledgeEdit request, see WithCallBody
+func (s *KnowledgeEditService) SetExtraBody(body M) *KnowledgeEditService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the KnowledgeEdit request
+func (s *KnowledgeEditService) SetExtraHeaders(headers map[string]string) *KnowledgeEditService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the KnowledgeEdit request locally, see ValidationError
+func (s *KnowledgeEditService) Validate() error {
+	v := newValidator("KnowledgeEdit")
+	v.required("knowledge_id", s.knowledgeID)
+	if s.name != nil {
+		v.required("name", *s.name)
+	}
+	return v.err()
+}
+
 // Do edits the knowledge
-func (s *KnowledgeEditService) Do(ctx context.Context) (err error) {
-	var (
-		resp     *resty.Response
-		apiError APIErrorResponse
-	)
+func (s *KnowledgeEditService) Do(ctx context.Context, opts ...CallOption) (err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invokeNoResult(ctx, s.client, &Operation{Name: "KnowledgeEdit", Method: "PUT", Path: "knowledge/" + s.knowledgeID, Body: s.buildBody()}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *KnowledgeEditService) buildBody() M {
 	body := M{}
 	if s.name != nil {
 		body["name"] = *s.name
@@ -133,9 +193,17 @@ func (s *KnowledgeEditService) Do(ctx context.Context) (err error) {
 	if s.embeddingID != nil {
 		body["embedding_id"] = *s.embeddingID
 	}
+	return body
+}
+
+func (s *KnowledgeEditService) do(ctx context.Context, op *Operation) (err error) {
+	var (
+		resp     *resty.Response
+		apiError APIErrorResponse
+	)
 	if resp, err = s.client.request(ctx).
 		SetPathParam("knowledge_id", s.knowledgeID).
-		SetBody(body).
+		SetBody(op.Body).
 		SetError(&apiError).
 		Put("knowledge/{knowledge_id}"); err != nil {
 		return
@@ -150,6 +218,7 @@ func (s *KnowledgeEditService) Do(ctx context.Context) (err error) {
 // KnowledgeListService lists the knowledge
 type KnowledgeListService struct {
 	client *Client
+	extras serviceExtras
 
 	page *int
 	size *int
@@ -171,6 +240,8 @@ type KnowledgeItem struct {
 
 // KnowledgeListResponse is the response of the KnowledgeListService
 type KnowledgeListResponse struct {
+	RawResponse
+
 	List  []KnowledgeItem `json:"list"`
 	Total int             `json:"total"`
 }
@@ -192,8 +263,35 @@ func (s *KnowledgeListService) SetSize(size int) *KnowledgeListService {
 	return s
 }
 
+// SetExtraBody sets extra fields of the KnowledgeList request, see WithCallBody
+func (s *KnowledgeListService) SetExtraBody(body M) *KnowledgeListService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the KnowledgeList request
+func (s *KnowledgeListService) SetExtraHeaders(headers map[string]string) *KnowledgeListService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the KnowledgeList request locally, see ValidationError
+func (s *KnowledgeListService) Validate() error {
+	v := newValidator("KnowledgeList")
+	v.min("page", s.page, 1)
+	v.min("size", s.size, 1)
+	return v.err()
+}
+
 // Do lists the knowledge
-func (s *KnowledgeListService) Do(ctx context.Context) (res KnowledgeListResponse, err error) {
+func (s *KnowledgeListService) Do(ctx context.Context, opts ...CallOption) (res KnowledgeListResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "KnowledgeList", Method: "GET", Path: "knowledge"}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *KnowledgeListService) do(ctx context.Context, op *Operation) (res KnowledgeListResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -221,6 +319,7 @@ func (s *KnowledgeListService) Do(ctx context.Context) (res KnowledgeListRespons
 // KnowledgeDeleteService deletes a knowledge
 type KnowledgeDeleteService struct {
 	client *Client
+	extras serviceExtras
 
 	knowledgeID string
 }
@@ -238,8 +337,34 @@ func (s *KnowledgeDeleteService) SetKnowledgeID(knowledgeID string) *KnowledgeDe
 	return s
 }
 
+// SetExtraBody sets extra fields of the KnowledgeDelete request, see WithCallBody
+func (s *KnowledgeDeleteService) SetExtraBody(body M) *KnowledgeDeleteService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the KnowledgeDelete request
+func (s *KnowledgeDeleteService) SetExtraHeaders(headers map[string]string) *KnowledgeDeleteService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the KnowledgeDelete request locally, see ValidationError
+func (s *KnowledgeDeleteService) Validate() error {
+	v := newValidator("KnowledgeDelete")
+	v.required("knowledge_id", s.knowledgeID)
+	return v.err()
+}
+
 // Do deletes the knowledge
-func (s *KnowledgeDeleteService) Do(ctx context.Context) (err error) {
+func (s *KnowledgeDeleteService) Do(ctx context.Context, opts ...CallOption) (err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invokeNoResult(ctx, s.client, &Operation{Name: "KnowledgeDelete", Method: "DELETE", Path: "knowledge/" + s.knowledgeID}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *KnowledgeDeleteService) do(ctx context.Context, op *Operation) (err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
@@ -260,6 +385,7 @@ func (s *KnowledgeDeleteService) Do(ctx context.Context) (err error) {
 // KnowledgeCapacityService query the capacity of the knowledge
 type KnowledgeCapacityService struct {
 	client *Client
+	extras serviceExtras
 }
 
 // KnowledgeCapacityItem is an item in the knowledge capacity
@@ -270,6 +396,8 @@ type KnowledgeCapacityItem struct {
 
 // KnowledgeCapacityResponse is the response of the KnowledgeCapacityService
 type KnowledgeCapacityResponse struct {
+	RawResponse
+
 	Used  KnowledgeCapacityItem `json:"used"`
 	Total KnowledgeCapacityItem `json:"total"`
 }
@@ -279,8 +407,32 @@ func NewKnowledgeCapacityService(client *Client) *KnowledgeCapacityService {
 	return &KnowledgeCapacityService{client: client}
 }
 
+// SetExtraBody sets extra fields of the KnowledgeCapacity request, see WithCallBody
+func (s *KnowledgeCapacityService) SetExtraBody(body M) *KnowledgeCapacityService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the KnowledgeCapacity request
+func (s *KnowledgeCapacityService) SetExtraHeaders(headers map[string]string) *KnowledgeCapacityService {
+	s.extras.headers = headers
+	return s
+}
+
+// Validate checks the parameters locally, there is no parameter to check
+func (s *KnowledgeCapacityService) Validate() error {
+	return nil
+}
+
 // Do query the capacity of the knowledge
-func (s *KnowledgeCapacityService) Do(ctx context.Context) (res KnowledgeCapacityResponse, err error) {
+func (s *KnowledgeCapacityService) Do(ctx context.Context, opts ...CallOption) (res KnowledgeCapacityResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "KnowledgeCapacity", Method: "GET", Path: "knowledge/capacity"}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *KnowledgeCapacityService) do(ctx context.Context, op *Operation) (res KnowledgeCapacityResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
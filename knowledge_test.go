@@ -8,7 +8,7 @@ import (
 )
 
 func TestKnowledgeCapacity(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.KnowledgeCapacity()
@@ -19,7 +19,7 @@ func TestKnowledgeCapacity(t *testing.T) {
 }
 
 func TestKnowledgeServiceAll(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.KnowledgeCreate()
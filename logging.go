@@ -0,0 +1,238 @@
+package zhipu
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	defaultLogBodyLimit = 4096
+
+	redactedValue = "[REDACTED]"
+)
+
+// LogDetail is the detail level of the request and response logs
+type LogDetail int
+
+const (
+	// LogDetailMetadata logs the method, url, status and duration only
+	LogDetailMetadata LogDetail = iota
+	// LogDetailPayload logs the redacted headers and the truncated bodies as well
+	LogDetailPayload
+)
+
+var (
+	// logRedactedHeaders are the headers never logged in clear
+	logRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+)
+
+// WithLogger set the structured logger of the client, requests and responses are logged at debug level,
+// responses with error status at warn level, and transport errors at error level
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(opts *clientOptions) {
+		opts.logger = logger
+	}
+}
+
+// WithLogDetail set the detail level of the logs, default to LogDetailMetadata
+func WithLogDetail(detail LogDetail) ClientOption {
+	return func(opts *clientOptions) {
+		opts.logDetail = detail
+	}
+}
+
+// WithLogBodyLimit set the maximum bytes of bodies logged with LogDetailPayload, default to 4096
+func WithLogBodyLimit(limit int) ClientOption {
+	return func(opts *clientOptions) {
+		opts.logBodyLimit = limit
+	}
+}
+
+// redactHeader clones the header with secrets redacted
+func redactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, key := range logRedactedHeaders {
+		if out.Get(key) != "" {
+			out.Set(key, redactedValue)
+		}
+	}
+	return out
+}
+
+// headerAttr converts a header into a slog group
+func headerAttr(h http.Header) slog.Attr {
+	var attrs []any
+	for key, values := range h {
+		if len(values) == 1 {
+			attrs = append(attrs, slog.String(key, values[0]))
+		} else {
+			attrs = append(attrs, slog.Any(key, values))
+		}
+	}
+	return slog.Group("header", attrs...)
+}
+
+// truncate returns the body as string, truncated to limit bytes
+func truncate(body []byte, limit int) string {
+	if len(body) <= limit {
+		return string(body)
+	}
+	return string(body[:limit]) + "...(truncated)"
+}
+
+// loggingTransport is a http.RoundTripper logging requests and responses
+type loggingTransport struct {
+	next   http.RoundTripper
+	logger *slog.Logger
+	detail LogDetail
+	limit  int
+}
+
+func newLoggingTransport(next http.RoundTripper, logger *slog.Logger, detail LogDetail, limit int) *loggingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if limit <= 0 {
+		limit = defaultLogBodyLimit
+	}
+	return &loggingTransport{next: next, logger: logger, detail: detail, limit: limit}
+}
+
+// peekBody reads at most limit+1 bytes of the request body without modifying the request of the caller,
+// the body is read from GetBody if any, or the request is cloned with the prefix put back
+func (t *loggingTransport) peekBody(req *http.Request) (out *http.Request, prefix []byte, err error) {
+	out = req
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+	if req.GetBody != nil {
+		var rc io.ReadCloser
+		if rc, err = req.GetBody(); err != nil {
+			return
+		}
+		prefix, err = io.ReadAll(io.LimitReader(rc, int64(t.limit)+1))
+		_ = rc.Close()
+		return
+	}
+	if prefix, err = io.ReadAll(io.LimitReader(req.Body, int64(t.limit)+1)); err != nil {
+		return
+	}
+	out = req.Clone(req.Context())
+	out.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), req.Body), req.Body}
+	return
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *loggingTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	ctx := req.Context()
+
+	attrs := []any{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+	}
+
+	if t.detail >= LogDetailPayload {
+		var body []byte
+		if req, body, err = t.peekBody(req); err != nil {
+			return
+		}
+		t.logger.Log(ctx, slog.LevelDebug, "zhipu: request", append(attrs,
+			headerAttr(redactHeader(req.Header)),
+			slog.String("body", truncate(body, t.limit)),
+		)...)
+	} else {
+		t.logger.Log(ctx, slog.LevelDebug, "zhipu: request", attrs...)
+	}
+
+	start := time.Now()
+
+	if res, err = t.next.RoundTrip(req); err != nil {
+		t.logger.Log(ctx, slog.LevelError, "zhipu: request failed", append(attrs,
+			slog.Duration("duration", time.Since(start)),
+			slog.String("error", err.Error()),
+		)...)
+		return
+	}
+
+	attrs = append(attrs,
+		slog.Int("status", res.StatusCode),
+		slog.Duration("duration", time.Since(start)),
+	)
+	if id := res.Header.Get("X-Request-Id"); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+
+	level := slog.LevelDebug
+	if res.StatusCode >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+
+	if t.detail < LogDetailPayload {
+		t.logger.Log(ctx, level, "zhipu: response", attrs...)
+		return
+	}
+
+	// the response is logged once the body is consumed, streams included
+	attrs = append(attrs, headerAttr(redactHeader(res.Header)))
+	res.Body = &loggingBody{
+		ReadCloser: res.Body,
+		limit:      t.limit,
+		done: func(body string) {
+			t.logger.Log(ctx, level, "zhipu: response", append(attrs, slog.String("body", body))...)
+		},
+	}
+	return
+}
+
+// loggingBody captures the head of a response body, and reports it on EOF or close
+type loggingBody struct {
+	io.ReadCloser
+	limit int
+	done  func(body string)
+
+	buf  bytes.Buffer
+	once sync.Once
+}
+
+func (b *loggingBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	if n > 0 {
+		if room := b.limit + 1 - b.buf.Len(); room > 0 {
+			b.buf.Write(p[:min(n, room)])
+		}
+	}
+	if err == io.EOF {
+		b.report()
+	}
+	return
+}
+
+func (b *loggingBody) Close() error {
+	b.report()
+	return b.ReadCloser.Close()
+}
+
+func (b *loggingBody) report() {
+	b.once.Do(func() {
+		b.done(truncate(b.buf.Bytes(), b.limit))
+	})
+}
+
+// redactRequestLog redacts the secrets in the resty debug log
+func redactRequestLog(rl *resty.RequestLog) error {
+	for _, key := range logRedactedHeaders {
+		if rl.Header.Get(key) != "" {
+			rl.Header.Set(key, redactedValue)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package zhipu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeLogs(t *testing.T, buf *bytes.Buffer) (records []M) {
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record M
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return
+}
+
+func TestLogger(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("X-Request-Id", "req-1")
+		if r.URL.Path == "/knowledge/capacity" {
+			rw.WriteHeader(http.StatusBadRequest)
+			_, _ = rw.Write([]byte(`{"error":{"code":"1214","message":"bad request"}}`))
+			return
+		}
+		_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[{"embedding":[1,2,3,4,5,6,7,8]}]}`))
+	}))
+	defer s.Close()
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"), WithLogger(logger), WithLogDetail(LogDetailPayload), WithLogBodyLimit(20))
+	require.NoError(t, err)
+
+	_, err = client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.NoError(t, err)
+
+	require.NotContains(t, buf.String(), "eyJ")
+
+	records := decodeLogs(t, buf)
+	require.Len(t, records, 2)
+
+	require.Equal(t, "zhipu: request", records[0]["msg"])
+	require.Equal(t, "DEBUG", records[0]["level"])
+	require.Equal(t, http.MethodPost, records[0]["method"])
+	require.Equal(t, redactedValue, records[0]["header"].(M)["Authorization"])
+	require.Equal(t, `{"input":"hello","mo...(truncated)`, records[0]["body"])
+
+	require.Equal(t, "zhipu: response", records[1]["msg"])
+	require.Equal(t, float64(200), records[1]["status"])
+	require.Equal(t, "req-1", records[1]["request_id"])
+	require.Equal(t, `{"model":"embedding-...(truncated)`, records[1]["body"])
+
+	// metadata only
+	buf.Reset()
+	client, err = NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"), WithLogger(logger))
+	require.NoError(t, err)
+
+	_, err = client.KnowledgeCapacity().Do(context.Background())
+	require.Error(t, err)
+
+	records = decodeLogs(t, buf)
+	require.Len(t, records, 2)
+	require.NotContains(t, records[0], "header")
+	require.NotContains(t, records[0], "body")
+	require.Equal(t, "WARN", records[1]["level"])
+	require.Equal(t, float64(400), records[1]["status"])
+	require.NotContains(t, records[1], "body")
+}
+
+func TestLoggerRequestBody(t *testing.T) {
+	body := `{"model":"glm-4-flash","messages":[{"role":"user","content":"` + strings.Repeat("a", 64) + `"}]}`
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	var received []string
+	transport := newLoggingTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		received = append(received, string(b))
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}, nil
+	}), logger, LogDetailPayload, 16)
+
+	// the body is peeked from GetBody, the body of the caller is left unread
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/chat/completions", strings.NewReader(body))
+	require.NoError(t, err)
+	original := req.Body
+	res, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, original, req.Body)
+
+	// without GetBody, the request is cloned before putting the prefix back
+	req, err = http.NewRequest(http.MethodPost, "http://localhost/chat/completions", io.NopCloser(strings.NewReader(body)))
+	require.NoError(t, err)
+	original = req.Body
+	res, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, original, req.Body)
+
+	require.Equal(t, []string{body, body}, received)
+	require.Equal(t, body[:16]+"...(truncated)", decodeLogs(t, buf)[0]["body"])
+}
@@ -0,0 +1,161 @@
+package zhipu
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// NewSystemMessage creates a system message
+func NewSystemMessage(content string) ChatCompletionMessage {
+	return ChatCompletionMessage{Role: RoleSystem, Content: content}
+}
+
+// NewUserMessage creates a user message
+func NewUserMessage(content string) ChatCompletionMessage {
+	return ChatCompletionMessage{Role: RoleUser, Content: content}
+}
+
+// NewAssistantMessage creates an assistant message
+func NewAssistantMessage(content string) ChatCompletionMessage {
+	return ChatCompletionMessage{Role: RoleAssistant, Content: content}
+}
+
+// NewToolMessage creates a tool message answering the tool call with the id
+func NewToolMessage(toolCallID string, content string) ChatCompletionMessage {
+	return ChatCompletionMessage{Role: RoleTool, ToolCallID: toolCallID, Content: content}
+}
+
+// NewToolResultMessage creates a tool message answering the tool call, the result is encoded as json unless it is a string
+func NewToolResultMessage(call ChatCompletionToolCall, result any) (msg ChatCompletionMessage, err error) {
+	content, ok := result.(string)
+	if !ok {
+		var buf []byte
+		if buf, err = json.Marshal(result); err != nil {
+			return
+		}
+		content = string(buf)
+	}
+	msg = NewToolMessage(call.ID, content)
+	return
+}
+
+// ToMessage converts the choice to an assistant message for the history of the next request, tool calls included
+func (c ChatCompletionChoice) ToMessage() ChatCompletionMessage {
+	msg := c.Message
+	// choices of streams without reduction only have the delta
+	if msg.Role == "" && msg.Content == "" && len(msg.ToolCalls) == 0 {
+		msg = c.Delta
+	}
+	msg.Role = RoleAssistant
+	msg.ToolCallID = ""
+	return msg
+}
+
+// ChatCompletionMessageBuilder builds a list of messages fluently
+type ChatCompletionMessageBuilder struct {
+	messages []ChatCompletionMessageType
+}
+
+// NewChatCompletionMessageBuilder creates a new ChatCompletionMessageBuilder
+func NewChatCompletionMessageBuilder() *ChatCompletionMessageBuilder {
+	return &ChatCompletionMessageBuilder{}
+}
+
+// System adds a system message
+func (b *ChatCompletionMessageBuilder) System(content string) *ChatCompletionMessageBuilder {
+	return b.Add(NewSystemMessage(content))
+}
+
+// User adds a user message
+func (b *ChatCompletionMessageBuilder) User(content string) *ChatCompletionMessageBuilder {
+	return b.Add(NewUserMessage(content))
+}
+
+// Assistant adds an assistant message
+func (b *ChatCompletionMessageBuilder) Assistant(content string) *ChatCompletionMessageBuilder {
+	return b.Add(NewAssistantMessage(content))
+}
+
+// Tool adds a tool message answering the tool call with the id
+func (b *ChatCompletionMessageBuilder) Tool(toolCallID string, content string) *ChatCompletionMessageBuilder {
+	return b.Add(NewToolMessage(toolCallID, content))
+}
+
+// Choice adds the assistant message of a choice, tool calls included
+func (b *ChatCompletionMessageBuilder) Choice(choice ChatCompletionChoice) *ChatCompletionMessageBuilder {
+	return b.Add(choice.ToMessage())
+}
+
+// Add adds messages
+func (b *ChatCompletionMessageBuilder) Add(messages ...ChatCompletionMessageType) *ChatCompletionMessageBuilder {
+	b.messages = append(b.messages, messages...)
+	return b
+}
+
+// Messages returns the messages, ready for ChatCompletionService.AddMessage
+func (b *ChatCompletionMessageBuilder) Messages() []ChatCompletionMessageType {
+	return append([]ChatCompletionMessageType(nil), b.messages...)
+}
+
+// ChatCompletionMultiMessageBuilder builds a multimodal message mixing text and images
+type ChatCompletionMultiMessageBuilder struct {
+	role    string
+	content []ChatCompletionMultiContent
+	err     error
+}
+
+// NewChatCompletionMultiMessageBuilder creates a new ChatCompletionMultiMessageBuilder with the role, like RoleUser
+func NewChatCompletionMultiMessageBuilder(role string) *ChatCompletionMultiMessageBuilder {
+	return &ChatCompletionMultiMessageBuilder{role: role}
+}
+
+// Text adds a text part
+func (b *ChatCompletionMultiMessageBuilder) Text(text string) *ChatCompletionMultiMessageBuilder {
+	b.content = append(b.content, ChatCompletionMultiContent{Type: MultiContentTypeText, Text: text})
+	return b
+}
+
+// ImageURL adds an image part with the url
+func (b *ChatCompletionMultiMessageBuilder) ImageURL(url string) *ChatCompletionMultiMessageBuilder {
+	b.content = append(b.content, ChatCompletionMultiContent{Type: MultiContentTypeImageURL, ImageURL: &URLItem{URL: url}})
+	return b
+}
+
+// ImageBase64 adds an image part with the data, encoded as base64
+func (b *ChatCompletionMultiMessageBuilder) ImageBase64(data []byte) *ChatCompletionMultiMessageBuilder {
+	return b.ImageURL(base64.StdEncoding.EncodeToString(data))
+}
+
+// ImageFile adds an image part with the content of the local file, read errors are returned by Build
+func (b *ChatCompletionMultiMessageBuilder) ImageFile(path string) *ChatCompletionMultiMessageBuilder {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return b
+	}
+	return b.ImageBase64(data)
+}
+
+// Image adds an image part, http and https urls are used as is, others are read as local files
+func (b *ChatCompletionMultiMessageBuilder) Image(pathOrURL string) *ChatCompletionMultiMessageBuilder {
+	if strings.HasPrefix(pathOrURL, "http://") || strings.HasPrefix(pathOrURL, "https://") {
+		return b.ImageURL(pathOrURL)
+	}
+	return b.ImageFile(pathOrURL)
+}
+
+// Build returns the message, or the first error of reading files
+func (b *ChatCompletionMultiMessageBuilder) Build() (msg ChatCompletionMultiMessage, err error) {
+	if err = b.err; err != nil {
+		return
+	}
+	msg = ChatCompletionMultiMessage{
+		Role:    b.role,
+		Content: append([]ChatCompletionMultiContent(nil), b.content...),
+	}
+	return
+}
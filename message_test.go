@@ -0,0 +1,63 @@
+package zhipu
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletionMessageBuilder(t *testing.T) {
+	call := ChatCompletionToolCall{
+		ID:       "call_1",
+		Type:     ToolTypeFunction,
+		Function: &ChatCompletionToolCallFunction{Name: "get_weather", Arguments: json.RawMessage(`{"city":"北京"}`)},
+	}
+	choice := ChatCompletionChoice{
+		FinishReason: FinishReasonToolCalls,
+		Message:      ChatCompletionMessage{Role: RoleAssistant, ToolCalls: []ChatCompletionToolCall{call}},
+	}
+
+	result, err := NewToolResultMessage(call, M{"weather": "晴"})
+	require.NoError(t, err)
+
+	messages := NewChatCompletionMessageBuilder().
+		System("you are a helpful assistant").
+		User("北京天气如何").
+		Choice(choice).
+		Add(result).
+		Messages()
+
+	require.Equal(t, []ChatCompletionMessageType{
+		ChatCompletionMessage{Role: RoleSystem, Content: "you are a helpful assistant"},
+		ChatCompletionMessage{Role: RoleUser, Content: "北京天气如何"},
+		ChatCompletionMessage{Role: RoleAssistant, ToolCalls: []ChatCompletionToolCall{call}},
+		ChatCompletionMessage{Role: RoleTool, ToolCallID: "call_1", Content: `{"weather":"晴"}`},
+	}, messages)
+
+	// stream chunks only have the delta
+	require.Equal(t, NewAssistantMessage("hi"), ChatCompletionChoice{Delta: ChatCompletionMessage{Content: "hi"}}.ToMessage())
+}
+
+func TestChatCompletionMultiMessageBuilder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.png")
+	require.NoError(t, os.WriteFile(file, []byte("png"), 0644))
+
+	msg, err := NewChatCompletionMultiMessageBuilder(RoleUser).
+		Text("what is it").
+		Image("https://example.com/a.png").
+		Image(file).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, ChatCompletionMultiMessage{Role: RoleUser, Content: []ChatCompletionMultiContent{
+		{Type: MultiContentTypeText, Text: "what is it"},
+		{Type: MultiContentTypeImageURL, ImageURL: &URLItem{URL: "https://example.com/a.png"}},
+		{Type: MultiContentTypeImageURL, ImageURL: &URLItem{URL: base64.StdEncoding.EncodeToString([]byte("png"))}},
+	}}, msg)
+
+	_, err = NewChatCompletionMultiMessageBuilder(RoleUser).ImageFile(filepath.Join(t.TempDir(), "missing.png")).Build()
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,92 @@
+package zhipu
+
+import (
+	"context"
+	"time"
+)
+
+// Operation is a logical operation of a service, passed through the middleware chain
+type Operation struct {
+	// Name is the name of the operation, same as the method of Client creating the service, e.g. "ChatCompletion"
+	Name string
+	// Model is the model of the operation, empty if not applicable
+	Model string
+	// Method is the http method
+	Method string
+	// Path is the resolved http path relative to the base url, for information only
+	Path string
+	// Body is the json request body, nil if the request has no json body, middleware may modify it
+	Body M
+	// Stream is true if the response is a server-sent event stream
+	Stream bool
+	// FirstChunkAt is the time the first chunk of the stream arrived, set by the service
+	FirstChunkAt time.Time
+	// Cached is true if the response is served from the cache set by WithCache, set by the service
+	Cached bool
+}
+
+// Handler invokes an operation, the response is the value returned by the Do method of the service
+type Handler func(ctx context.Context, op *Operation) (res any, err error)
+
+// Middleware wraps a Handler with cross-cutting logic, like logging, metrics, caching or policy checks
+type Middleware func(next Handler) Handler
+
+// WithMiddleware appends middleware to the client, the first one is the outermost
+func WithMiddleware(middleware ...Middleware) ClientOption {
+	return func(opts *clientOptions) {
+		opts.middleware = append(opts.middleware, middleware...)
+	}
+}
+
+// invoke runs the operation through the middleware chain of the client
+func invoke[T any](ctx context.Context, c *Client, op *Operation, do func(ctx context.Context, op *Operation) (T, error), optFns ...CallOption) (res T, err error) {
+	ctx, opts := withCallOptions(ctx, optFns)
+	if opts != nil {
+		if opts.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+			defer cancel()
+		}
+		if op.Body != nil {
+			for k, v := range opts.body {
+				op.Body[k] = v
+			}
+		} else if opts.body != nil {
+			// requests without json body carry the extra fields in the query, or in the multipart form of FileCreate
+			opts.params = formatExtraParams(opts.body)
+		}
+	}
+
+	// the raw json body and the metadata are captured by the client, and set before the middleware sees the response
+	capture := &responseCapture{}
+	ctx = context.WithValue(ctx, responseCaptureKey{}, capture)
+
+	var h Handler = func(ctx context.Context, op *Operation) (any, error) {
+		start := time.Now()
+		res, err := do(ctx, op)
+		if s, ok := any(&res).(rawJSONSetter); ok && err == nil && capture.body != nil {
+			s.setRawJSON(capture.body)
+		}
+		if opts != nil && opts.meta != nil {
+			capture.fill(opts.meta, op, start, time.Now())
+		}
+		return res, err
+	}
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		h = c.middleware[i](h)
+	}
+
+	out, err := h(ctx, op)
+	if v, ok := out.(T); ok {
+		res = v
+	}
+	return
+}
+
+// invokeNoResult runs an operation without response through the middleware chain of the client
+func invokeNoResult(ctx context.Context, c *Client, op *Operation, do func(ctx context.Context, op *Operation) error, optFns ...CallOption) (err error) {
+	_, err = invoke(ctx, c, op, func(ctx context.Context, op *Operation) (struct{}, error) {
+		return struct{}{}, do(ctx, op)
+	}, optFns...)
+	return
+}
@@ -0,0 +1,72 @@
+package zhipu
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	var received M
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(buf, &received)
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[{"embedding":[1]}]}`))
+	}))
+	defer s.Close()
+
+	var (
+		calls []string
+		ops   []Operation
+	)
+
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, op *Operation) (any, error) {
+				calls = append(calls, name+">")
+				res, err := next(ctx, op)
+				calls = append(calls, "<"+name)
+				return res, err
+			}
+		}
+	}
+
+	policy := func(next Handler) Handler {
+		return func(ctx context.Context, op *Operation) (any, error) {
+			ops = append(ops, *op)
+			if op.Body != nil {
+				op.Body["user_id"] = "redacted"
+			}
+			if op.Name == "KnowledgeCapacity" {
+				return KnowledgeCapacityResponse{Used: KnowledgeCapacityItem{Length: 42}}, nil
+			}
+			return next(ctx, op)
+		}
+	}
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"), WithMiddleware(trace("a"), trace("b")), WithMiddleware(policy))
+	require.NoError(t, err)
+
+	res, err := client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []float64{1}, res.Data[0].Embedding)
+	require.Equal(t, []string{"a>", "b>", "<b", "<a"}, calls)
+	require.Equal(t, "Embedding", ops[0].Name)
+	require.Equal(t, "embedding-2", ops[0].Model)
+	require.Equal(t, "POST", ops[0].Method)
+	require.Equal(t, "embeddings", ops[0].Path)
+	require.Equal(t, "redacted", received["user_id"])
+
+	// short-circuit
+	capacity, err := client.KnowledgeCapacity().Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(42), capacity.Used.Length)
+	require.Equal(t, "knowledge/capacity", ops[1].Path)
+	require.Nil(t, ops[1].Body)
+}
@@ -0,0 +1,282 @@
+package zhipu
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ModelKindChat      = "chat"
+	ModelKindEmbedding = "embedding"
+	ModelKindImage     = "image"
+	ModelKindVideo     = "video"
+
+	ModalityText  = "text"
+	ModalityImage = "image"
+	ModalityVideo = "video"
+)
+
+// ModelInfo is the metadata of a model
+type ModelInfo struct {
+	// Name is the model name, like "glm-4-flash"
+	Name string
+	// Kind is the kind of the model, like ModelKindChat
+	Kind string
+	// ContextWindow is the max tokens of the input and output, 0 if unknown
+	ContextWindow int
+	// MaxOutputTokens is the max tokens of the output, 0 if unknown
+	MaxOutputTokens int
+	// InputModalities are the modalities of the input, like ModalityText and ModalityImage
+	InputModalities []string
+	// OutputModalities are the modalities of the output
+	OutputModalities []string
+	// Tools are the supported tool types, like ToolTypeFunction
+	Tools []string
+	// Thinking is true if the thinking mode is supported
+	Thinking bool
+	// BatchEndpoint is the batch endpoint, like BatchEndpointV4ChatCompletions, empty if batch is not supported
+	BatchEndpoint string
+}
+
+var (
+	toolsStandard = []string{ToolTypeFunction, ToolTypeRetrieval, ToolTypeWebSearch}
+	toolsAllTools = []string{ToolTypeFunction, ToolTypeCodeInterpreter, ToolTypeDrawingTool, ToolTypeWebBrowser}
+
+	modalitiesText   = []string{ModalityText}
+	modalitiesVision = []string{ModalityText, ModalityImage}
+	modalitiesVideo  = []string{ModalityText, ModalityImage, ModalityVideo}
+)
+
+// builtinModels is the metadata of the models known by the sdk
+var builtinModels = []ModelInfo{
+	// chat
+	{Name: "glm-4.6", Kind: ModelKindChat, ContextWindow: 200000, MaxOutputTokens: 128000, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, Thinking: true, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4.5", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 96000, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, Thinking: true, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4.5-x", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 96000, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, Thinking: true},
+	{Name: "glm-4.5-air", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 96000, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, Thinking: true, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4.5-airx", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 96000, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, Thinking: true},
+	{Name: "glm-4.5-flash", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 96000, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, Thinking: true},
+	{Name: "glm-4.5v", Kind: ModelKindChat, ContextWindow: 64000, MaxOutputTokens: 16384, InputModalities: modalitiesVideo, OutputModalities: modalitiesText, Thinking: true},
+	{Name: "glm-4-plus", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4-0520", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4-air", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4-air-250414", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 16384, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4-airx", Kind: ModelKindChat, ContextWindow: 8192, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard},
+	{Name: "glm-4-long", Kind: ModelKindChat, ContextWindow: 1000000, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4-flashx", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4-flash", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4-flash-250414", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 16384, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsStandard, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4-alltools", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 4095, InputModalities: modalitiesText, OutputModalities: modalitiesText, Tools: toolsAllTools},
+	{Name: "glm-4v", Kind: ModelKindChat, ContextWindow: 2048, MaxOutputTokens: 1024, InputModalities: modalitiesVision, OutputModalities: modalitiesText, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4v-plus", Kind: ModelKindChat, ContextWindow: 8192, MaxOutputTokens: 1024, InputModalities: modalitiesVideo, OutputModalities: modalitiesText, BatchEndpoint: BatchEndpointV4ChatCompletions},
+	{Name: "glm-4v-flash", Kind: ModelKindChat, ContextWindow: 8192, MaxOutputTokens: 1024, InputModalities: modalitiesVision, OutputModalities: modalitiesText},
+	{Name: "glm-z1-air", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 32000, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	{Name: "glm-z1-airx", Kind: ModelKindChat, ContextWindow: 32000, MaxOutputTokens: 30000, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	{Name: "glm-z1-flash", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 32000, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	{Name: "charglm-3", Kind: ModelKindChat, ContextWindow: 4096, MaxOutputTokens: 2048, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	{Name: "charglm-4", Kind: ModelKindChat, ContextWindow: 8192, MaxOutputTokens: 4096, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	{Name: "codegeex-4", Kind: ModelKindChat, ContextWindow: 128000, MaxOutputTokens: 32768, InputModalities: modalitiesText, OutputModalities: modalitiesText},
+	// embedding
+	{Name: "embedding-2", Kind: ModelKindEmbedding, ContextWindow: 512, InputModalities: modalitiesText, BatchEndpoint: BatchEndpointV4Embeddings},
+	{Name: "embedding-3", Kind: ModelKindEmbedding, ContextWindow: 8192, InputModalities: modalitiesText, BatchEndpoint: BatchEndpointV4Embeddings},
+	// image
+	{Name: "cogview-3", Kind: ModelKindImage, InputModalities: modalitiesText, OutputModalities: []string{ModalityImage}, BatchEndpoint: BatchEndpointV4ImagesGenerations},
+	{Name: "cogview-3-plus", Kind: ModelKindImage, InputModalities: modalitiesText, OutputModalities: []string{ModalityImage}, BatchEndpoint: BatchEndpointV4ImagesGenerations},
+	{Name: "cogview-3-flash", Kind: ModelKindImage, InputModalities: modalitiesText, OutputModalities: []string{ModalityImage}},
+	{Name: "cogview-4", Kind: ModelKindImage, InputModalities: modalitiesText, OutputModalities: []string{ModalityImage}},
+	{Name: "cogview-4-250304", Kind: ModelKindImage, InputModalities: modalitiesText, OutputModalities: []string{ModalityImage}},
+	// video
+	{Name: "cogvideox", Kind: ModelKindVideo, InputModalities: modalitiesVision, OutputModalities: []string{ModalityVideo}, BatchEndpoint: BatchEndpointV4VideosGenerations},
+	{Name: "cogvideox-flash", Kind: ModelKindVideo, InputModalities: modalitiesVision, OutputModalities: []string{ModalityVideo}},
+	{Name: "cogvideox-2", Kind: ModelKindVideo, InputModalities: modalitiesVision, OutputModalities: []string{ModalityVideo}},
+	{Name: "cogvideox-3", Kind: ModelKindVideo, InputModalities: modalitiesVision, OutputModalities: []string{ModalityVideo}},
+}
+
+// ModelRegistry is a registry of model metadata, used by the pre-flight validation
+type ModelRegistry struct {
+	mu     sync.RWMutex
+	models map[string]ModelInfo
+}
+
+// NewModelRegistry creates a new ModelRegistry with the models
+func NewModelRegistry(models ...ModelInfo) *ModelRegistry {
+	r := &ModelRegistry{models: map[string]ModelInfo{}}
+	return r.Register(models...)
+}
+
+// DefaultModelRegistry creates a new ModelRegistry with the built-in models, changes do not affect other registries
+func DefaultModelRegistry() *ModelRegistry {
+	return NewModelRegistry(builtinModels...)
+}
+
+// Register adds or overrides models
+func (r *ModelRegistry) Register(models ...ModelInfo) *ModelRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range models {
+		r.models[strings.ToLower(m.Name)] = m
+	}
+	return r
+}
+
+// Lookup returns the metadata of a model, fine-tuned models like "glm-4-flash:xxx" fall back to the base model
+func (r *ModelRegistry) Lookup(model string) (info ModelInfo, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	model = strings.ToLower(model)
+	if info, ok = r.models[model]; ok {
+		return
+	}
+	if base, _, found := strings.Cut(model, ":"); found {
+		info, ok = r.models[base]
+	}
+	return
+}
+
+// Models returns all models sorted by name
+func (r *ModelRegistry) Models() (out []ModelInfo) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, m := range r.models {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return
+}
+
+// ModelValidationError is the error of the pre-flight validation
+type ModelValidationError struct {
+	Model    string
+	Problems []string
+}
+
+// Error implements error
+func (e *ModelValidationError) Error() string {
+	return fmt.Sprintf("zhipu: invalid request for model %s: %s", e.Model, strings.Join(e.Problems, "; "))
+}
+
+// operationModelKinds is the model kind expected by the operations
+var operationModelKinds = map[string]string{
+	"ChatCompletion":  ModelKindChat,
+	"Embedding":       ModelKindEmbedding,
+	"ImageGeneration": ModelKindImage,
+	"VideoGeneration": ModelKindVideo,
+}
+
+// Validate checks the request of an operation against the model metadata, unknown models and operations are not checked
+func (r *ModelRegistry) Validate(op *Operation) error {
+	kind, ok := operationModelKinds[op.Name]
+	if !ok {
+		return nil
+	}
+	info, ok := r.Lookup(op.Model)
+	if !ok {
+		return nil
+	}
+
+	var problems []string
+
+	if info.Kind != kind {
+		problems = append(problems, fmt.Sprintf("model: %s model used for %s", info.Kind, kind))
+	}
+
+	if kind == ModelKindChat {
+		problems = append(problems, validateChatCompletionBody(info, op.Body)...)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ModelValidationError{Model: op.Model, Problems: problems}
+}
+
+// ValidateBatch checks a request written to a batch file
+func (r *ModelRegistry) ValidateBatch(s BatchSupport) error {
+	body, _ := s.BatchBody().(M)
+	model, _ := body["model"].(string)
+	info, ok := r.Lookup(model)
+	if !ok {
+		return nil
+	}
+	if info.BatchEndpoint != s.BatchURL() {
+		return &ModelValidationError{Model: model, Problems: []string{"batch: endpoint " + s.BatchURL() + " not supported"}}
+	}
+	return nil
+}
+
+// validateChatCompletionBody checks the tools, the content modalities, the thinking mode and the max tokens
+func validateChatCompletionBody(info ModelInfo, body M) (problems []string) {
+	if tools, ok := body["tools"].([]any); ok {
+		for i, tool := range tools {
+			if m, ok := tool.(map[string]any); ok {
+				if typ, _ := m["type"].(string); typ != "" && !slices.Contains(info.Tools, typ) {
+					problems = append(problems, fmt.Sprintf("tools[%d]: tool %s not supported", i, typ))
+				}
+			}
+		}
+	}
+
+	if messages, ok := body["messages"].([]any); ok {
+		for i, message := range messages {
+			multi, ok := message.(ChatCompletionMultiMessage)
+			if !ok {
+				continue
+			}
+			for j, content := range multi.Content {
+				modality := ModalityText
+				switch content.Type {
+				case MultiContentTypeImageURL:
+					modality = ModalityImage
+				case "video_url":
+					modality = ModalityVideo
+				}
+				if !slices.Contains(info.InputModalities, modality) {
+					problems = append(problems, fmt.Sprintf("messages[%d].content[%d]: %s input not supported", i, j, modality))
+				}
+			}
+		}
+	}
+
+	if thinking, ok := body["thinking"].(ChatCompletionThinking); ok && thinking.Type == ThinkingEnabled && !info.Thinking {
+		problems = append(problems, "thinking: thinking mode not supported")
+	}
+
+	if maxTokens, ok := body["max_tokens"].(int); ok && info.MaxOutputTokens > 0 && maxTokens > info.MaxOutputTokens {
+		problems = append(problems, fmt.Sprintf("max_tokens: %d exceeds the max output tokens %d", maxTokens, info.MaxOutputTokens))
+	}
+	return
+}
+
+// WithModelRegistry set the model registry of the client, default to DefaultModelRegistry
+func WithModelRegistry(registry *ModelRegistry) ClientOption {
+	return func(opts *clientOptions) {
+		opts.models = registry
+	}
+}
+
+// WithModelValidation enables the pre-flight validation, misuse of models is reported locally as *ModelValidationError
+func WithModelValidation() ClientOption {
+	return func(opts *clientOptions) {
+		opts.modelValidation = true
+	}
+}
+
+// Models returns the model registry of the client
+func (c *Client) Models() *ModelRegistry {
+	return c.models
+}
+
+// validateModel is the middleware of the pre-flight validation
+func (c *Client) validateModel(next Handler) Handler {
+	return func(ctx context.Context, op *Operation) (any, error) {
+		if err := c.models.Validate(op); err != nil {
+			return nil, err
+		}
+		return next(ctx, op)
+	}
+}
@@ -0,0 +1,73 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelRegistry(t *testing.T) {
+	r := DefaultModelRegistry()
+
+	info, ok := r.Lookup("GLM-4-Flash")
+	require.True(t, ok)
+	require.Equal(t, ModelKindChat, info.Kind)
+
+	info, ok = r.Lookup("glm-4-flash:my-fine-tune")
+	require.True(t, ok)
+	require.Equal(t, "glm-4-flash", info.Name)
+
+	_, ok = r.Lookup("unknown")
+	require.False(t, ok)
+
+	// overriding does not affect other registries
+	r.Register(ModelInfo{Name: "glm-4-flash", Kind: ModelKindChat, MaxOutputTokens: 100})
+	info, _ = DefaultModelRegistry().Lookup("glm-4-flash")
+	require.Equal(t, 4095, info.MaxOutputTokens)
+
+	require.NoError(t, r.ValidateBatch(NewEmbeddingService(nil).SetModel("embedding-3")))
+	require.Error(t, r.ValidateBatch(NewChatCompletionService(nil).SetModel("glm-4-alltools")))
+}
+
+func TestModelValidation(t *testing.T) {
+	client, err := NewClient(WithAPIKey("a.b"), WithBaseURL("http://127.0.0.1:1"), WithModelValidation())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	_, err = client.ChatCompletion("glm-4-flash").
+		AddMessage(ChatCompletionMultiMessage{Role: RoleUser, Content: []ChatCompletionMultiContent{
+			{Type: MultiContentTypeText, Text: "what is it"},
+			{Type: MultiContentTypeImageURL, ImageURL: &URLItem{URL: "https://example.com/a.png"}},
+		}}).
+		AddTool(ChatCompletionToolCodeInterpreter{}).
+		SetThinkingMode(ThinkingEnabled).
+		SetMaxTokens(100000).
+		Do(ctx)
+	var verr *ModelValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, []string{
+		"tools[0]: tool code_interpreter not supported",
+		"messages[0].content[1]: image input not supported",
+		"thinking: thinking mode not supported",
+		"max_tokens: 100000 exceeds the max output tokens 4095",
+	}, verr.Problems)
+
+	_, err = client.Embedding("glm-4-flash").SetInput("hello").Do(ctx)
+	require.ErrorContains(t, err, "chat model used for embedding")
+
+	// unknown models are not checked, the request reaches the transport and fails on the network
+	_, err = client.ChatCompletion("my-model").
+		AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).
+		AddTool(ChatCompletionToolCodeInterpreter{}).
+		Do(ctx)
+	require.Error(t, err)
+	require.False(t, errors.As(err, &verr))
+	var ferr *ValidationError
+	require.False(t, errors.As(err, &ferr))
+	var nerr *net.OpError
+	require.ErrorAs(t, err, &nerr)
+}
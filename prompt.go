@@ -0,0 +1,371 @@
+package zhipu
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+)
+
+var (
+	// ErrPromptNotFound is returned when a prompt template or version is not found in the library
+	ErrPromptNotFound = errors.New("zhipu: prompt template not found")
+)
+
+// promptSectionPattern matches the section lines of a prompt template, like "--- system ---" or "--- example user ---"
+var promptSectionPattern = regexp.MustCompile(`^---\s*(example\s+)?(system|user|assistant)\s*---\s*$`)
+
+// promptFuncs are the functions available in prompt templates
+var promptFuncs = template.FuncMap{
+	"join": strings.Join,
+	"trim": strings.TrimSpace,
+	"json": func(v any) (string, error) {
+		buf, err := json.Marshal(v)
+		return string(buf), err
+	},
+}
+
+// promptSection is a message of a prompt template
+type promptSection struct {
+	role    string
+	example bool
+	tmpl    *template.Template
+}
+
+// PromptTemplate is a prompt made of system, user and assistant messages, written with text/template
+//
+// Sections start with lines like "--- system ---", "--- user ---" or "--- assistant ---", a template without
+// section lines is a single user message. Sections like "--- example user ---" and "--- example assistant ---"
+// form a few-shot block, repeated for each example given to Render. Messages rendered empty are dropped,
+// so a whole message can be made conditional. Missing variables are errors.
+type PromptTemplate struct {
+	// Name is the name of the template
+	Name string
+	// Version is the version of the template, may be empty
+	Version string
+
+	sections []promptSection
+}
+
+// ParsePromptTemplate parses a prompt template
+func ParsePromptTemplate(name, version, text string) (t *PromptTemplate, err error) {
+	t = &PromptTemplate{Name: name, Version: version}
+	root := template.New(t.Tag()).Option("missingkey=error").Funcs(promptFuncs)
+
+	var (
+		current *promptSection
+		lines   []string
+	)
+	flush := func() error {
+		body := strings.Trim(strings.Join(lines, "\n"), "\n")
+		lines = nil
+		if current == nil {
+			if strings.TrimSpace(body) == "" {
+				return nil
+			}
+			current = &promptSection{role: RoleUser}
+		}
+		var err error
+		if current.tmpl, err = root.New(fmt.Sprintf("%s#%d", t.Tag(), len(t.sections))).Parse(body); err != nil {
+			return err
+		}
+		t.sections = append(t.sections, *current)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := promptSectionPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			lines = append(lines, line)
+			continue
+		}
+		if err = flush(); err != nil {
+			return
+		}
+		current = &promptSection{role: match[2], example: match[1] != ""}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	if err = flush(); err != nil {
+		return
+	}
+	if len(t.sections) == 0 {
+		err = fmt.Errorf("zhipu: prompt template %s is empty", t.Tag())
+	}
+	return
+}
+
+// MustParsePromptTemplate is like ParsePromptTemplate but panics on error
+func MustParsePromptTemplate(name, version, text string) *PromptTemplate {
+	t, err := ParsePromptTemplate(name, version, text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Tag returns "name@version", or the name if no version, useful as a usage tag to compare versions
+func (t *PromptTemplate) Tag() string {
+	if t.Version == "" {
+		return t.Name
+	}
+	return t.Name + "@" + t.Version
+}
+
+// Render renders the messages with the variables, the few-shot block is repeated for each example,
+// the variables of an example override vars
+func (t *PromptTemplate) Render(vars M, examples ...M) (messages []ChatCompletionMessage, err error) {
+	if vars == nil {
+		vars = M{}
+	}
+	for i := 0; i < len(t.sections); {
+		if !t.sections[i].example {
+			if messages, err = t.sections[i].render(messages, vars); err != nil {
+				return
+			}
+			i++
+			continue
+		}
+		j := i
+		for j < len(t.sections) && t.sections[j].example {
+			j++
+		}
+		for _, example := range examples {
+			data := M{}
+			for k, v := range vars {
+				data[k] = v
+			}
+			for k, v := range example {
+				data[k] = v
+			}
+			for _, section := range t.sections[i:j] {
+				if messages, err = section.render(messages, data); err != nil {
+					return
+				}
+			}
+		}
+		i = j
+	}
+	return
+}
+
+// render renders the section and appends the message unless empty
+func (s promptSection) render(messages []ChatCompletionMessage, data M) ([]ChatCompletionMessage, error) {
+	var sb strings.Builder
+	if err := s.tmpl.Execute(&sb, data); err != nil {
+		return messages, err
+	}
+	content := strings.TrimSpace(sb.String())
+	if content == "" {
+		return messages, nil
+	}
+	return append(messages, ChatCompletionMessage{Role: s.role, Content: content}), nil
+}
+
+// Apply renders the messages and adds them to the chat completion
+func (t *PromptTemplate) Apply(s *ChatCompletionService, vars M, examples ...M) (err error) {
+	var messages []ChatCompletionMessage
+	if messages, err = t.Render(vars, examples...); err != nil {
+		return
+	}
+	for _, message := range messages {
+		s.AddMessage(message)
+	}
+	return
+}
+
+// PromptLibrary holds prompt templates by name and version
+type PromptLibrary struct {
+	mu        sync.RWMutex
+	templates map[string]map[string]*PromptTemplate
+	latest    map[string]string
+	defaults  map[string]string
+}
+
+// NewPromptLibrary creates a new PromptLibrary
+func NewPromptLibrary() *PromptLibrary {
+	return &PromptLibrary{
+		templates: map[string]map[string]*PromptTemplate{},
+		latest:    map[string]string{},
+		defaults:  map[string]string{},
+	}
+}
+
+// LoadPromptLibrary creates a new PromptLibrary with the files matching the patterns, see Load
+func LoadPromptLibrary(fsys fs.FS, patterns ...string) (l *PromptLibrary, err error) {
+	l = NewPromptLibrary()
+	for _, pattern := range patterns {
+		if err = l.Load(fsys, pattern); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Load adds the files matching the pattern, like "prompts/*.tmpl", from a fs.FS like embed.FS or os.DirFS,
+// a file "summarize@v2.tmpl" is the version "v2" of the template "summarize"
+func (l *PromptLibrary) Load(fsys fs.FS, pattern string) (err error) {
+	var files []string
+	if files, err = fs.Glob(fsys, pattern); err != nil {
+		return
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		var buf []byte
+		if buf, err = fs.ReadFile(fsys, file); err != nil {
+			return
+		}
+		name := strings.TrimSuffix(path.Base(file), path.Ext(file))
+		var version string
+		if i := strings.LastIndex(name, "@"); i >= 0 {
+			name, version = name[:i], name[i+1:]
+		}
+		var t *PromptTemplate
+		if t, err = ParsePromptTemplate(name, version, string(buf)); err != nil {
+			err = fmt.Errorf("zhipu: failed to parse prompt template %s: %w", file, err)
+			return
+		}
+		l.Add(t)
+	}
+	return
+}
+
+// Add adds templates, replacing the same name and version, the greatest version is the latest, see ComparePromptVersions
+func (l *PromptLibrary) Add(templates ...*PromptTemplate) *PromptLibrary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, t := range templates {
+		if l.templates[t.Name] == nil {
+			l.templates[t.Name] = map[string]*PromptTemplate{}
+		}
+		l.templates[t.Name][t.Version] = t
+		if latest, ok := l.latest[t.Name]; !ok || ComparePromptVersions(t.Version, latest) >= 0 {
+			l.latest[t.Name] = t.Version
+		}
+	}
+	return l
+}
+
+// SetDefault sets the version returned by Get with an empty version, default to the latest
+func (l *PromptLibrary) SetDefault(name, version string) *PromptLibrary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaults[name] = version
+	return l
+}
+
+// Get returns the version of the template, the default version if version is empty
+func (l *PromptLibrary) Get(name, version string) (t *PromptTemplate, err error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := l.templates[name]
+	if versions == nil {
+		err = fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+		return
+	}
+	if version == "" {
+		var ok bool
+		if version, ok = l.defaults[name]; !ok {
+			version = l.latest[name]
+		}
+	}
+	if t = versions[version]; t == nil {
+		err = fmt.Errorf("%w: %s@%s", ErrPromptNotFound, name, version)
+	}
+	return
+}
+
+// Names returns the names of the templates, sorted
+func (l *PromptLibrary) Names() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	names := make([]string, 0, len(l.templates))
+	for name := range l.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions returns the versions of the template, sorted by ComparePromptVersions
+func (l *PromptLibrary) Versions(name string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := make([]string, 0, len(l.templates[name]))
+	for version := range l.templates[name] {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return ComparePromptVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// ComparePromptVersions compares two versions, returning -1, 0 or 1
+//
+// Runs of digits compare numerically, so "v2" < "v10" and "1.2.9" < "1.2.10", a leading "v" is ignored,
+// and a pre-release like "1.0.0-beta" comes before "1.0.0" as in semver.
+func ComparePromptVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	a, preA, _ := strings.Cut(a, "-")
+	b, preB, _ := strings.Cut(b, "-")
+	if c := compareNatural(a, b); c != 0 {
+		return c
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return compareNatural(preA, preB)
+}
+
+// compareNatural compares two strings, runs of digits compare numerically
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		ra, rb := chunkNatural(a), chunkNatural(b)
+		a, b = a[len(ra):], b[len(rb):]
+		na, errA := strconv.ParseUint(ra, 10, 64)
+		nb, errB := strconv.ParseUint(rb, 10, 64)
+		if errA == nil && errB == nil {
+			if na != nb {
+				if na < nb {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+		if c := strings.Compare(ra, rb); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// chunkNatural returns the leading run of digits or non-digits of s
+func chunkNatural(s string) string {
+	digit := unicode.IsDigit(rune(s[0]))
+	for i, r := range s {
+		if unicode.IsDigit(r) != digit {
+			return s[:i]
+		}
+	}
+	return s
+}
@@ -0,0 +1,125 @@
+package zhipu
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPromptTemplate = `--- system ---
+You are a {{.role}}.{{if .formal}} Answer formally.{{end}}
+
+--- example user ---
+Review: {{.review}}
+--- example assistant ---
+{{.label}}
+
+--- user ---
+Review: {{.review}}
+--- assistant ---
+{{with index . "prefill"}}{{.}}{{end}}
+`
+
+func TestPromptTemplateRender(t *testing.T) {
+	tmpl, err := ParsePromptTemplate("classify", "v1", testPromptTemplate)
+	require.NoError(t, err)
+	require.Equal(t, "classify@v1", tmpl.Tag())
+
+	messages, err := tmpl.Render(
+		M{"role": "classifier", "formal": true, "review": "great"},
+		M{"review": "bad", "label": "negative"},
+		M{"review": "nice", "label": "positive"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []ChatCompletionMessage{
+		NewSystemMessage("You are a classifier. Answer formally."),
+		NewUserMessage("Review: bad"),
+		NewAssistantMessage("negative"),
+		NewUserMessage("Review: nice"),
+		NewAssistantMessage("positive"),
+		NewUserMessage("Review: great"),
+	}, messages)
+
+	messages, err = tmpl.Render(M{"role": "classifier", "formal": false, "review": "ok", "prefill": "Label:"})
+	require.NoError(t, err)
+	require.Equal(t, []ChatCompletionMessage{
+		NewSystemMessage("You are a classifier."),
+		NewUserMessage("Review: ok"),
+		NewAssistantMessage("Label:"),
+	}, messages)
+
+	_, err = tmpl.Render(M{"role": "classifier"})
+	require.Error(t, err)
+}
+
+func TestPromptTemplateApply(t *testing.T) {
+	tmpl := MustParsePromptTemplate("hello", "", "Say hello to {{.name}}")
+	require.Equal(t, "hello", tmpl.Tag())
+
+	s := NewChatCompletionService(nil)
+	require.NoError(t, tmpl.Apply(s, M{"name": "world"}))
+	require.Equal(t, []any{NewUserMessage("Say hello to world")}, s.messages)
+
+	_, err := ParsePromptTemplate("empty", "", "\n\n")
+	require.Error(t, err)
+	_, err = ParsePromptTemplate("broken", "", "{{.name")
+	require.Error(t, err)
+}
+
+func TestPromptLibrary(t *testing.T) {
+	fsys := fstest.MapFS{
+		"prompts/summarize@v1.tmpl": {Data: []byte("Summarize: {{.text}}")},
+		"prompts/summarize@v2.tmpl": {Data: []byte("--- system ---\nBe brief.\n--- user ---\nSummarize: {{.text}}")},
+		"prompts/translate.tmpl":    {Data: []byte("Translate: {{.text}}")},
+	}
+	l, err := LoadPromptLibrary(fsys, "prompts/*.tmpl")
+	require.NoError(t, err)
+	require.Equal(t, []string{"summarize", "translate"}, l.Names())
+	require.Equal(t, []string{"v1", "v2"}, l.Versions("summarize"))
+
+	tmpl, err := l.Get("summarize", "")
+	require.NoError(t, err)
+	require.Equal(t, "v2", tmpl.Version)
+
+	l.SetDefault("summarize", "v1")
+	tmpl, err = l.Get("summarize", "")
+	require.NoError(t, err)
+	require.Equal(t, "v1", tmpl.Version)
+
+	// versions compare numerically, whatever the order they are added
+	l.Add(MustParsePromptTemplate("summarize", "v10", "Summarize: {{.text}}"), MustParsePromptTemplate("summarize", "v9", "Summarize: {{.text}}"))
+	require.Equal(t, []string{"v1", "v2", "v9", "v10"}, l.Versions("summarize"))
+	tmpl, err = NewPromptLibrary().Add(l.templates["summarize"]["v10"], l.templates["summarize"]["v2"]).Get("summarize", "")
+	require.NoError(t, err)
+	require.Equal(t, "v10", tmpl.Version)
+
+	tmpl, err = l.Get("translate", "")
+	require.NoError(t, err)
+	require.Equal(t, "", tmpl.Version)
+
+	_, err = l.Get("summarize", "v3")
+	require.True(t, errors.Is(err, ErrPromptNotFound))
+	_, err = l.Get("missing", "")
+	require.True(t, errors.Is(err, ErrPromptNotFound))
+}
+
+func TestComparePromptVersions(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"v2", "v10", -1},
+		{"v10", "v2", 1},
+		{"1.2.9", "1.2.10", -1},
+		{"v1.0.0", "1.0.0", 0},
+		{"1.0.0-beta", "1.0.0", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.10", -1},
+		{"1.0", "1.0.1", -1},
+		{"", "v1", -1},
+		{"draft", "v1", 1},
+	} {
+		require.Equal(t, c.want, ComparePromptVersions(c.a, c.b), "%s vs %s", c.a, c.b)
+	}
+}
@@ -0,0 +1,404 @@
+package zhipu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// rateLimitBackoffMin is the initial pause after a 429 response without Retry-After
+	rateLimitBackoffMin = time.Second
+	// rateLimitBackoffMax is the maximum pause after consecutive 429 responses
+	rateLimitBackoffMax = time.Minute
+)
+
+// RateLimit is the client side limit of a model
+type RateLimit struct {
+	// RequestsPerMinute is the maximum requests per minute, 0 means unlimited
+	RequestsPerMinute int
+	// TokensPerMinute is the maximum estimated tokens per minute, 0 means unlimited
+	TokensPerMinute int
+	// MaxConcurrency is the maximum in-flight requests, 0 means unlimited
+	MaxConcurrency int
+}
+
+// WithRateLimit set the client side rate limit of a model, the model "" sets the default limit of every model.
+// Each model has its own token buckets and concurrency semaphore, keyed on the "model" field of the request body.
+// The limiter pauses a model when the platform responds 429, and follows the x-ratelimit-* headers if any.
+func WithRateLimit(model string, limit RateLimit) ClientOption {
+	return func(opts *clientOptions) {
+		if opts.rateLimits == nil {
+			opts.rateLimits = map[string]RateLimit{}
+		}
+		opts.rateLimits[model] = limit
+	}
+}
+
+// tokenBucket is a token bucket refilled continuously
+type tokenBucket struct {
+	capacity float64
+	rate     float64 // tokens per second
+
+	mu      sync.Mutex
+	tokens  float64
+	updated time.Time
+	blocked time.Time
+}
+
+func newTokenBucket(perMinute int) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		tokens:   float64(perMinute),
+		updated:  time.Now(),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+	b.updated = now
+}
+
+// reserve takes n tokens if available, otherwise returns the duration to wait
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(b.blocked) {
+		return b.blocked.Sub(now)
+	}
+	b.refill(now)
+	// a request larger than the bucket waits for a full bucket
+	if n > b.capacity {
+		n = b.capacity
+	}
+	if b.tokens >= n {
+		b.tokens -= n
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// wait takes n tokens, blocking until they are available or the context is done
+func (b *tokenBucket) wait(ctx context.Context, n float64) error {
+	for {
+		d := b.reserve(n)
+		if d <= 0 {
+			return nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// adjust gives back or takes tokens after the actual usage is known
+func (b *tokenBucket) adjust(n float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.tokens = math.Min(b.capacity, b.tokens-n)
+}
+
+// sync aligns the bucket with the remaining quota reported by the platform
+func (b *tokenBucket) sync(remaining float64, reset time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	if remaining < b.tokens {
+		b.tokens = remaining
+	}
+	if remaining <= 0 && reset > 0 {
+		b.blockUntil(time.Now().Add(reset))
+	}
+}
+
+func (b *tokenBucket) blockUntil(t time.Time) {
+	if t.After(b.blocked) {
+		b.blocked = t
+	}
+}
+
+// modelLimiter limits the requests of a single model
+type modelLimiter struct {
+	requests *tokenBucket
+	tokens   *tokenBucket
+	sem      chan struct{}
+
+	mu      sync.Mutex
+	backoff time.Duration
+	paused  time.Time
+}
+
+func newModelLimiter(limit RateLimit) *modelLimiter {
+	l := &modelLimiter{}
+	if limit.RequestsPerMinute > 0 {
+		l.requests = newTokenBucket(limit.RequestsPerMinute)
+	}
+	if limit.TokensPerMinute > 0 {
+		l.tokens = newTokenBucket(limit.TokensPerMinute)
+	}
+	if limit.MaxConcurrency > 0 {
+		l.sem = make(chan struct{}, limit.MaxConcurrency)
+	}
+	return l
+}
+
+// acquire waits for the concurrency slot and the buckets, the returned function releases the slot
+func (l *modelLimiter) acquire(ctx context.Context, tokens float64) (release func(), err error) {
+	release = func() {}
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-l.sem }) }
+	}
+	if err = l.waitPause(ctx); err != nil {
+		release()
+		return
+	}
+	if l.requests != nil {
+		if err = l.requests.wait(ctx, 1); err != nil {
+			release()
+			return
+		}
+	}
+	if l.tokens != nil {
+		if err = l.tokens.wait(ctx, tokens); err != nil {
+			release()
+			return
+		}
+	}
+	return
+}
+
+// waitPause waits until the pause after a 429 response is over
+func (l *modelLimiter) waitPause(ctx context.Context) error {
+	l.mu.Lock()
+	d := time.Until(l.paused)
+	l.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// observe adapts the limiter to the response
+func (l *modelLimiter) observe(res *http.Response) {
+	if res.StatusCode == http.StatusTooManyRequests {
+		l.mu.Lock()
+		if l.backoff == 0 {
+			l.backoff = rateLimitBackoffMin
+		} else {
+			l.backoff = min(l.backoff*2, rateLimitBackoffMax)
+		}
+		pause := l.backoff
+		if d, ok := parseRateLimitDuration(res.Header.Get("Retry-After")); ok {
+			pause = d
+		}
+		if until := time.Now().Add(pause); until.After(l.paused) {
+			l.paused = until
+		}
+		l.mu.Unlock()
+		return
+	}
+
+	l.mu.Lock()
+	l.backoff = 0
+	l.mu.Unlock()
+
+	if l.requests != nil {
+		if remaining, err := strconv.ParseFloat(res.Header.Get("X-Ratelimit-Remaining-Requests"), 64); err == nil {
+			reset, _ := parseRateLimitDuration(res.Header.Get("X-Ratelimit-Reset-Requests"))
+			l.requests.sync(remaining, reset)
+		}
+	}
+	if l.tokens != nil {
+		if remaining, err := strconv.ParseFloat(res.Header.Get("X-Ratelimit-Remaining-Tokens"), 64); err == nil {
+			reset, _ := parseRateLimitDuration(res.Header.Get("X-Ratelimit-Reset-Tokens"))
+			l.tokens.sync(remaining, reset)
+		}
+	}
+}
+
+// parseRateLimitDuration parses durations like "2", "1.5" (seconds) or "1m30s"
+func parseRateLimitDuration(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, true
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// estimateTokens roughly estimates the tokens of a request body, plus the max_tokens of the completion
+func estimateTokens(body []byte) float64 {
+	var req struct {
+		MaxTokens int `json:"max_tokens"`
+	}
+	_ = json.Unmarshal(body, &req)
+	return float64(utf8.RuneCount(body)/2 + req.MaxTokens)
+}
+
+// rateLimitTransport is a http.RoundTripper limiting requests per model
+type rateLimitTransport struct {
+	next   http.RoundTripper
+	limits map[string]RateLimit
+
+	mu       sync.Mutex
+	limiters map[string]*modelLimiter
+}
+
+func newRateLimitTransport(next http.RoundTripper, limits map[string]RateLimit) *rateLimitTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &rateLimitTransport{next: next, limits: limits, limiters: map[string]*modelLimiter{}}
+}
+
+// limiter returns the limiter of the model, nil if the model is not limited
+func (t *rateLimitTransport) limiter(model string) *modelLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if l, ok := t.limiters[model]; ok {
+		return l
+	}
+	limit, ok := t.limits[model]
+	if !ok {
+		if limit, ok = t.limits[""]; !ok {
+			return nil
+		}
+	}
+	l := newModelLimiter(limit)
+	t.limiters[model] = l
+	return l
+}
+
+// readRequestBody reads the request body without modifying the request of the caller, the body is read from
+// GetBody if any, or the request is cloned with the body buffered
+func readRequestBody(req *http.Request) (out *http.Request, body []byte, err error) {
+	out = req
+	if req.GetBody != nil {
+		var rc io.ReadCloser
+		if rc, err = req.GetBody(); err != nil {
+			return
+		}
+		body, err = io.ReadAll(rc)
+		_ = rc.Close()
+		return
+	}
+	if body, err = io.ReadAll(req.Body); err != nil {
+		return
+	}
+	_ = req.Body.Close()
+	out = req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody && strings.Contains(req.Header.Get("Content-Type"), "json") {
+		if req, body, err = readRequestBody(req); err != nil {
+			return
+		}
+	}
+
+	var model struct {
+		Model string `json:"model"`
+	}
+	_ = json.Unmarshal(body, &model)
+
+	l := t.limiter(model.Model)
+	if l == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	estimated := estimateTokens(body)
+
+	release, err := l.acquire(req.Context(), estimated)
+	if err != nil {
+		return
+	}
+
+	if res, err = t.next.RoundTrip(req); err != nil {
+		release()
+		return
+	}
+
+	l.observe(res)
+
+	// correct the token bucket with the actual usage of non-stream responses
+	if l.tokens != nil && res.StatusCode == http.StatusOK && strings.Contains(res.Header.Get("Content-Type"), "json") {
+		var buf []byte
+		buf, err = io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		release()
+		if err != nil {
+			return
+		}
+		res.Body = io.NopCloser(bytes.NewReader(buf))
+		var usage struct {
+			Usage struct {
+				TotalTokens int64 `json:"total_tokens"`
+			} `json:"usage"`
+		}
+		if json.Unmarshal(buf, &usage) == nil && usage.Usage.TotalTokens > 0 {
+			l.tokens.adjust(float64(usage.Usage.TotalTokens) - estimated)
+		}
+		return
+	}
+
+	// streams hold the concurrency slot until the body is closed
+	res.Body = &releaseBody{ReadCloser: res.Body, release: release}
+	return
+}
+
+// releaseBody calls release when the body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
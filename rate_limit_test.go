@@ -0,0 +1,155 @@
+package zhipu
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(60)
+	b.rate = 20
+
+	require.Zero(t, b.reserve(60))
+	d := b.reserve(1)
+	require.Greater(t, d, time.Duration(0))
+	require.LessOrEqual(t, d, 50*time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, b.wait(context.Background(), 2))
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, b.wait(ctx, 60), context.Canceled)
+}
+
+func TestRateLimit(t *testing.T) {
+	var (
+		inflight, peak int64
+		calls          int64
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&inflight, 1)
+		defer atomic.AddInt64(&inflight, -1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if atomic.AddInt64(&calls, 1) == 1 {
+			rw.Header().Set("Retry-After", "0.2")
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			_, _ = rw.Write([]byte(`{"error":{"code":"1302","message":"rate limited"}}`))
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[],"usage":{"total_tokens":1}}`))
+	}))
+	defer s.Close()
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"), WithRateLimit("embedding-2", RateLimit{
+		RequestsPerMinute: 600,
+		TokensPerMinute:   100000,
+		MaxConcurrency:    2,
+	}))
+	require.NoError(t, err)
+
+	// the first request is rejected and pauses the model
+	start := time.Now()
+	_, err = client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.Equal(t, "1302", GetAPIErrorCode(err))
+	_, err = client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	// the concurrency is capped
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Embedding("embedding-2").SetInput("hello").Do(context.Background())
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int64(2), atomic.LoadInt64(&peak))
+
+	// other models are not limited
+	atomic.StoreInt64(&peak, 0)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Embedding("embedding-3").SetInput("hello").Do(context.Background())
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.Greater(t, atomic.LoadInt64(&peak), int64(2))
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestRateLimitRequestBody(t *testing.T) {
+	const body = `{"model":"glm-4-flash","messages":[]}`
+
+	var received []string
+	transport := newRateLimitTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		buf, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		received = append(received, string(buf))
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}, nil
+	}), map[string]RateLimit{"": {RequestsPerMinute: 100}})
+
+	// the body is read from GetBody, the body of the caller is left unread
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/chat/completions", bytes.NewReader([]byte(body)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	original := req.Body
+	res, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, original, req.Body)
+
+	// without GetBody, the request is cloned before replacing the body
+	req, err = http.NewRequest(http.MethodPost, "http://localhost/chat/completions", io.NopCloser(bytes.NewReader([]byte(body))))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	require.Nil(t, req.GetBody)
+	original = req.Body
+	res, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	require.Equal(t, original, req.Body)
+	require.Nil(t, req.GetBody)
+
+	require.Equal(t, []string{body, body}, received)
+}
+
+func TestParseRateLimitDuration(t *testing.T) {
+	d, ok := parseRateLimitDuration("1.5")
+	require.True(t, ok)
+	require.Equal(t, 1500*time.Millisecond, d)
+	d, ok = parseRateLimitDuration("1m30s")
+	require.True(t, ok)
+	require.Equal(t, 90*time.Second, d)
+	_, ok = parseRateLimitDuration("")
+	require.False(t, ok)
+}
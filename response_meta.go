@@ -0,0 +1,88 @@
+package zhipu
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// ResponseMeta is the metadata of the http response of a call
+type ResponseMeta struct {
+	// StatusCode is the http status code, 0 if no response is received or served from the cache
+	StatusCode int
+	// Header is the http response header
+	Header http.Header
+	// RequestID is the X-Request-Id header
+	RequestID string
+	// Latency is the duration of the call, until the body or the stream is fully read
+	Latency time.Duration
+	// TimeToFirstChunk is the duration until the first chunk of a stream, 0 if not a stream
+	TimeToFirstChunk time.Duration
+	// Cached is true if the response is served from the cache set by WithCache
+	Cached bool
+}
+
+// WithResponseMeta fills the metadata of the http response of the call into meta, errors included
+func WithResponseMeta(meta *ResponseMeta) CallOption {
+	return func(opts *callOptions) {
+		opts.meta = meta
+	}
+}
+
+type responseCaptureKey struct{}
+
+// responseCapture holds the last http response of an operation
+type responseCapture struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// fill fills the metadata of the operation
+func (c *responseCapture) fill(meta *ResponseMeta, op *Operation, start, end time.Time) {
+	*meta = ResponseMeta{
+		StatusCode: c.status,
+		Header:     c.header,
+		Latency:    end.Sub(start),
+		Cached:     op.Cached,
+	}
+	if c.header != nil {
+		meta.RequestID = c.header.Get("X-Request-Id")
+	}
+	if !op.FirstChunkAt.IsZero() {
+		meta.TimeToFirstChunk = op.FirstChunkAt.Sub(start)
+	}
+}
+
+// captureResponse is a resty response hook saving the body for the operation, streams are not parsed by resty
+func captureResponse(_ *resty.Client, resp *resty.Response) error {
+	if capture, ok := resp.Request.Context().Value(responseCaptureKey{}).(*responseCapture); ok && !resp.IsError() {
+		capture.body = resp.Body()
+	}
+	return nil
+}
+
+// captureTransport is a http.RoundTripper saving the status and the header for the operation, streams included
+type captureTransport struct {
+	next http.RoundTripper
+}
+
+func newCaptureTransport(next http.RoundTripper) *captureTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &captureTransport{next: next}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *captureTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	if res, err = t.next.RoundTrip(req); err != nil {
+		return
+	}
+	if capture, ok := req.Context().Value(responseCaptureKey{}).(*responseCapture); ok {
+		capture.status = res.StatusCode
+		capture.header = res.Header
+	}
+	return
+}
@@ -0,0 +1,63 @@
+package zhipu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseMeta(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("X-Request-Id", "req-1")
+		rw.Header().Set("X-Ratelimit-Remaining-Requests", "9")
+		switch r.URL.Path {
+		case "/chat/completions":
+			rw.Header().Set("Content-Type", "text/event-stream")
+			_, _ = rw.Write([]byte("data: {\"id\":\"1\",\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"hi\"}}]}\n\n"))
+			rw.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+			_, _ = rw.Write([]byte("data: [DONE]\n\n"))
+		case "/batches/b1":
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(http.StatusNotFound)
+			_, _ = rw.Write([]byte(`{"error":{"code":"1000","message":"not found"}}`))
+		default:
+			rw.Header().Set("Content-Type", "application/json")
+			_, _ = rw.Write([]byte(`{"model":"embedding-2","data":[]}`))
+		}
+	}))
+	defer s.Close()
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"))
+	require.NoError(t, err)
+
+	var meta ResponseMeta
+	_, err = client.Embedding("embedding-2").SetInput("hello").Do(context.Background(), WithResponseMeta(&meta))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, meta.StatusCode)
+	require.Equal(t, "req-1", meta.RequestID)
+	require.Equal(t, "9", meta.Header.Get("X-Ratelimit-Remaining-Requests"))
+	require.NotZero(t, meta.Latency)
+	require.Zero(t, meta.TimeToFirstChunk)
+
+	_, err = client.ChatCompletion("glm-4-flash").
+		AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).
+		SetStreamHandler(func(chunk ChatCompletionResponse) error { return nil }).
+		Do(context.Background(), WithResponseMeta(&meta))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, meta.StatusCode)
+	require.NotZero(t, meta.TimeToFirstChunk)
+	// the first chunk may be read late under load, so only the pause of the server is certain
+	require.Greater(t, meta.Latency, meta.TimeToFirstChunk)
+	require.GreaterOrEqual(t, meta.Latency, 50*time.Millisecond)
+
+	// errors included
+	_, err = client.BatchGet("b1").Do(context.Background(), WithResponseMeta(&meta))
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, meta.StatusCode)
+	require.Equal(t, "req-1", meta.RequestID)
+}
@@ -0,0 +1,155 @@
+package zhipu
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultTokenTTL = time.Hour * 24 * 7
+)
+
+// TokenProvider provides the value of the Authorization header
+type TokenProvider interface {
+	// Token returns the value of the Authorization header
+	Token(ctx context.Context) (string, error)
+}
+
+// WithTokenProvider set the token provider of the client, the api key is not required if set
+func WithTokenProvider(provider TokenProvider) ClientOption {
+	return func(opts *clientOptions) {
+		opts.tokenProvider = provider
+	}
+}
+
+// WithTokenTTL set the ttl of the jwt tokens signed from the api key, default to 7 days
+func WithTokenTTL(ttl time.Duration) ClientOption {
+	return func(opts *clientOptions) {
+		opts.tokenTTL = ttl
+	}
+}
+
+// WithPlainAPIKey sends the api key as a bearer token, instead of a signed jwt token
+func WithPlainAPIKey() ClientOption {
+	return func(opts *clientOptions) {
+		opts.plainAPIKey = true
+	}
+}
+
+// JWTTokenProvider signs jwt tokens from the api key, tokens are cached and refreshed ahead of expiry
+type JWTTokenProvider struct {
+	keyID     string
+	keySecret []byte
+	ttl       time.Duration
+
+	mu     sync.Mutex
+	token  string
+	expire time.Time
+}
+
+var (
+	_ TokenProvider = &JWTTokenProvider{}
+)
+
+// NewJWTTokenProvider creates a new JWTTokenProvider, ttl defaults to 7 days if not positive
+func NewJWTTokenProvider(apiKey string, ttl time.Duration) (*JWTTokenProvider, error) {
+	keyComponents := strings.SplitN(apiKey, ".", 2)
+	if len(keyComponents) != 2 {
+		return nil, ErrAPIKeyMalformed
+	}
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+	return &JWTTokenProvider{
+		keyID:     keyComponents[0],
+		keySecret: []byte(keyComponents[1]),
+		ttl:       ttl,
+	}, nil
+}
+
+// KeyID returns the id part of the api key
+func (p *JWTTokenProvider) KeyID() string {
+	return p.keyID
+}
+
+// Token implements TokenProvider, the cached token is refreshed when less than a tenth of the ttl is left
+func (p *JWTTokenProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.token != "" && now.Add(p.ttl/10).Before(p.expire) {
+		return p.token, nil
+	}
+
+	expire := now.Add(p.ttl)
+
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"api_key":   p.keyID,
+		"timestamp": now.UnixMilli(),
+		"exp":       expire.UnixMilli(),
+	})
+	t.Header = map[string]interface{}{
+		"alg":       "HS256",
+		"sign_type": "SIGN",
+	}
+
+	token, err := t.SignedString(p.keySecret)
+	if err != nil {
+		return "", fmt.Errorf("zhipu: failed to sign jwt token: %w", err)
+	}
+	p.token, p.expire = token, expire
+	return token, nil
+}
+
+// APIKeyTokenProvider sends the api key as a bearer token
+type APIKeyTokenProvider struct {
+	apiKey string
+}
+
+var (
+	_ TokenProvider = &APIKeyTokenProvider{}
+)
+
+// NewAPIKeyTokenProvider creates a new APIKeyTokenProvider
+func NewAPIKeyTokenProvider(apiKey string) *APIKeyTokenProvider {
+	return &APIKeyTokenProvider{apiKey: apiKey}
+}
+
+// Token implements TokenProvider
+func (p *APIKeyTokenProvider) Token(ctx context.Context) (string, error) {
+	return "Bearer " + p.apiKey, nil
+}
+
+// authTransport is a http.RoundTripper setting the Authorization header
+type authTransport struct {
+	next     http.RoundTripper
+	provider TokenProvider
+}
+
+func newAuthTransport(next http.RoundTripper, provider TokenProvider) *authTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &authTransport{next: next, provider: provider}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.provider.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", token)
+	return t.next.RoundTrip(req)
+}
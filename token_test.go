@@ -0,0 +1,69 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTTokenProvider(t *testing.T) {
+	_, err := NewJWTTokenProvider("malformed", 0)
+	require.ErrorIs(t, err, ErrAPIKeyMalformed)
+
+	p, err := NewJWTTokenProvider("id.secret", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, "id", p.KeyID())
+
+	token1, err := p.Token(context.Background())
+	require.NoError(t, err)
+	token2, err := p.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, token1, token2)
+
+	parsed, err := jwt.Parse(token1, func(t *jwt.Token) (any, error) { return []byte("secret"), nil }, jwt.WithoutClaimsValidation())
+	require.NoError(t, err)
+	require.Equal(t, "SIGN", parsed.Header["sign_type"])
+	claims := parsed.Claims.(jwt.MapClaims)
+	require.Equal(t, "id", claims["api_key"])
+	require.InDelta(t, time.Minute.Milliseconds(), claims["exp"].(float64)-claims["timestamp"].(float64), 1)
+
+	// refreshed ahead of expiry
+	p.expire = time.Now().Add(time.Second)
+	_, err = p.Token(context.Background())
+	require.NoError(t, err)
+	require.Greater(t, time.Until(p.expire), 30*time.Second)
+}
+
+type failingTokenProvider struct{}
+
+func (failingTokenProvider) Token(ctx context.Context) (string, error) {
+	return "", errors.New("no token")
+}
+
+func TestTokenProvider(t *testing.T) {
+	var auth string
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{}`))
+	}))
+	defer s.Close()
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("id.secret"), WithPlainAPIKey())
+	require.NoError(t, err)
+	_, err = client.KnowledgeCapacity().Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Bearer id.secret", auth)
+
+	t.Setenv(envAPIKey, "")
+	client, err = NewClient(WithBaseURL(s.URL), WithTokenProvider(failingTokenProvider{}))
+	require.NoError(t, err)
+	_, err = client.KnowledgeCapacity().Do(context.Background())
+	require.ErrorContains(t, err, "no token")
+}
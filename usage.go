@@ -0,0 +1,350 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrBudgetExceeded is returned before calling the api when a budget of the UsageTracker is exceeded
+	ErrBudgetExceeded = errors.New("zhipu: budget exceeded")
+)
+
+// ModelPrice is the price of a model, per million tokens
+type ModelPrice struct {
+	// Input is the price of prompt tokens
+	Input float64
+	// Output is the price of completion tokens
+	Output float64
+	// Training is the price of fine-tuning trained tokens
+	Training float64
+}
+
+// UsageBudget is a budget limit, zero fields are unlimited
+type UsageBudget struct {
+	MaxTokens int64
+	MaxCost   float64
+}
+
+// UsageStats is the accumulated usage
+type UsageStats struct {
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	TrainedTokens    int64   `json:"trained_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (s *UsageStats) add(o UsageStats) {
+	s.Requests += o.Requests
+	s.PromptTokens += o.PromptTokens
+	s.CompletionTokens += o.CompletionTokens
+	s.TotalTokens += o.TotalTokens
+	s.TrainedTokens += o.TrainedTokens
+	s.Cost += o.Cost
+}
+
+// exceeds returns true if the stats reached the budget
+func (s UsageStats) exceeds(b UsageBudget) bool {
+	return (b.MaxTokens > 0 && s.TotalTokens+s.TrainedTokens >= b.MaxTokens) ||
+		(b.MaxCost > 0 && s.Cost >= b.MaxCost)
+}
+
+// UsageSnapshot is a point-in-time copy of the UsageTracker, ready for json export
+type UsageSnapshot struct {
+	At     time.Time             `json:"at"`
+	Total  UsageStats            `json:"total"`
+	Models map[string]UsageStats `json:"models"`
+	Users  map[string]UsageStats `json:"users"`
+	Tags   map[string]UsageStats `json:"tags"`
+}
+
+// UsageRecord is a usage to record
+type UsageRecord struct {
+	Model  string
+	UserID string
+	Tags   []string
+	Usage  ChatCompletionUsage
+	// TrainedTokens is the fine-tuning trained tokens
+	TrainedTokens int64
+}
+
+// UsageTracker accumulates token usage and cost per model, per user id and per tag
+type UsageTracker struct {
+	mu sync.Mutex
+
+	prices map[string]ModelPrice
+
+	budget      *UsageBudget
+	userBudgets map[string]UsageBudget
+	tagBudgets  map[string]UsageBudget
+
+	total  UsageStats
+	models map[string]*UsageStats
+	users  map[string]*UsageStats
+	tags   map[string]*UsageStats
+
+	// fineTuneJobs are the ids of the fine-tuning jobs already recorded
+	fineTuneJobs map[string]bool
+}
+
+// NewUsageTracker creates a new UsageTracker
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		prices:      map[string]ModelPrice{},
+		userBudgets: map[string]UsageBudget{},
+		tagBudgets:  map[string]UsageBudget{},
+		models:      map[string]*UsageStats{},
+		users:       map[string]*UsageStats{},
+		tags:        map[string]*UsageStats{},
+
+		fineTuneJobs: map[string]bool{},
+	}
+}
+
+// SetPrice sets the price of a model, model "" is the default price of models not in the table
+func (t *UsageTracker) SetPrice(model string, price ModelPrice) *UsageTracker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prices[model] = price
+	return t
+}
+
+// SetPrices sets the price table
+func (t *UsageTracker) SetPrices(prices map[string]ModelPrice) *UsageTracker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for model, price := range prices {
+		t.prices[model] = price
+	}
+	return t
+}
+
+// SetBudget sets the budget of all usage
+func (t *UsageTracker) SetBudget(budget UsageBudget) *UsageTracker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budget = &budget
+	return t
+}
+
+// SetUserBudget sets the budget of a user id
+func (t *UsageTracker) SetUserBudget(userID string, budget UsageBudget) *UsageTracker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.userBudgets[userID] = budget
+	return t
+}
+
+// SetTagBudget sets the budget of a tag
+func (t *UsageTracker) SetTagBudget(tag string, budget UsageBudget) *UsageTracker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tagBudgets[tag] = budget
+	return t
+}
+
+// cost computes the cost of a record, the caller holds the lock
+func (t *UsageTracker) cost(r UsageRecord) float64 {
+	price, ok := t.prices[r.Model]
+	if !ok {
+		price = t.prices[""]
+	}
+	return (float64(r.Usage.PromptTokens)*price.Input +
+		float64(r.Usage.CompletionTokens)*price.Output +
+		float64(r.TrainedTokens)*price.Training) / 1_000_000
+}
+
+// Record records a usage
+func (t *UsageTracker) Record(r UsageRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := UsageStats{
+		Requests:         1,
+		PromptTokens:     r.Usage.PromptTokens,
+		CompletionTokens: r.Usage.CompletionTokens,
+		TotalTokens:      r.Usage.TotalTokens,
+		TrainedTokens:    r.TrainedTokens,
+		Cost:             t.cost(r),
+	}
+
+	add := func(m map[string]*UsageStats, key string) {
+		s, ok := m[key]
+		if !ok {
+			s = &UsageStats{}
+			m[key] = s
+		}
+		s.add(stats)
+	}
+
+	t.total.add(stats)
+	add(t.models, r.Model)
+	if r.UserID != "" {
+		add(t.users, r.UserID)
+	}
+	for _, tag := range r.Tags {
+		add(t.tags, tag)
+	}
+}
+
+// RecordResponse records the usage of a response, like ChatCompletionResponse or EmbeddingResponse, or a successful BatchResult
+// of them, other responses are ignored
+func (t *UsageTracker) RecordResponse(res any, userID string, tags ...string) {
+	r := UsageRecord{UserID: userID, Tags: tags}
+	switch v := res.(type) {
+	case BatchResult[ChatCompletionResponse]:
+		if !v.Response.succeeded() {
+			return
+		}
+		r.Model, r.Usage = v.Response.Body.Model, v.Response.Body.Usage
+	case *BatchResult[ChatCompletionResponse]:
+		if !v.Response.succeeded() {
+			return
+		}
+		r.Model, r.Usage = v.Response.Body.Model, v.Response.Body.Usage
+	case BatchResult[EmbeddingResponse]:
+		if !v.Response.succeeded() {
+			return
+		}
+		r.Model, r.Usage = v.Response.Body.Model, v.Response.Body.Usage
+	case *BatchResult[EmbeddingResponse]:
+		if !v.Response.succeeded() {
+			return
+		}
+		r.Model, r.Usage = v.Response.Body.Model, v.Response.Body.Usage
+	case ChatCompletionResponse:
+		r.Model, r.Usage = v.Model, v.Usage
+	case *ChatCompletionResponse:
+		r.Model, r.Usage = v.Model, v.Usage
+	case EmbeddingResponse:
+		r.Model, r.Usage = v.Model, v.Usage
+	case *EmbeddingResponse:
+		r.Model, r.Usage = v.Model, v.Usage
+	default:
+		return
+	}
+	t.Record(r)
+}
+
+// RecordFineTune records the trained tokens of a fine-tuning job, see RecordFineTuneJob to record a job once
+func (t *UsageTracker) RecordFineTune(model string, trainedTokens int64, tags ...string) {
+	t.Record(UsageRecord{Model: model, Tags: tags, TrainedTokens: trainedTokens})
+}
+
+// RecordFineTuneJob records the trained tokens of a succeeded fine-tuning job once per job id, jobs recorded
+// before a Reset included, FineTuneWatcher records the jobs it watches
+func (t *UsageTracker) RecordFineTuneJob(job FineTuneItem, tags ...string) {
+	if job.Status != FineTuneStatusSucceeded {
+		return
+	}
+	t.mu.Lock()
+	recorded := t.fineTuneJobs[job.ID]
+	t.fineTuneJobs[job.ID] = true
+	t.mu.Unlock()
+	if recorded {
+		return
+	}
+	t.RecordFineTune(job.Model, job.TrainedTokens, tags...)
+}
+
+// Check returns ErrBudgetExceeded if the budget of all usage, the user id or any tag is exceeded
+func (t *UsageTracker) Check(userID string, tags ...string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.budget != nil && t.total.exceeds(*t.budget) {
+		return ErrBudgetExceeded
+	}
+	if userID != "" {
+		if b, ok := t.userBudgets[userID]; ok && t.users[userID] != nil && t.users[userID].exceeds(b) {
+			return fmt.Errorf("%w: user %s", ErrBudgetExceeded, userID)
+		}
+	}
+	for _, tag := range tags {
+		if b, ok := t.tagBudgets[tag]; ok && t.tags[tag] != nil && t.tags[tag].exceeds(b) {
+			return fmt.Errorf("%w: tag %s", ErrBudgetExceeded, tag)
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a copy of the accumulated usage
+func (t *UsageTracker) Snapshot() (out UsageSnapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	copyStats := func(m map[string]*UsageStats) map[string]UsageStats {
+		out := make(map[string]UsageStats, len(m))
+		for k, v := range m {
+			out[k] = *v
+		}
+		return out
+	}
+
+	return UsageSnapshot{
+		At:     time.Now(),
+		Total:  t.total,
+		Models: copyStats(t.models),
+		Users:  copyStats(t.users),
+		Tags:   copyStats(t.tags),
+	}
+}
+
+// Reset clears the accumulated usage, prices and budgets are kept
+func (t *UsageTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.total = UsageStats{}
+	t.models = map[string]*UsageStats{}
+	t.users = map[string]*UsageStats{}
+	t.tags = map[string]*UsageStats{}
+}
+
+// middleware checks the budgets before the call and records the usage after, cached responses are free
+func (t *UsageTracker) middleware(next Handler) Handler {
+	return func(ctx context.Context, op *Operation) (res any, err error) {
+		userID, _ := op.Body["user_id"].(string)
+		tags := UsageTags(ctx)
+
+		if err = t.Check(userID, tags...); err != nil {
+			return
+		}
+		if res, err = next(ctx, op); err != nil || op.Cached {
+			return
+		}
+		t.RecordResponse(res, userID, tags...)
+		return
+	}
+}
+
+// WithUsageTracker attaches a UsageTracker to the client, usage of chat completions and embeddings is recorded,
+// as well as the trained tokens of the jobs succeeded in FineTuneWatcher, and calls are aborted with ErrBudgetExceeded
+// once a budget is exceeded
+func WithUsageTracker(tracker *UsageTracker) ClientOption {
+	return func(opts *clientOptions) {
+		opts.usage = tracker
+	}
+}
+
+type usageTagsKey struct{}
+
+// WithUsageTags returns a context tagging the usage of the calls
+func WithUsageTags(ctx context.Context, tags ...string) context.Context {
+	return context.WithValue(ctx, usageTagsKey{}, append(UsageTags(ctx), tags...))
+}
+
+// UsageTags returns the usage tags of the context
+func UsageTags(ctx context.Context) []string {
+	tags, _ := ctx.Value(usageTagsKey{}).([]string)
+	return tags[:len(tags):len(tags)]
+}
+
+// Usage returns the UsageTracker set by WithUsageTracker, nil if not set
+func (c *Client) Usage() *UsageTracker {
+	return c.usage
+}
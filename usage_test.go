@@ -0,0 +1,117 @@
+package zhipu
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageTracker(t *testing.T) {
+	var hits int64
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write([]byte(`{"id":"1","model":"glm-4-flash","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":100,"completion_tokens":200,"total_tokens":300}}`))
+	}))
+	defer s.Close()
+
+	tracker := NewUsageTracker().
+		SetPrice("glm-4-flash", ModelPrice{Input: 1, Output: 2}).
+		SetPrice("", ModelPrice{Training: 10}).
+		SetUserBudget("u1", UsageBudget{MaxTokens: 500})
+
+	client, err := NewClient(WithBaseURL(s.URL), WithAPIKey("a.b"), WithUsageTracker(tracker))
+	require.NoError(t, err)
+	require.Equal(t, tracker, client.Usage())
+
+	ctx := WithUsageTags(context.Background(), "eval")
+
+	for i := 0; i < 2; i++ {
+		_, err = client.ChatCompletion("glm-4-flash").SetUserID("u1").AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).Do(ctx)
+		require.NoError(t, err)
+	}
+
+	// the budget of u1 is exceeded, other users are not affected
+	_, err = client.ChatCompletion("glm-4-flash").SetUserID("u1").AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).Do(ctx)
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	require.Equal(t, int64(2), atomic.LoadInt64(&hits))
+	_, err = client.ChatCompletion("glm-4-flash").SetUserID("u2").AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).Do(context.Background())
+	require.NoError(t, err)
+
+	// successful batch results are recorded, failed ones are ignored
+	br := NewBatchResultReader[ChatCompletionResponse](strings.NewReader(`
+{"id":"r1","custom_id":"1","response":{"status_code":200,"body":{"model":"glm-4-flash","usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}}}
+{"id":"r2","custom_id":"2","response":{"status_code":400,"body":{"model":"glm-4-flash","usage":{"prompt_tokens":1,"completion_tokens":0,"total_tokens":1}}}}
+`))
+	for {
+		var result BatchResult[ChatCompletionResponse]
+		if err := br.Read(&result); err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+		tracker.RecordResponse(result, "", "batch")
+	}
+	tracker.RecordFineTune("glm-4-flash-ft", 1000)
+
+	snap := tracker.Snapshot()
+	require.Equal(t, int64(5), snap.Total.Requests)
+	require.Equal(t, int64(902), snap.Total.TotalTokens)
+	require.Equal(t, int64(1000), snap.Total.TrainedTokens)
+	require.InDelta(t, 3*500.0/1e6+3.0/1e6+0.01, snap.Total.Cost, 1e-9)
+	require.Equal(t, UsageStats{Requests: 2, PromptTokens: 200, CompletionTokens: 400, TotalTokens: 600, Cost: 1000.0 / 1e6}, snap.Users["u1"])
+	require.Equal(t, int64(2), snap.Tags["eval"].Requests)
+	require.Equal(t, int64(1), snap.Tags["batch"].Requests)
+	require.Equal(t, int64(4), snap.Models["glm-4-flash"].Requests)
+
+	tracker.SetBudget(UsageBudget{MaxCost: 0.01})
+	require.ErrorIs(t, tracker.Check(""), ErrBudgetExceeded)
+
+	tracker.Reset()
+	require.NoError(t, tracker.Check("u1"))
+	require.Empty(t, tracker.Snapshot().Models)
+}
+
+func TestUsageTrackerRecordFineTuneJob(t *testing.T) {
+	tracker := NewUsageTracker()
+	job := FineTuneItem{ID: "job-1", Model: "glm-4-flash", Status: FineTuneStatusSucceeded, TrainedTokens: 1000}
+	tracker.RecordFineTuneJob(job)
+	tracker.RecordFineTuneJob(job)
+	tracker.RecordFineTuneJob(FineTuneItem{ID: "job-2", Model: "glm-4-flash", Status: FineTuneStatusFailed, TrainedTokens: 500})
+	require.Equal(t, int64(1000), tracker.Snapshot().Total.TrainedTokens)
+
+	// a job recorded before a reset is not recorded again
+	tracker.Reset()
+	tracker.RecordFineTuneJob(job)
+	require.Zero(t, tracker.Snapshot().Total.TrainedTokens)
+}
+
+func TestBatchResultReaderUsageTracker(t *testing.T) {
+	tracker := NewUsageTracker()
+	client, err := NewClient(WithAPIKey("a.b"), WithUsageTracker(tracker))
+	require.NoError(t, err)
+
+	br := NewBatchResultReader[EmbeddingResponse](strings.NewReader(`
+{"id":"r1","custom_id":"1","response":{"status_code":200,"body":{"model":"embedding-3","usage":{"prompt_tokens":4,"total_tokens":4}}}}
+{"id":"r2","custom_id":"2","response":{"status_code":500,"body":{"model":"embedding-3","usage":{"prompt_tokens":4,"total_tokens":4}}}}
+{"id":"r3","custom_id":"3","response":{"status_code":200,"body":{"model":"embedding-3","usage":{"prompt_tokens":6,"total_tokens":6}}}}
+`)).SetUsageTracker(client.Usage(), "batch")
+	for {
+		var result BatchResult[EmbeddingResponse]
+		if err := br.Read(&result); err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+	}
+
+	snap := tracker.Snapshot()
+	require.Equal(t, int64(2), snap.Total.Requests)
+	require.Equal(t, int64(10), snap.Total.TotalTokens)
+	require.Equal(t, int64(2), snap.Tags["batch"].Requests)
+}
@@ -7,6 +7,8 @@ type URLItem struct {
 
 // IDItem is a struct that contains an ID.
 type IDItem struct {
+	RawResponse
+
 	ID string `json:"id,omitempty"`
 }
 
@@ -0,0 +1,136 @@
+package zhipu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is a validation error of a field
+type FieldError struct {
+	// Field is the path of the field in the request body, like "messages[0].content"
+	Field string
+	// Message is the problem of the field
+	Message string
+}
+
+// Error implements error
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError is the error of the Validate method of the services, with all invalid fields.
+// Do calls Validate before sending the request unless disabled by WithValidation or WithCallValidation,
+// so an invalid request fails locally without a round trip.
+type ValidationError struct {
+	// Service is the service, like "ChatCompletion"
+	Service string
+	// Fields are the invalid fields
+	Fields []*FieldError
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	items := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		items = append(items, f.Error())
+	}
+	return fmt.Sprintf("zhipu: invalid %s request: %s", e.Service, strings.Join(items, "; "))
+}
+
+// Unwrap returns the field errors
+func (e *ValidationError) Unwrap() []error {
+	out := make([]error, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		out = append(out, f)
+	}
+	return out
+}
+
+// WithValidation enables or disables the Validate call in the Do methods, default to enabled
+func WithValidation(enabled bool) ClientOption {
+	return func(opts *clientOptions) {
+		opts.validation = &enabled
+	}
+}
+
+// WithCallValidation enables or disables the Validate call of the call, overriding WithValidation
+func WithCallValidation(enabled bool) CallOption {
+	return func(opts *callOptions) {
+		opts.validation = &enabled
+	}
+}
+
+// validate calls Validate of the service unless disabled, services without a client fail with ErrClientMissing
+func (c *Client) validate(s interface{ Validate() error }, optFns []CallOption) error {
+	if c == nil {
+		return ErrClientMissing
+	}
+	enabled := !c.skipValidation
+	if len(optFns) != 0 {
+		var opts callOptions
+		for _, optFn := range optFns {
+			optFn(&opts)
+		}
+		if opts.validation != nil {
+			enabled = *opts.validation
+		}
+	}
+	if !enabled {
+		return nil
+	}
+	return s.Validate()
+}
+
+// validator collects the field errors of a service
+type validator struct {
+	service string
+	fields  []*FieldError
+}
+
+func newValidator(service string) *validator {
+	return &validator{service: service}
+}
+
+// add adds a field error
+func (v *validator) add(field string, format string, args ...any) {
+	v.fields = append(v.fields, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// required adds a field error if the value is empty
+func (v *validator) required(field string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+	}
+}
+
+// oneOf adds a field error if the value is not one of the values
+func (v *validator) oneOf(field string, value string, values ...string) {
+	for _, item := range values {
+		if value == item {
+			return
+		}
+	}
+	v.add(field, "must be one of %s", strings.Join(values, ", "))
+}
+
+// min adds a field error if the value is less than min
+func (v *validator) min(field string, value *int, min int) {
+	if value != nil && *value < min {
+		v.add(field, "must be at least %d", min)
+	}
+}
+
+// between adds a field error if the value is out of [min, max]
+func (v *validator) between(field string, value *int, min, max int) {
+	if value != nil && (*value < min || *value > max) {
+		v.add(field, "must be in [%d, %d]", min, max)
+	}
+}
+
+// err returns the ValidationError, nil if no field error
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Service: v.service, Fields: v.fields}
+}
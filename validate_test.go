@@ -0,0 +1,84 @@
+package zhipu
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	err := NewChatCompletionService(nil).
+		SetTemperature(1.5).
+		AddTool(ChatCompletionToolRetrieval{}, ChatCompletionToolFunction{Name: "get weather"}).
+		Validate()
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, "ChatCompletion", verr.Service)
+
+	var fields []string
+	for _, f := range verr.Fields {
+		fields = append(fields, f.Field)
+	}
+	require.Equal(t, []string{"model", "messages", "temperature", "tools[0].retrieval.knowledge_id", "tools[1].function.name"}, fields)
+
+	var ferr *FieldError
+	require.True(t, errors.As(err, &ferr))
+	require.Equal(t, "model", ferr.Field)
+
+	require.NoError(t, NewChatCompletionService(nil).SetModel("glm-4-flash").
+		AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).
+		AddTool(ChatCompletionToolFunction{Name: "get_weather"}).
+		Validate())
+
+	// temperature and top_p are open intervals, the bounds are rejected
+	for _, value := range []float64{0, 1} {
+		err = NewChatCompletionService(nil).SetModel("glm-4-flash").
+			AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).
+			SetTemperature(value).
+			SetTopP(value).
+			Validate()
+		require.EqualError(t, err, "zhipu: invalid ChatCompletion request: temperature: must be in (0, 1); top_p: must be in (0, 1)")
+	}
+	require.NoError(t, NewChatCompletionService(nil).SetModel("glm-4-flash").
+		AddMessage(ChatCompletionMessage{Role: RoleUser, Content: "hi"}).
+		SetTemperature(0.99).
+		SetTopP(0.01).
+		Validate())
+
+	err = NewChatCompletionService(nil).SetModel("glm-4v").
+		AddMessage(ChatCompletionMultiMessage{Role: RoleUser, Content: []ChatCompletionMultiContent{{Type: MultiContentTypeImageURL}}}).
+		Validate()
+	require.EqualError(t, err, "zhipu: invalid ChatCompletion request: messages[0].content[0].image_url: is required")
+
+	err = NewFileCreateService(nil).SetPurpose(FilePurposeFineTune).Validate()
+	require.EqualError(t, err, "zhipu: invalid FileCreate request: file: is required")
+
+	err = NewFileEditService(nil).SetDocumentID("doc").SetCustomSeparator("\n", "").SetSentenceSize(10).Validate()
+	require.EqualError(t, err, "zhipu: invalid FileEdit request: custom_separator[1]: must not be empty; sentence_size: must be in [20, 2000]")
+
+	require.NoError(t, NewKnowledgeCapacityService(nil).Validate())
+}
+
+func TestValidationOptOut(t *testing.T) {
+	client, err := NewClient(WithAPIKey("a.b"), WithBaseURL("http://127.0.0.1:1"))
+	require.NoError(t, err)
+
+	_, err = client.ChatCompletion("glm-4-flash").Do(context.Background())
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+
+	// the request is sent and fails on the network
+	_, err = client.ChatCompletion("glm-4-flash").Do(context.Background(), WithCallValidation(false))
+	require.Error(t, err)
+	require.False(t, errors.As(err, &verr))
+
+	client, err = NewClient(WithAPIKey("a.b"), WithBaseURL("http://127.0.0.1:1"), WithValidation(false))
+	require.NoError(t, err)
+	_, err = client.ChatCompletion("glm-4-flash").Do(context.Background())
+	require.False(t, errors.As(err, &verr))
+	_, err = client.ChatCompletion("glm-4-flash").Do(context.Background(), WithCallValidation(true))
+	require.ErrorAs(t, err, &verr)
+}
@@ -2,6 +2,7 @@ package zhipu
 
 import (
 	"context"
+	"strings"
 
 	"github.com/go-resty/resty/v2"
 )
@@ -15,6 +16,7 @@ const (
 // VideoGenerationService creates a new video generation
 type VideoGenerationService struct {
 	client *Client
+	extras serviceExtras
 
 	model     string
 	prompt    string
@@ -29,6 +31,8 @@ var (
 
 // VideoGenerationResponse is the response of the VideoGenerationService
 type VideoGenerationResponse struct {
+	RawResponse
+
 	RequestID  string `json:"request_id"`
 	ID         string `json:"id"`
 	Model      string `json:"model"`
@@ -50,7 +54,7 @@ func (s *VideoGenerationService) BatchURL() string {
 }
 
 func (s *VideoGenerationService) BatchBody() any {
-	return s.buildBody()
+	return s.extras.mergeBody(s.buildBody())
 }
 
 // SetModel sets the model parameter
@@ -100,16 +104,75 @@ func (s *VideoGenerationService) buildBody() M {
 	return body
 }
 
-func (s *VideoGenerationService) Do(ctx context.Context) (res VideoGenerationResponse, err error) {
+// SetExtraBody sets extra fields of the VideoGeneration request, see WithCallBody
+func (s *VideoGenerationService) SetExtraBody(body M) *VideoGenerationService {
+	s.extras.body = body
+	return s
+}
+
+// SetExtraHeaders sets extra headers of the VideoGeneration request
+func (s *VideoGenerationService) SetExtraHeaders(headers map[string]string) *VideoGenerationService {
+	s.extras.headers = headers
+	return s
+}
+
+// FromBody rebuilds the service from a json request body, like the body of a batch line or a logged request,
+// unknown fields are kept as extra body, fields missing from the body are reset, the client and extra headers are kept
+func (s *VideoGenerationService) FromBody(data []byte) (err error) {
+	var fields bodyFields
+	if fields, err = decodeBodyFields(data); err != nil {
+		return
+	}
+	*s = VideoGenerationService{client: s.client, extras: serviceExtras{headers: s.extras.headers}}
+	if _, err = fields.take("model", &s.model); err != nil {
+		return
+	}
+	if _, err = fields.take("prompt", &s.prompt); err != nil {
+		return
+	}
+	if _, err = fields.take("user_id", &s.userID); err != nil {
+		return
+	}
+	if _, err = fields.take("image_url", &s.imageURL); err != nil {
+		return
+	}
+	if _, err = fields.take("request_id", &s.requestID); err != nil {
+		return
+	}
+	s.extras.body, err = fields.rest()
+	return
+}
+
+// UnmarshalJSON implements json.Unmarshaler with FromBody
+func (s *VideoGenerationService) UnmarshalJSON(data []byte) error {
+	return s.FromBody(data)
+}
+
+// Validate checks the VideoGeneration request locally, see ValidationError
+func (s *VideoGenerationService) Validate() error {
+	v := newValidator("VideoGeneration")
+	v.required("model", s.model)
+	if strings.TrimSpace(s.prompt) == "" && s.imageURL == "" {
+		v.add("prompt", "is required without image_url")
+	}
+	return v.err()
+}
+
+func (s *VideoGenerationService) Do(ctx context.Context, opts ...CallOption) (res VideoGenerationResponse, err error) {
+	if err = s.client.validate(s, opts); err != nil {
+		return
+	}
+	return invoke(ctx, s.client, &Operation{Name: "VideoGeneration", Model: s.model, Method: "POST", Path: "videos/generations", Body: s.buildBody()}, s.do, s.extras.callOptions(opts)...)
+}
+
+func (s *VideoGenerationService) do(ctx context.Context, op *Operation) (res VideoGenerationResponse, err error) {
 	var (
 		resp     *resty.Response
 		apiError APIErrorResponse
 	)
 
-	body := s.buildBody()
-
 	if resp, err = s.client.request(ctx).
-		SetBody(body).
+		SetBody(op.Body).
 		SetResult(&res).
 		SetError(&apiError).
 		Post("videos/generations"); err != nil {
@@ -9,7 +9,7 @@ import (
 )
 
 func TestVideoGeneration(t *testing.T) {
-	client, err := NewClient()
+	client, err := newTestClient(t)
 	require.NoError(t, err)
 
 	s := client.VideoGeneration("cogvideox")
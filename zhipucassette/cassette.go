@@ -0,0 +1,378 @@
+// Package zhipucassette provides a record/replay http.RoundTripper for deterministic tests.
+//
+// In record mode, exchanges with the real platform are saved into a fixture file,
+// with the Authorization header removed. In replay mode, the fixture is served offline,
+// and server-sent event streams are replayed with their original chunk boundaries.
+//
+// Example:
+//
+//	c, err := zhipucassette.New("testdata/cassettes/chat.json", zhipucassette.ModeFromEnv())
+//	defer c.Save()
+//	client, err := zhipu.NewClient(zhipu.WithHTTPClient(c.Client()))
+package zhipucassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// EnvMode is the environment variable read by ModeFromEnv
+	EnvMode = "ZHIPUAI_CASSETTE"
+)
+
+// Mode is the mode of a cassette
+type Mode string
+
+const (
+	// ModeReplay serves the fixture, requests without a recorded exchange fail
+	ModeReplay Mode = "replay"
+	// ModeRecord forwards requests to the real transport and saves the exchanges
+	ModeRecord Mode = "record"
+	// ModeAuto replays if the fixture exists, otherwise records
+	ModeAuto Mode = "auto"
+)
+
+var (
+	// ErrNoInteraction is returned in replay mode when no recorded exchange matches a request
+	ErrNoInteraction = errors.New("zhipucassette: no recorded interaction matches the request")
+
+	// redactedHeaders are never written into fixtures
+	redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+)
+
+// ModeFromEnv returns the mode from the environment variable ZHIPUAI_CASSETTE, default to ModeReplay
+func ModeFromEnv() Mode {
+	switch Mode(strings.ToLower(strings.TrimSpace(os.Getenv(EnvMode)))) {
+	case ModeRecord:
+		return ModeRecord
+	case ModeAuto:
+		return ModeAuto
+	default:
+		return ModeReplay
+	}
+}
+
+// Request is a recorded request
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+	// Base64 is true if the body is base64 encoded binary data
+	Base64 bool `json:"base64,omitempty"`
+	// Chunks are the sizes in bytes of the pieces of the body as they were read from the wire
+	Chunks []int `json:"chunks,omitempty"`
+}
+
+// decodeBody returns the body split into the recorded chunks
+func (r Response) decodeBody() (chunks [][]byte, err error) {
+	body := []byte(r.Body)
+	if r.Base64 {
+		if body, err = base64.StdEncoding.DecodeString(r.Body); err != nil {
+			return
+		}
+	}
+	for _, size := range r.Chunks {
+		if size > len(body) {
+			size = len(body)
+		}
+		chunks = append(chunks, body[:size])
+		body = body[size:]
+	}
+	if len(body) != 0 {
+		chunks = append(chunks, body)
+	}
+	return
+}
+
+// Interaction is a recorded request and response pair
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+
+	used bool
+	body bytes.Buffer
+}
+
+// encodeBody encodes the body read so far into the response
+func (i *Interaction) encodeBody() {
+	if i.Response.Base64 {
+		i.Response.Body = base64.StdEncoding.EncodeToString(i.body.Bytes())
+	} else {
+		i.Response.Body = i.body.String()
+	}
+}
+
+// fixture is the content of a fixture file
+type fixture struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Cassette is a record/replay http.RoundTripper
+type Cassette struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []*Interaction
+}
+
+var (
+	_ http.RoundTripper = &Cassette{}
+)
+
+// Option configures a cassette
+type Option func(c *Cassette)
+
+// WithTransport sets the transport used in record mode, default to http.DefaultTransport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Cassette) {
+		c.transport = transport
+	}
+}
+
+// New creates a cassette backed by the fixture file at path
+func New(path string, mode Mode, opts ...Option) (c *Cassette, err error) {
+	c = &Cassette{path: path, mode: mode, transport: http.DefaultTransport}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.mode == ModeAuto {
+		if _, err = os.Stat(path); err == nil {
+			c.mode = ModeReplay
+		} else if os.IsNotExist(err) {
+			c.mode, err = ModeRecord, nil
+		} else {
+			return
+		}
+	}
+
+	if c.mode == ModeReplay {
+		var buf []byte
+		if buf, err = os.ReadFile(path); err != nil {
+			return
+		}
+		var f fixture
+		if err = json.Unmarshal(buf, &f); err != nil {
+			err = fmt.Errorf("zhipucassette: invalid fixture %s: %w", path, err)
+			return
+		}
+		c.interactions = f.Interactions
+	}
+	return
+}
+
+// Mode returns the effective mode of the cassette, ModeAuto is resolved on creation
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// Client returns a http.Client using the cassette as transport
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Save writes the recorded interactions into the fixture file, it's a no-op in replay mode
+func (c *Cassette) Save() (err error) {
+	if c.mode != ModeRecord {
+		return
+	}
+
+	c.mu.Lock()
+	for _, item := range c.interactions {
+		item.encodeBody()
+	}
+	buf, err := json.MarshalIndent(fixture{Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return
+	}
+	return os.WriteFile(c.path, append(buf, '\n'), 0644)
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := Request{
+		Method: req.Method,
+		URL:    req.URL.RequestURI(),
+		Header: redact(req.Header),
+	}
+	if isTextual(req.Header.Get("Content-Type")) {
+		recorded.Body = string(body)
+	}
+
+	if c.mode == ModeReplay {
+		return c.replay(req, recorded)
+	}
+	return c.record(req, recorded)
+}
+
+func (c *Cassette) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var found *Interaction
+	for _, item := range c.interactions {
+		if item.used || item.Request.Method != recorded.Method || item.Request.URL != recorded.URL {
+			continue
+		}
+		if item.Request.Body != "" && !equalBody(item.Request.Body, recorded.Body) {
+			continue
+		}
+		found = item
+		break
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.URL)
+	}
+	found.used = true
+
+	chunks, err := found.Response.decodeBody()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", found.Response.StatusCode, http.StatusText(found.Response.StatusCode)),
+		StatusCode:    found.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        found.Response.Header.Clone(),
+		Body:          &chunkReader{chunks: chunks},
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+func (c *Cassette) record(req *http.Request, recorded Request) (*http.Response, error) {
+	res, err := c.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     redact(res.Header),
+			Base64:     !isTextual(res.Header.Get("Content-Type")),
+		},
+	}
+
+	c.mu.Lock()
+	c.interactions = append(c.interactions, item)
+	c.mu.Unlock()
+
+	res.Body = &recordingReader{ReadCloser: res.Body, cassette: c, item: item}
+	return res, nil
+}
+
+// readRequestBody reads the body of the request and restores it
+func readRequestBody(req *http.Request) (body []byte, err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+	if body, err = io.ReadAll(req.Body); err != nil {
+		return
+	}
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return
+}
+
+// redact clones the header without secrets
+func redact(h http.Header) http.Header {
+	out := h.Clone()
+	for _, key := range redactedHeaders {
+		out.Del(key)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func isTextual(contentType string) bool {
+	return strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/")
+}
+
+// equalBody compares two bodies, json bodies are compared semantically
+func equalBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var va, vb any
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return bytes.Equal(ca, cb)
+}
+
+// recordingReader records every read of a response body as a chunk
+type recordingReader struct {
+	io.ReadCloser
+	cassette *Cassette
+	item     *Interaction
+}
+
+func (r *recordingReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		r.cassette.mu.Lock()
+		r.item.body.Write(p[:n])
+		r.item.Response.Chunks = append(r.item.Response.Chunks, n)
+		r.cassette.mu.Unlock()
+	}
+	return
+}
+
+// chunkReader replays chunks, one chunk per read at most
+type chunkReader struct {
+	chunks [][]byte
+}
+
+func (r *chunkReader) Read(p []byte) (n int, err error) {
+	for len(r.chunks) != 0 && len(r.chunks[0]) == 0 {
+		r.chunks = r.chunks[1:]
+	}
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n = copy(p, r.chunks[0])
+	r.chunks[0] = r.chunks[0][n:]
+	return
+}
+
+func (r *chunkReader) Close() error {
+	return nil
+}
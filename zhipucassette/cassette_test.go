@@ -0,0 +1,179 @@
+package zhipucassette
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yankeguo/zhipu"
+)
+
+var testChunks = []string{
+	"data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"你\"}}]}\n\n",
+	"data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"好\"},\"finish_reason\":\"stop\"}]}\n\n",
+	"data: [DONE]\n\n",
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /chat/completions", func(rw http.ResponseWriter, r *http.Request) {
+		require.NotEmpty(t, r.Header.Get("Authorization"))
+		buf, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(buf), `"stream":true`) {
+			rw.Header().Set("Content-Type", "text/event-stream")
+			for _, chunk := range testChunks {
+				_, _ = io.WriteString(rw, chunk)
+				rw.(http.Flusher).Flush()
+			}
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(rw, `{"id":"1","choices":[{"finish_reason":"stop","message":{"role":"assistant","content":"你好"}}]}`)
+	})
+	mux.HandleFunc("GET /files/{file_id}/content", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = rw.Write([]byte{0xff, 0x00, 0xfe})
+	})
+	return httptest.NewServer(mux)
+}
+
+func newChat(client *zhipu.Client) *zhipu.ChatCompletionService {
+	return client.ChatCompletion("glm-4-flash").AddMessage(zhipu.ChatCompletionMessage{Role: zhipu.RoleUser, Content: "你好"})
+}
+
+func TestCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "chat.json")
+	ctx := context.Background()
+
+	s := newTestServer(t)
+
+	// record
+	c, err := New(path, ModeAuto)
+	require.NoError(t, err)
+	require.Equal(t, ModeRecord, c.Mode())
+
+	client, err := zhipu.NewClient(zhipu.WithBaseURL(s.URL), zhipu.WithAPIKey("id.secret"), zhipu.WithHTTPClient(c.Client()))
+	require.NoError(t, err)
+
+	res, err := newChat(client).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "你好", res.Choices[0].Message.Content)
+
+	var recordedChunks []string
+	_, err = newChat(client).SetStreamHandler(func(chunk zhipu.ChatCompletionResponse) error {
+		recordedChunks = append(recordedChunks, chunk.Choices[0].Delta.Content)
+		return nil
+	}).Do(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, client.FileDownload("file-1").SetOutput(io.Discard).Do(ctx))
+
+	require.NoError(t, c.Save())
+	s.Close()
+
+	buf, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(buf), "Authorization")
+	require.NotContains(t, string(buf), "eyJ")
+
+	// replay, the server is closed
+	c, err = New(path, ModeAuto)
+	require.NoError(t, err)
+	require.Equal(t, ModeReplay, c.Mode())
+
+	client, err = zhipu.NewClient(zhipu.WithBaseURL(s.URL), zhipu.WithAPIKey("other.key"), zhipu.WithHTTPClient(c.Client()))
+	require.NoError(t, err)
+
+	res, err = newChat(client).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "你好", res.Choices[0].Message.Content)
+
+	var replayedChunks []string
+	_, err = newChat(client).SetStreamHandler(func(chunk zhipu.ChatCompletionResponse) error {
+		replayedChunks = append(replayedChunks, chunk.Choices[0].Delta.Content)
+		return nil
+	}).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, recordedChunks, replayedChunks)
+
+	out := &strings.Builder{}
+	require.NoError(t, client.FileDownload("file-1").SetOutput(out).Do(ctx))
+	require.Equal(t, string([]byte{0xff, 0x00, 0xfe}), out.String())
+
+	// every interaction is used once
+	_, err = newChat(client).Do(ctx)
+	require.ErrorIs(t, err, ErrNoInteraction)
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestCassetteChunkBoundaries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw.json")
+
+	// a transport delivering the body in fixed pieces, splitting a multibyte rune
+	var pieces [][]byte
+	for _, chunk := range testChunks {
+		pieces = append(pieces, []byte(chunk))
+	}
+	pieces = append([][]byte{pieces[0][:62], pieces[0][62:]}, pieces[1:]...)
+
+	c, err := New(path, ModeRecord, WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			Body:       &chunkReader{chunks: pieces},
+		}, nil
+	})))
+	require.NoError(t, err)
+
+	readChunks := func(rt http.RoundTripper) (chunks []string) {
+		req, _ := http.NewRequest(http.MethodPost, "https://example.com/chat/completions", strings.NewReader(`{"stream":true}`))
+		req.Header.Set("Authorization", "secret")
+		req.Header.Set("Content-Type", "application/json")
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		buf := make([]byte, 4096)
+		for {
+			n, err := res.Body.Read(buf)
+			if n > 0 {
+				chunks = append(chunks, string(buf[:n]))
+			}
+			if err != nil {
+				break
+			}
+		}
+		return
+	}
+
+	recorded := readChunks(c)
+	require.Len(t, recorded, 4)
+	require.NoError(t, c.Save())
+
+	buf, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(buf), "secret")
+
+	c, err = New(path, ModeReplay)
+	require.NoError(t, err)
+	require.Equal(t, recorded, readChunks(c))
+}
+
+func TestModeFromEnv(t *testing.T) {
+	t.Setenv(EnvMode, "")
+	require.Equal(t, ModeReplay, ModeFromEnv())
+	t.Setenv(EnvMode, "Record")
+	require.Equal(t, ModeRecord, ModeFromEnv())
+	t.Setenv(EnvMode, "auto")
+	require.Equal(t, ModeAuto, ModeFromEnv())
+}
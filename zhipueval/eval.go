@@ -0,0 +1,440 @@
+// Package zhipueval evaluates prompts and models on a dataset, live or through the Batch API,
+// and compares the scores, usage and cost of each variant.
+//
+// Example:
+//
+//	dataset, err := zhipueval.LoadDataset("dataset.jsonl")
+//
+//	report, err := zhipueval.NewRunner(client).
+//		AddVariant(zhipueval.Variant{Model: "glm-4-flash"}, zhipueval.Variant{Model: "glm-4-plus"}).
+//		AddScorer(zhipueval.ExactMatch()).
+//		Run(ctx, dataset)
+//
+//	report.WriteMarkdown(os.Stdout)
+package zhipueval
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yankeguo/zhipu"
+)
+
+const (
+	batchStatusCompleted = "completed"
+	batchStatusFailed    = "failed"
+	batchStatusExpired   = "expired"
+	batchStatusCancelled = "cancelled"
+)
+
+// Example is a line of a dataset
+type Example struct {
+	// ID identifies the example in the report, default to the line number
+	ID string `json:"id,omitempty"`
+	// Input is the user message, or the "input" variable of the prompt template
+	Input string `json:"input,omitempty"`
+	// Vars are the variables of the prompt template
+	Vars zhipu.M `json:"vars,omitempty"`
+	// Expected is the expected output
+	Expected string `json:"expected,omitempty"`
+}
+
+// ReadDataset reads a dataset in JSONL, one Example per line, blank lines are skipped
+func ReadDataset(r io.Reader) (dataset []Example, err error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 16<<20)
+	var line int
+	for sc.Scan() {
+		line++
+		buf := bytes.TrimSpace(sc.Bytes())
+		if len(buf) == 0 {
+			continue
+		}
+		var ex Example
+		if err = json.Unmarshal(buf, &ex); err != nil {
+			err = fmt.Errorf("zhipueval: invalid example at line %d: %w", line, err)
+			return
+		}
+		if ex.ID == "" {
+			ex.ID = strconv.Itoa(line)
+		}
+		dataset = append(dataset, ex)
+	}
+	err = sc.Err()
+	return
+}
+
+// LoadDataset reads a dataset from a JSONL file
+func LoadDataset(file string) (dataset []Example, err error) {
+	var f *os.File
+	if f, err = os.Open(file); err != nil {
+		return
+	}
+	defer f.Close()
+	return ReadDataset(f)
+}
+
+// Variant is a model with a prompt and a parameter set to evaluate
+type Variant struct {
+	// Name identifies the variant in the report, default to the model, must be unique
+	Name string
+	// Model is the model of the chat completion
+	Model string
+	// Prompt renders the messages with the vars of the example and "input", default to a user message of the input
+	Prompt *zhipu.PromptTemplate
+	// Examples are the few-shot examples of the prompt
+	Examples []zhipu.M
+	// Configure sets the parameters of the chat completion, like the temperature
+	Configure func(s *zhipu.ChatCompletionService)
+}
+
+// Runner runs a dataset through the variants and scores the outputs
+type Runner struct {
+	client *zhipu.Client
+
+	variants     []Variant
+	scorers      []Scorer
+	concurrency  int
+	pollInterval time.Duration
+	prices       map[string]zhipu.ModelPrice
+}
+
+// NewRunner creates a new Runner
+func NewRunner(client *zhipu.Client) *Runner {
+	return &Runner{
+		client:       client,
+		concurrency:  4,
+		pollInterval: 10 * time.Second,
+		prices:       map[string]zhipu.ModelPrice{},
+	}
+}
+
+// AddVariant adds variants
+func (r *Runner) AddVariant(variants ...Variant) *Runner {
+	r.variants = append(r.variants, variants...)
+	return r
+}
+
+// AddScorer adds scorers
+func (r *Runner) AddScorer(scorers ...Scorer) *Runner {
+	r.scorers = append(r.scorers, scorers...)
+	return r
+}
+
+// SetConcurrency sets the max concurrent calls of Run and of the scorers, default to 4
+func (r *Runner) SetConcurrency(concurrency int) *Runner {
+	r.concurrency = concurrency
+	return r
+}
+
+// SetPollInterval sets the interval of polling the batch in RunBatch, default to 10s
+func (r *Runner) SetPollInterval(interval time.Duration) *Runner {
+	r.pollInterval = interval
+	return r
+}
+
+// SetPrice sets the price of a model for the cost in the report, model "" is the default price
+func (r *Runner) SetPrice(model string, price zhipu.ModelPrice) *Runner {
+	r.prices[model] = price
+	return r
+}
+
+// variantsChecked returns the variants with default names, or an error if a name is duplicated
+func (r *Runner) variantsChecked() (variants []Variant, err error) {
+	if len(r.variants) == 0 {
+		err = errors.New("zhipueval: no variant")
+		return
+	}
+	seen := map[string]bool{}
+	for _, v := range r.variants {
+		if v.Name == "" {
+			v.Name = v.Model
+		}
+		if seen[v.Name] {
+			err = fmt.Errorf("zhipueval: duplicated variant %s", v.Name)
+			return
+		}
+		seen[v.Name] = true
+		variants = append(variants, v)
+	}
+	return
+}
+
+// service builds the chat completion of the example
+func (r *Runner) service(v Variant, ex Example) (s *zhipu.ChatCompletionService, err error) {
+	s = r.client.ChatCompletion(v.Model)
+	if v.Configure != nil {
+		v.Configure(s)
+	}
+	if v.Prompt == nil {
+		s.AddMessage(zhipu.NewUserMessage(ex.Input))
+		return
+	}
+	vars := zhipu.M{"input": ex.Input}
+	for k, val := range ex.Vars {
+		vars[k] = val
+	}
+	err = v.Prompt.Apply(s, vars, v.Examples...)
+	return
+}
+
+// newResults creates the results of all variants and examples, in the order of variants then examples
+func newResults(variants []Variant, dataset []Example) []Result {
+	results := make([]Result, 0, len(variants)*len(dataset))
+	for _, v := range variants {
+		for _, ex := range dataset {
+			results = append(results, Result{Variant: v.Name, Model: v.Model, ExampleID: ex.ID, Expected: ex.Expected})
+		}
+	}
+	return results
+}
+
+// each calls fn for each index with bounded concurrency
+func (r *Runner) each(ctx context.Context, n int, fn func(i int)) error {
+	concurrency := r.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// setResponse sets the output and usage of the result
+func setResponse(result *Result, res zhipu.ChatCompletionResponse) {
+	result.Usage = res.Usage
+	if len(res.Choices) != 0 {
+		result.Output = res.Choices[0].Message.Content
+	}
+}
+
+// Run runs the dataset live, with bounded concurrency, failed calls are reported in the results
+func (r *Runner) Run(ctx context.Context, dataset []Example) (report *Report, err error) {
+	var variants []Variant
+	if variants, err = r.variantsChecked(); err != nil {
+		return
+	}
+	results := newResults(variants, dataset)
+
+	if err = r.each(ctx, len(results), func(i int) {
+		v, ex, result := variants[i/len(dataset)], dataset[i%len(dataset)], &results[i]
+
+		s, err := r.service(v, ex)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+		start := time.Now()
+		res, err := s.Do(ctx)
+		result.Latency = time.Since(start)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+		setResponse(result, res)
+		r.score(ctx, ex, result)
+	}); err != nil {
+		return
+	}
+
+	report = r.report(variants, results)
+	return
+}
+
+// RunBatch runs the dataset through the Batch API, one batch per model as a batch serves a single model,
+// waits for the batches and scores the outputs
+func (r *Runner) RunBatch(ctx context.Context, dataset []Example) (report *Report, err error) {
+	var variants []Variant
+	if variants, err = r.variantsChecked(); err != nil {
+		return
+	}
+	results := newResults(variants, dataset)
+
+	// the indexes of the results by model, in the order of variants
+	var (
+		models  []string
+		indexes = map[string][]int{}
+	)
+	for i := range results {
+		model := variants[i/len(dataset)].Model
+		if _, ok := indexes[model]; !ok {
+			models = append(models, model)
+		}
+		indexes[model] = append(indexes[model], i)
+	}
+
+	batches := make([]zhipu.BatchItem, 0, len(models))
+	for _, model := range models {
+		var batch zhipu.BatchItem
+		if batch, err = r.createBatch(ctx, variants, dataset, indexes[model]); err != nil {
+			err = fmt.Errorf("zhipueval: model %s: %w", model, err)
+			return
+		}
+		batches = append(batches, batch)
+	}
+
+	seen := make([]bool, len(results))
+	for m, batch := range batches {
+		if batch, err = r.waitBatch(ctx, batch); err != nil {
+			return
+		}
+		for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+			if fileID == "" {
+				continue
+			}
+			if err = r.readBatchResults(ctx, fileID, results, seen); err != nil {
+				return
+			}
+		}
+		for _, i := range indexes[models[m]] {
+			if !seen[i] {
+				results[i].Error = "missing in the results of batch " + batch.ID
+			}
+		}
+	}
+
+	if err = r.each(ctx, len(results), func(i int) {
+		if result := &results[i]; result.Error == "" {
+			r.score(ctx, dataset[i%len(dataset)], result)
+		}
+	}); err != nil {
+		return
+	}
+
+	report = r.report(variants, results)
+	return
+}
+
+// createBatch uploads the requests of the results and creates a batch, custom ids are the indexes of the results
+func (r *Runner) createBatch(ctx context.Context, variants []Variant, dataset []Example, indexes []int) (batch zhipu.BatchItem, err error) {
+	buf := &bytes.Buffer{}
+	w := zhipu.NewBatchFileWriter(buf)
+	for _, i := range indexes {
+		v, ex := variants[i/len(dataset)], dataset[i%len(dataset)]
+		var s *zhipu.ChatCompletionService
+		if s, err = r.service(v, ex); err != nil {
+			err = fmt.Errorf("variant %s, example %s: %w", v.Name, ex.ID, err)
+			return
+		}
+		if err = w.Write(strconv.Itoa(i), s); err != nil {
+			return
+		}
+	}
+
+	var file zhipu.FileCreateResponse
+	if file, err = r.client.FileCreate(zhipu.FilePurposeBatch).SetFile(buf, "eval.jsonl").Do(ctx); err != nil {
+		return
+	}
+	return r.client.BatchCreate().
+		SetInputFileID(file.ID).
+		SetEndpoint(zhipu.BatchEndpointV4ChatCompletions).
+		SetCompletionWindow(zhipu.BatchCompletionWindow24h).
+		Do(ctx)
+}
+
+// waitBatch polls the batch until a terminal status
+func (r *Runner) waitBatch(ctx context.Context, batch zhipu.BatchItem) (res zhipu.BatchItem, err error) {
+	res = batch
+	for {
+		switch res.Status {
+		case batchStatusCompleted:
+			return
+		case batchStatusFailed, batchStatusExpired, batchStatusCancelled:
+			if res.OutputFileID == "" && res.ErrorFileID == "" {
+				err = fmt.Errorf("zhipueval: batch %s is %s", res.ID, res.Status)
+			}
+			return
+		}
+		timer := time.NewTimer(r.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			err = ctx.Err()
+			return
+		case <-timer.C:
+		}
+		if res, err = r.client.BatchGet(batch.ID).Do(ctx); err != nil {
+			return
+		}
+	}
+}
+
+// readBatchResults downloads a result file of the batch into the results, custom ids are the indexes of the results
+func (r *Runner) readBatchResults(ctx context.Context, fileID string, results []Result, seen []bool) (err error) {
+	buf := &bytes.Buffer{}
+	if err = r.client.FileDownload(fileID).SetOutput(buf).Do(ctx); err != nil {
+		return
+	}
+	br := zhipu.NewBatchResultReader[json.RawMessage](buf)
+	for {
+		var item zhipu.BatchResult[json.RawMessage]
+		if err = br.Read(&item); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return
+		}
+		i, perr := strconv.Atoi(item.CustomID)
+		if perr != nil || i < 0 || i >= len(results) {
+			continue
+		}
+		result := &results[i]
+		seen[i] = true
+		if item.Response.StatusCode != 200 {
+			var apiErr zhipu.APIErrorResponse
+			_ = json.Unmarshal(item.Response.Body, &apiErr)
+			result.Error = strings.TrimSpace(fmt.Sprintf("status %d %s", item.Response.StatusCode, apiErr.Message))
+			continue
+		}
+		var res zhipu.ChatCompletionResponse
+		if err = json.Unmarshal(item.Response.Body, &res); err != nil {
+			return
+		}
+		setResponse(result, res)
+	}
+}
+
+// score scores the output of the result with all scorers
+func (r *Runner) score(ctx context.Context, ex Example, result *Result) {
+	for _, scorer := range r.scorers {
+		score, err := scorer.Score(ctx, ex, result.Output)
+		if err != nil {
+			if result.ScoreErrors == nil {
+				result.ScoreErrors = map[string]string{}
+			}
+			result.ScoreErrors[scorer.Name()] = err.Error()
+			continue
+		}
+		if result.Scores == nil {
+			result.Scores = map[string]float64{}
+		}
+		result.Scores[scorer.Name()] = score
+	}
+}
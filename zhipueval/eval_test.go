@@ -0,0 +1,146 @@
+package zhipueval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yankeguo/zhipu"
+	"github.com/yankeguo/zhipu/zhipumock"
+)
+
+const testDataset = `{"id":"a","input":"hello","expected":"hello"}
+
+{"input":"world","expected":"planet"}
+`
+
+func newTestRunner(t *testing.T) (*zhipumock.Server, *Runner) {
+	s := zhipumock.NewServer()
+	t.Cleanup(s.Close)
+
+	client, err := zhipu.NewClient(s.ClientOptions()...)
+	require.NoError(t, err)
+
+	prompt := zhipu.MustParsePromptTemplate("echo", "v1", "--- user ---\n{{.input}}")
+
+	r := NewRunner(client).
+		SetConcurrency(2).
+		SetPollInterval(time.Millisecond).
+		SetPrice("", zhipu.ModelPrice{Input: 1000000, Output: 2000000}).
+		AddVariant(
+			Variant{Model: "glm-4-flash"},
+			Variant{Name: "glm-4-plus@v1", Model: "glm-4-plus", Prompt: prompt, Configure: func(s *zhipu.ChatCompletionService) {
+				s.SetTemperature(0.5)
+			}},
+		).
+		AddScorer(ExactMatch())
+	return s, r
+}
+
+func TestReadDataset(t *testing.T) {
+	dataset, err := ReadDataset(strings.NewReader(testDataset))
+	require.NoError(t, err)
+	require.Equal(t, []Example{
+		{ID: "a", Input: "hello", Expected: "hello"},
+		{ID: "3", Input: "world", Expected: "planet"},
+	}, dataset)
+
+	_, err = ReadDataset(strings.NewReader("{"))
+	require.Error(t, err)
+}
+
+func TestRunnerRun(t *testing.T) {
+	_, r := newTestRunner(t)
+
+	dataset, err := ReadDataset(strings.NewReader(testDataset))
+	require.NoError(t, err)
+
+	report, err := r.Run(context.Background(), dataset)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 4)
+	require.Len(t, report.Summaries, 2)
+
+	for _, summary := range report.Summaries {
+		require.Equal(t, 2, summary.Examples)
+		require.Equal(t, 0, summary.Errors)
+		require.Equal(t, 0.5, summary.Scores["exact_match"])
+		require.Equal(t, int64(2), summary.Usage.Requests)
+		require.Equal(t, int64(10), summary.Usage.PromptTokens)
+		require.Equal(t, 30.0, summary.Usage.Cost)
+	}
+	require.Equal(t, "glm-4-plus@v1", report.Summaries[1].Variant)
+	require.Equal(t, "world", report.Results[3].Output)
+
+	best, ok := report.Best("exact_match")
+	require.True(t, ok)
+	require.Equal(t, "glm-4-flash", best.Variant)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, report.WriteMarkdown(buf))
+	require.Contains(t, buf.String(), "| glm-4-plus@v1 | glm-4-plus | 2 | 0 | 0.500 | 10 | 10 | 30.0000 |")
+}
+
+func TestRunnerRunErrors(t *testing.T) {
+	s, r := newTestRunner(t)
+	s.AddChatCompletionReply(zhipumock.ChatCompletionReply{Error: &zhipu.APIError{Code: "1214", Message: "bad request"}})
+
+	report, err := r.SetConcurrency(1).Run(context.Background(), []Example{{ID: "1", Input: "hi", Expected: "hi"}})
+	require.NoError(t, err)
+	require.Contains(t, report.Results[0].Error, "bad request")
+	require.Equal(t, 1, report.Summaries[0].Errors)
+	require.Empty(t, report.Summaries[0].Scores)
+	require.Equal(t, 1.0, report.Summaries[1].Scores["exact_match"])
+
+	_, err = NewRunner(r.client).AddVariant(Variant{Model: "a"}, Variant{Model: "a"}).Run(context.Background(), nil)
+	require.Error(t, err)
+}
+
+func TestRunnerRunBatch(t *testing.T) {
+	s, r := newTestRunner(t)
+
+	dataset, err := ReadDataset(strings.NewReader(testDataset))
+	require.NoError(t, err)
+
+	report, err := r.RunBatch(context.Background(), dataset)
+	require.NoError(t, err)
+	require.Len(t, report.Results, 4)
+	for _, summary := range report.Summaries {
+		require.Equal(t, 0, summary.Errors)
+		require.Equal(t, 0.5, summary.Scores["exact_match"])
+		require.Equal(t, 30.0, summary.Usage.Cost)
+	}
+
+	// one batch per model, each input file holds the requests of a single model
+	var models []string
+	for _, req := range s.Requests() {
+		if req.Path != "/batches" {
+			continue
+		}
+		var batch struct {
+			InputFileID string `json:"input_file_id"`
+		}
+		require.NoError(t, req.DecodeJSON(&batch))
+		content, ok := s.FileContent(batch.InputFileID)
+		require.True(t, ok)
+
+		seen := map[string]bool{}
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			var item struct {
+				Body struct {
+					Model string `json:"model"`
+				} `json:"body"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(line), &item))
+			seen[item.Body.Model] = true
+		}
+		require.Len(t, seen, 1)
+		for model := range seen {
+			models = append(models, model)
+		}
+	}
+	require.ElementsMatch(t, []string{"glm-4-flash", "glm-4-plus"}, models)
+}
@@ -0,0 +1,141 @@
+package zhipueval
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yankeguo/zhipu"
+)
+
+// Result is the output and the scores of a variant on an example
+type Result struct {
+	Variant   string                    `json:"variant"`
+	Model     string                    `json:"model"`
+	ExampleID string                    `json:"example_id"`
+	Expected  string                    `json:"expected,omitempty"`
+	Output    string                    `json:"output"`
+	Scores    map[string]float64        `json:"scores,omitempty"`
+	Usage     zhipu.ChatCompletionUsage `json:"usage"`
+	// Latency is the duration of the call, 0 in batch mode
+	Latency time.Duration `json:"latency"`
+	// Error is the error of the call, the result is not scored
+	Error string `json:"error,omitempty"`
+	// ScoreErrors are the errors of the scorers, by scorer name
+	ScoreErrors map[string]string `json:"score_errors,omitempty"`
+}
+
+// Summary is the aggregation of the results of a variant
+type Summary struct {
+	Variant  string `json:"variant"`
+	Model    string `json:"model"`
+	Examples int    `json:"examples"`
+	Errors   int    `json:"errors"`
+	// Scores are the mean scores of the scored results, by scorer name
+	Scores map[string]float64 `json:"scores"`
+	// Usage is the usage of the variant with the cost, scorers excluded
+	Usage zhipu.UsageStats `json:"usage"`
+	// Latency is the mean latency of the successful calls
+	Latency time.Duration `json:"latency"`
+}
+
+// Report is the comparison of the variants
+type Report struct {
+	Scorers   []string  `json:"scorers"`
+	Summaries []Summary `json:"summaries"`
+	Results   []Result  `json:"results"`
+}
+
+// report aggregates the results by variant
+func (r *Runner) report(variants []Variant, results []Result) *Report {
+	tracker := zhipu.NewUsageTracker().SetPrices(r.prices)
+
+	report := &Report{Results: results}
+	for _, scorer := range r.scorers {
+		report.Scorers = append(report.Scorers, scorer.Name())
+	}
+
+	for _, v := range variants {
+		var (
+			summary = Summary{Variant: v.Name, Model: v.Model, Scores: map[string]float64{}}
+			counts  = map[string]int{}
+			latency time.Duration
+		)
+		for _, result := range results {
+			if result.Variant != v.Name {
+				continue
+			}
+			summary.Examples++
+			if result.Error != "" {
+				summary.Errors++
+				continue
+			}
+			tracker.Record(zhipu.UsageRecord{Model: v.Model, Tags: []string{v.Name}, Usage: result.Usage})
+			latency += result.Latency
+			for name, score := range result.Scores {
+				summary.Scores[name] += score
+				counts[name]++
+			}
+		}
+		for name, count := range counts {
+			summary.Scores[name] /= float64(count)
+		}
+		if ok := summary.Examples - summary.Errors; ok > 0 {
+			summary.Latency = latency / time.Duration(ok)
+		}
+		summary.Usage = tracker.Snapshot().Tags[v.Name]
+		report.Summaries = append(report.Summaries, summary)
+	}
+	return report
+}
+
+// Best returns the summary with the highest mean score of the scorer, false if no variant has the score
+func (r *Report) Best(scorer string) (best Summary, ok bool) {
+	for _, summary := range r.Summaries {
+		score, found := summary.Scores[scorer]
+		if found && (!ok || score > best.Scores[scorer]) {
+			best, ok = summary, true
+		}
+	}
+	return
+}
+
+// WriteMarkdown writes the summaries as a markdown table
+func (r *Report) WriteMarkdown(w io.Writer) (err error) {
+	scorers := append([]string(nil), r.Scorers...)
+	sort.Strings(scorers)
+
+	header := append([]string{"variant", "model", "examples", "errors"}, scorers...)
+	header = append(header, "prompt tokens", "completion tokens", "cost", "latency")
+
+	rows := [][]string{header, make([]string, len(header))}
+	for i := range rows[1] {
+		rows[1][i] = "---"
+	}
+	for _, s := range r.Summaries {
+		row := []string{s.Variant, s.Model, fmt.Sprint(s.Examples), fmt.Sprint(s.Errors)}
+		for _, name := range scorers {
+			if score, ok := s.Scores[name]; ok {
+				row = append(row, fmt.Sprintf("%.3f", score))
+			} else {
+				row = append(row, "-")
+			}
+		}
+		row = append(row,
+			fmt.Sprint(s.Usage.PromptTokens),
+			fmt.Sprint(s.Usage.CompletionTokens),
+			fmt.Sprintf("%.4f", s.Usage.Cost),
+			s.Latency.Round(time.Millisecond).String(),
+		)
+		rows = append(rows, row)
+	}
+
+	for _, row := range rows {
+		if _, err = io.WriteString(w, "| "+strings.Join(row, " | ")+" |\n"); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,300 @@
+package zhipueval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yankeguo/zhipu"
+)
+
+// Scorer scores the output of an example, usually in [0, 1]
+type Scorer interface {
+	// Name is the name of the score in the report
+	Name() string
+	// Score scores the output
+	Score(ctx context.Context, ex Example, output string) (float64, error)
+}
+
+type scorerFunc struct {
+	name string
+	fn   func(ctx context.Context, ex Example, output string) (float64, error)
+}
+
+func (s scorerFunc) Name() string {
+	return s.name
+}
+
+func (s scorerFunc) Score(ctx context.Context, ex Example, output string) (float64, error) {
+	return s.fn(ctx, ex, output)
+}
+
+// NewScorer creates a Scorer with a function
+func NewScorer(name string, fn func(ctx context.Context, ex Example, output string) (float64, error)) Scorer {
+	return scorerFunc{name: name, fn: fn}
+}
+
+func boolScore(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// ExactMatch scores 1 if the output equals the expected output, surrounding spaces ignored
+func ExactMatch() Scorer {
+	return NewScorer("exact_match", func(ctx context.Context, ex Example, output string) (float64, error) {
+		return boolScore(strings.TrimSpace(output) == strings.TrimSpace(ex.Expected)), nil
+	})
+}
+
+// Regex scores 1 if the output matches the pattern, the expected output is used as the pattern if pattern is empty
+func Regex(pattern string) (Scorer, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return NewScorer("regex", func(ctx context.Context, ex Example, output string) (float64, error) {
+		re := re
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(ex.Expected); err != nil {
+				return 0, err
+			}
+		}
+		return boolScore(re.MatchString(output)), nil
+	}), nil
+}
+
+// JSONSchema scores 1 if the output, optionally in a markdown code block, is json valid against the schema,
+// the keywords type, enum, properties, required, additionalProperties, items, minItems, maxItems, minimum and maximum are supported
+func JSONSchema(schema []byte) (Scorer, error) {
+	var s jsonSchema
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil, err
+	}
+	return NewScorer("json_schema", func(ctx context.Context, ex Example, output string) (float64, error) {
+		var v any
+		if err := json.Unmarshal([]byte(stripCodeBlock(output)), &v); err != nil {
+			return 0, nil
+		}
+		return boolScore(s.validate(v) == nil), nil
+	}), nil
+}
+
+// stripCodeBlock returns the content of a markdown code block, or the text if not a code block
+func stripCodeBlock(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
+// jsonSchema is the subset of json schema supported by JSONSchema
+type jsonSchema struct {
+	Type                 jsonSchemaTypes        `json:"type"`
+	Enum                 []any                  `json:"enum"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+}
+
+// jsonSchemaTypes is the type keyword, a string or an array of strings
+type jsonSchemaTypes []string
+
+func (t *jsonSchemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if json.Unmarshal(data, &single) == nil {
+		*t = jsonSchemaTypes{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+// jsonType returns the json schema type of a decoded value
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return ""
+}
+
+func (s *jsonSchema) validate(v any) error {
+	if len(s.Type) != 0 {
+		typ, ok := jsonType(v), false
+		for _, want := range s.Type {
+			if want == typ || (want == "number" && typ == "integer") {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("expected %s, got %s", strings.Join(s.Type, " or "), typ)
+		}
+	}
+	if len(s.Enum) != 0 {
+		buf, _ := json.Marshal(v)
+		ok := false
+		for _, item := range s.Enum {
+			if b, _ := json.Marshal(item); string(b) == string(buf) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return errors.New("not in enum")
+		}
+	}
+	switch v := v.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("less than %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("greater than %v", *s.Maximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Errorf("less than %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fmt.Errorf("more than %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(item); err != nil {
+					return fmt.Errorf("[%d]: %w", i, err)
+				}
+			}
+		}
+	case map[string]any:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				return fmt.Errorf("%s is required", key)
+			}
+		}
+		for key, item := range v {
+			prop, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s is not allowed", key)
+				}
+				continue
+			}
+			if err := prop.validate(item); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// EmbeddingSimilarity scores the cosine similarity of the embeddings of the output and the expected output
+func EmbeddingSimilarity(client *zhipu.Client, model string) Scorer {
+	embed := func(ctx context.Context, input string) ([]float64, error) {
+		res, err := client.Embedding(model).SetInput(input).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(res.Data) == 0 {
+			return nil, errors.New("zhipueval: no embedding")
+		}
+		return res.Data[0].Embedding, nil
+	}
+	return NewScorer("embedding_similarity", func(ctx context.Context, ex Example, output string) (score float64, err error) {
+		if strings.TrimSpace(output) == "" {
+			return
+		}
+		var a, b []float64
+		if a, err = embed(ctx, output); err != nil {
+			return
+		}
+		if b, err = embed(ctx, ex.Expected); err != nil {
+			return
+		}
+		score = cosineSimilarity(a, b)
+		return
+	})
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// llmJudgeScorePattern matches the score in the reply of the judge
+var llmJudgeScorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// LLMJudge asks a model to grade the output from 0 to 10 against the criteria, the score is normalized to [0, 1]
+func LLMJudge(client *zhipu.Client, model string, criteria string) Scorer {
+	return NewScorer("llm_judge", func(ctx context.Context, ex Example, output string) (score float64, err error) {
+		user := fmt.Sprintf("Criteria:\n%s\n\nInput:\n%s\n\nExpected output:\n%s\n\nActual output:\n%s", criteria, ex.Input, ex.Expected, output)
+		var res zhipu.ChatCompletionResponse
+		if res, err = client.ChatCompletion(model).
+			AddMessage(
+				zhipu.NewSystemMessage("You are a strict grader. Grade the actual output against the criteria and the expected output. Reply with a single number from 0 to 10, nothing else."),
+				zhipu.NewUserMessage(user),
+			).
+			Do(ctx); err != nil {
+			return
+		}
+		if len(res.Choices) == 0 {
+			err = errors.New("zhipueval: no reply from the judge")
+			return
+		}
+		reply := res.Choices[0].Message.Content
+		match := llmJudgeScorePattern.FindString(reply)
+		if match == "" {
+			err = fmt.Errorf("zhipueval: no score in the reply of the judge: %q", reply)
+			return
+		}
+		if score, err = strconv.ParseFloat(match, 64); err != nil {
+			return
+		}
+		score = math.Max(0, math.Min(10, score)) / 10
+		return
+	})
+}
@@ -0,0 +1,90 @@
+package zhipueval
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yankeguo/zhipu"
+	"github.com/yankeguo/zhipu/zhipumock"
+)
+
+func TestScorers(t *testing.T) {
+	ctx := context.Background()
+	ex := Example{Input: "q", Expected: "  yes "}
+
+	score, err := ExactMatch().Score(ctx, ex, "yes")
+	require.NoError(t, err)
+	require.Equal(t, 1.0, score)
+
+	re, err := Regex(`^\d+$`)
+	require.NoError(t, err)
+	score, err = re.Score(ctx, ex, "123")
+	require.NoError(t, err)
+	require.Equal(t, 1.0, score)
+	score, err = re.Score(ctx, ex, "12a")
+	require.NoError(t, err)
+	require.Equal(t, 0.0, score)
+
+	re, err = Regex("")
+	require.NoError(t, err)
+	score, err = re.Score(ctx, Example{Expected: "ye+s"}, "yeees")
+	require.NoError(t, err)
+	require.Equal(t, 1.0, score)
+
+	_, err = Regex("(")
+	require.Error(t, err)
+}
+
+func TestJSONSchema(t *testing.T) {
+	ctx := context.Background()
+	scorer, err := JSONSchema([]byte(`{
+		"type": "object",
+		"required": ["label", "tags"],
+		"additionalProperties": false,
+		"properties": {
+			"label": {"enum": ["positive", "negative"]},
+			"score": {"type": "integer", "minimum": 0, "maximum": 10},
+			"tags": {"type": "array", "minItems": 1, "items": {"type": "string"}}
+		}
+	}`))
+	require.NoError(t, err)
+
+	for output, want := range map[string]float64{
+		`{"label":"positive","tags":["a"]}`:                                   1,
+		"```json\n{\"label\":\"negative\",\"tags\":[\"a\"],\"score\":3}\n```": 1,
+		`{"label":"neutral","tags":["a"]}`:                                    0,
+		`{"label":"positive","tags":[]}`:                                      0,
+		`{"label":"positive","tags":[1]}`:                                     0,
+		`{"label":"positive","tags":["a"],"score":1.5}`:                       0,
+		`{"label":"positive","tags":["a"],"score":11}`:                        0,
+		`{"label":"positive","tags":["a"],"extra":true}`:                      0,
+		`{"label":"positive"}`:                                                0,
+		`not json`:                                                            0,
+	} {
+		score, err := scorer.Score(ctx, Example{}, output)
+		require.NoError(t, err)
+		require.Equal(t, want, score, output)
+	}
+}
+
+func TestModelScorers(t *testing.T) {
+	ctx := context.Background()
+
+	s := zhipumock.NewServer()
+	defer s.Close()
+	client, err := zhipu.NewClient(s.ClientOptions()...)
+	require.NoError(t, err)
+
+	score, err := EmbeddingSimilarity(client, "embedding-3").Score(ctx, Example{Expected: "same"}, "same")
+	require.NoError(t, err)
+	require.InDelta(t, 1.0, score, 1e-9)
+
+	s.AddChatCompletionReply(zhipumock.ChatCompletionReply{Content: "Score: 7"}, zhipumock.ChatCompletionReply{Content: "great"})
+	judge := LLMJudge(client, "glm-4-plus", "correctness")
+	score, err = judge.Score(ctx, Example{Input: "1+1", Expected: "2"}, "2")
+	require.NoError(t, err)
+	require.InDelta(t, 0.7, score, 1e-9)
+	_, err = judge.Score(ctx, Example{Input: "1+1", Expected: "2"}, "2")
+	require.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package zhipumock
+
+import (
+	"net/http"
+
+	"github.com/yankeguo/zhipu"
+)
+
+type asyncTask struct {
+	polls  int
+	result zhipu.AsyncResultResponse
+}
+
+// SetAsyncResult overrides the result of an async task, the result is returned as is on the next poll
+func (s *Server) SetAsyncResult(id string, res zhipu.AsyncResultResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res.ID = id
+	s.tasks[id] = &asyncTask{polls: 0, result: res}
+}
+
+func (s *Server) handleVideoGenerations(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model     string `json:"model"`
+		RequestID string `json:"request_id"`
+	}
+	if !readJSON(r, &req) {
+		writeError(rw, http.StatusBadRequest, zhipu.APIError{Code: "1214", Message: "invalid request body"})
+		return
+	}
+
+	s.mu.Lock()
+	id := s.nextID("video")
+	task := &asyncTask{
+		polls: s.opts.asyncPolls,
+		result: zhipu.AsyncResultResponse{
+			Model:      req.Model,
+			TaskStatus: zhipu.VideoGenerationTaskStatusSuccess,
+			RequestID:  req.RequestID,
+			ID:         id,
+			VideoResult: []zhipu.AsyncResultVideo{{
+				URL:           s.URL + "/_mock/" + id + ".mp4",
+				CoverImageURL: s.URL + "/_mock/" + id + ".png",
+			}},
+		},
+	}
+	s.tasks[id] = task
+	s.mu.Unlock()
+
+	writeJSON(rw, http.StatusOK, zhipu.VideoGenerationResponse{
+		RequestID:  req.RequestID,
+		ID:         id,
+		Model:      req.Model,
+		TaskStatus: zhipu.VideoGenerationTaskStatusProcessing,
+	})
+}
+
+func (s *Server) handleAsyncResult(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	task, ok := s.tasks[r.PathValue("id")]
+	var res zhipu.AsyncResultResponse
+	if ok {
+		res = task.result
+		if task.polls > 0 {
+			task.polls--
+			res.TaskStatus = zhipu.VideoGenerationTaskStatusProcessing
+			res.VideoResult = nil
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeNotFound(rw, "task")
+		return
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) routeAsync(mux *http.ServeMux) {
+	mux.HandleFunc("POST /videos/generations", s.handleVideoGenerations)
+	mux.HandleFunc("GET /async-result/{id}", s.handleAsyncResult)
+}
@@ -0,0 +1,257 @@
+package zhipumock
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/yankeguo/zhipu"
+)
+
+// ChatCompletionReply is a scripted reply of the chat completion endpoint
+type ChatCompletionReply struct {
+	// Content is the content of the assistant message
+	Content string
+	// Chunks splits the content in stream mode, defaults to one chunk per rune
+	Chunks []string
+	// ToolCalls are the tool calls of the assistant message, sent in the last chunk in stream mode
+	ToolCalls []zhipu.ChatCompletionToolCall
+	// FinishReason defaults to "tool_calls" if there are tool calls, otherwise "stop"
+	FinishReason string
+	// Usage defaults to one token per rune
+	Usage *zhipu.ChatCompletionUsage
+	// WebSearch is returned as is
+	WebSearch []zhipu.ChatCompletionWebSearch
+
+	// Status and Error make the request fail, Status defaults to 400
+	Status int
+	Error  *zhipu.APIError
+}
+
+// AddChatCompletionReply queues replies of the chat completion endpoint,
+// when the queue is empty, the server echoes the last message
+func (s *Server) AddChatCompletionReply(replies ...ChatCompletionReply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chatReplies = append(s.chatReplies, replies...)
+}
+
+// chatCompletionRequest is the subset of the chat completion request the server inspects
+type chatCompletionRequest struct {
+	Model    string            `json:"model"`
+	Stream   bool              `json:"stream"`
+	Messages []json.RawMessage `json:"messages"`
+}
+
+// messageText extracts the text of a message, either plain or multi content
+func messageText(raw json.RawMessage) string {
+	var m struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(raw, &m) != nil {
+		return ""
+	}
+	var text string
+	if json.Unmarshal(m.Content, &text) == nil {
+		return text
+	}
+	var parts []zhipu.ChatCompletionMultiContent
+	_ = json.Unmarshal(m.Content, &parts)
+	for _, p := range parts {
+		text += p.Text
+	}
+	return text
+}
+
+func countRunes(s string) int64 {
+	return int64(len([]rune(s)))
+}
+
+// nextChatCompletionReply pops a scripted reply or builds an echo reply
+func (s *Server) nextChatCompletionReply(req chatCompletionRequest) (reply ChatCompletionReply) {
+	s.mu.Lock()
+	if len(s.chatReplies) != 0 {
+		reply = s.chatReplies[0]
+		s.chatReplies = s.chatReplies[1:]
+	} else if len(req.Messages) != 0 {
+		reply.Content = messageText(req.Messages[len(req.Messages)-1])
+	}
+	s.mu.Unlock()
+
+	if reply.FinishReason == "" {
+		if len(reply.ToolCalls) != 0 {
+			reply.FinishReason = zhipu.FinishReasonToolCalls
+		} else {
+			reply.FinishReason = zhipu.FinishReasonStop
+		}
+	}
+	if reply.Usage == nil {
+		var prompt int64
+		for _, m := range req.Messages {
+			prompt += countRunes(messageText(m))
+		}
+		completion := countRunes(reply.Content)
+		reply.Usage = &zhipu.ChatCompletionUsage{
+			PromptTokens:     prompt,
+			CompletionTokens: completion,
+			TotalTokens:      prompt + completion,
+		}
+	}
+	if len(reply.Chunks) == 0 {
+		for _, r := range reply.Content {
+			reply.Chunks = append(reply.Chunks, string(r))
+		}
+	}
+	return
+}
+
+// chatCompletion builds the non-stream response of a reply
+func (s *Server) chatCompletion(req chatCompletionRequest, reply ChatCompletionReply) zhipu.M {
+	s.mu.Lock()
+	id := s.nextID("chatcmpl")
+	s.mu.Unlock()
+
+	message := zhipu.M{"role": zhipu.RoleAssistant, "content": reply.Content}
+	if len(reply.ToolCalls) != 0 {
+		message["tool_calls"] = reply.ToolCalls
+	}
+	out := zhipu.M{
+		"id":      id,
+		"created": time.Now().Unix(),
+		"model":   req.Model,
+		"choices": []zhipu.M{{"index": 0, "finish_reason": reply.FinishReason, "message": message}},
+		"usage":   reply.Usage,
+	}
+	if len(reply.WebSearch) != 0 {
+		out["web_search"] = reply.WebSearch
+	}
+	return out
+}
+
+func (s *Server) handleChatCompletions(rw http.ResponseWriter, r *http.Request) {
+	var req chatCompletionRequest
+	if !readJSON(r, &req) {
+		writeError(rw, http.StatusBadRequest, zhipu.APIError{Code: "1214", Message: "invalid request body"})
+		return
+	}
+
+	reply := s.nextChatCompletionReply(req)
+
+	if reply.Error != nil {
+		status := reply.Status
+		if status == 0 {
+			status = http.StatusBadRequest
+		}
+		writeError(rw, status, *reply.Error)
+		return
+	}
+
+	if !req.Stream {
+		writeJSON(rw, http.StatusOK, s.chatCompletion(req, reply))
+		return
+	}
+
+	s.mu.Lock()
+	id := s.nextID("chatcmpl")
+	s.mu.Unlock()
+
+	created := time.Now().Unix()
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.WriteHeader(http.StatusOK)
+
+	flusher, _ := rw.(http.Flusher)
+
+	writeChunk := func(chunk zhipu.M) {
+		buf, _ := json.Marshal(chunk)
+		_, _ = rw.Write([]byte("data: "))
+		_, _ = rw.Write(buf)
+		_, _ = rw.Write([]byte("\n\n"))
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for _, content := range reply.Chunks {
+		writeChunk(zhipu.M{
+			"id":      id,
+			"created": created,
+			"model":   req.Model,
+			"choices": []zhipu.M{{"index": 0, "delta": zhipu.M{"role": zhipu.RoleAssistant, "content": content}}},
+		})
+	}
+
+	last := zhipu.M{"role": zhipu.RoleAssistant}
+	if len(reply.ToolCalls) != 0 {
+		last["tool_calls"] = reply.ToolCalls
+	}
+	final := zhipu.M{
+		"id":      id,
+		"created": created,
+		"model":   req.Model,
+		"choices": []zhipu.M{{"index": 0, "finish_reason": reply.FinishReason, "delta": last}},
+		"usage":   reply.Usage,
+	}
+	if len(reply.WebSearch) != 0 {
+		final["web_search"] = reply.WebSearch
+	}
+	writeChunk(final)
+
+	_, _ = rw.Write([]byte("data: [DONE]\n\n"))
+}
+
+// Embedding returns the deterministic embedding the server generates for the input
+func (s *Server) Embedding(input string) []float64 {
+	out := make([]float64, s.opts.embeddingDimensions)
+	var norm float64
+	for i := range out {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte{byte(i)})
+		_, _ = h.Write([]byte(input))
+		out[i] = float64(h.Sum64()%2000)/1000 - 1
+		norm += out[i] * out[i]
+	}
+	norm = math.Sqrt(norm)
+	for i := range out {
+		if norm != 0 {
+			out[i] /= norm
+		}
+	}
+	return out
+}
+
+func (s *Server) handleEmbeddings(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+		Input string `json:"input"`
+	}
+	if !readJSON(r, &req) {
+		writeError(rw, http.StatusBadRequest, zhipu.APIError{Code: "1214", Message: "invalid request body"})
+		return
+	}
+	tokens := countRunes(req.Input)
+	writeJSON(rw, http.StatusOK, zhipu.EmbeddingResponse{
+		Model:  req.Model,
+		Object: "list",
+		Data:   []zhipu.EmbeddingData{{Embedding: s.Embedding(req.Input), Index: 0, Object: "embedding"}},
+		Usage:  zhipu.ChatCompletionUsage{PromptTokens: tokens, TotalTokens: tokens},
+	})
+}
+
+func (s *Server) handleImageGenerations(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	id := s.nextID("image")
+	s.mu.Unlock()
+	writeJSON(rw, http.StatusOK, zhipu.ImageGenerationResponse{
+		Created: time.Now().Unix(),
+		Data:    []zhipu.URLItem{{URL: s.URL + "/_mock/" + id + ".png"}},
+	})
+}
+
+func (s *Server) routeChat(mux *http.ServeMux) {
+	mux.HandleFunc("POST /chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("POST /embeddings", s.handleEmbeddings)
+	mux.HandleFunc("POST /images/generations", s.handleImageGenerations)
+}
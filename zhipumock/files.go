@@ -0,0 +1,233 @@
+package zhipumock
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yankeguo/zhipu"
+)
+
+type file struct {
+	id          string
+	purpose     string
+	filename    string
+	content     []byte
+	createdAt   int64
+	knowledgeID string
+	document    zhipu.FileListKnowledgeItem
+}
+
+// AddFile stores a file as if it was uploaded, and returns its id
+func (s *Server) AddFile(purpose, filename string, content []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addFile(purpose, filename, content, "").id
+}
+
+// FileContent returns the content of a stored file
+func (s *Server) FileContent(id string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[id]
+	if !ok {
+		return nil, false
+	}
+	return f.content, true
+}
+
+func (s *Server) addFile(purpose, filename string, content []byte, knowledgeID string) *file {
+	f := &file{
+		id:          s.nextID("file"),
+		purpose:     purpose,
+		filename:    filename,
+		content:     content,
+		createdAt:   time.Now().Unix(),
+		knowledgeID: knowledgeID,
+	}
+	f.document = zhipu.FileListKnowledgeItem{
+		ID:           f.id,
+		Name:         filename,
+		URL:          s.URL + "/files/" + f.id + "/content",
+		Length:       int64(len(content)),
+		WordNum:      countRunes(string(content)),
+		SentenceSize: 300,
+	}
+	s.files[f.id] = f
+	s.fileOrder = append(s.fileOrder, f.id)
+	if kb, ok := s.knowledge[knowledgeID]; ok {
+		kb.DocumentSize++
+		kb.Length += f.document.Length
+		kb.WordNum += f.document.WordNum
+	}
+	return f
+}
+
+func (s *Server) handleFileCreate(rw http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(rw, http.StatusBadRequest, zhipu.APIError{Code: "1214", Message: err.Error()})
+		return
+	}
+	mf, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, zhipu.APIError{Code: "1214", Message: "file is required"})
+		return
+	}
+	defer mf.Close()
+	content, _ := io.ReadAll(mf)
+
+	purpose := r.FormValue("purpose")
+	knowledgeID := r.FormValue("knowledge_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if purpose == zhipu.FilePurposeRetrieval {
+		var res zhipu.FileCreateKnowledgeResponse
+		if _, ok := s.knowledge[knowledgeID]; !ok {
+			res.FailedInfos = append(res.FailedInfos, zhipu.FileCreateKnowledgeFailedInfo{Filename: header.Filename, FailReason: "knowledge not found"})
+		} else {
+			f := s.addFile(purpose, header.Filename, content, knowledgeID)
+			if v, err := strconv.Atoi(r.FormValue("sentence_size")); err == nil {
+				f.document.SentenceSize = int64(v)
+			}
+			if v := r.FormValue("custom_separator"); v != "" {
+				f.document.CustomSeparator = []string{v}
+			}
+			res.SuccessInfos = append(res.SuccessInfos, zhipu.FileCreateKnowledgeSuccessInfo{Filename: header.Filename, DocumentID: f.id})
+		}
+		writeJSON(rw, http.StatusOK, res)
+		return
+	}
+
+	f := s.addFile(purpose, header.Filename, content, "")
+	writeJSON(rw, http.StatusOK, zhipu.FileCreateFineTuneResponse{
+		Bytes:     int64(len(content)),
+		CreatedAt: f.createdAt,
+		Filename:  f.filename,
+		Object:    "file",
+		Purpose:   f.purpose,
+		ID:        f.id,
+	})
+}
+
+func (s *Server) handleFileList(rw http.ResponseWriter, r *http.Request) {
+	purpose := r.URL.Query().Get("purpose")
+	knowledgeID := r.URL.Query().Get("knowledge_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if purpose == zhipu.FilePurposeRetrieval {
+		res := zhipu.FileListKnowledgeResponse{List: []zhipu.FileListKnowledgeItem{}}
+		for _, id := range s.fileOrder {
+			if f := s.files[id]; f.purpose == purpose && f.knowledgeID == knowledgeID {
+				res.List = append(res.List, f.document)
+			}
+		}
+		res.Total = len(res.List)
+		writeJSON(rw, http.StatusOK, res)
+		return
+	}
+
+	res := zhipu.FileListFineTuneResponse{Object: "list", Data: []zhipu.FileListFineTuneItem{}}
+	for _, id := range s.fileOrder {
+		if f := s.files[id]; purpose == "" || f.purpose == purpose {
+			res.Data = append(res.Data, zhipu.FileListFineTuneItem{
+				Bytes:     int64(len(f.content)),
+				CreatedAt: f.createdAt,
+				Filename:  f.filename,
+				ID:        f.id,
+				Object:    "file",
+				Purpose:   f.purpose,
+			})
+		}
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleFileDelete(rw http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("file_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[id]
+	if !ok {
+		writeNotFound(rw, "file")
+		return
+	}
+	if kb, ok := s.knowledge[f.knowledgeID]; ok {
+		kb.DocumentSize--
+		kb.Length -= f.document.Length
+		kb.WordNum -= f.document.WordNum
+	}
+	delete(s.files, id)
+	for i, item := range s.fileOrder {
+		if item == id {
+			s.fileOrder = append(s.fileOrder[:i], s.fileOrder[i+1:]...)
+			break
+		}
+	}
+	writeJSON(rw, http.StatusOK, zhipu.M{"id": id, "object": "file", "deleted": true})
+}
+
+func (s *Server) handleFileContent(rw http.ResponseWriter, r *http.Request) {
+	content, ok := s.FileContent(r.PathValue("file_id"))
+	if !ok {
+		writeNotFound(rw, "file")
+		return
+	}
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = rw.Write(content)
+}
+
+func (s *Server) handleDocumentGet(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	f, ok := s.files[r.PathValue("document_id")]
+	var doc zhipu.FileListKnowledgeItem
+	if ok {
+		doc = f.document
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeNotFound(rw, "document")
+		return
+	}
+	writeJSON(rw, http.StatusOK, doc)
+}
+
+func (s *Server) handleDocumentEdit(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CustomSeparator []string `json:"custom_separator"`
+		SentenceSize    string   `json:"sentence_size"`
+	}
+	_ = readJSON(r, &req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.files[r.PathValue("document_id")]
+	if !ok {
+		writeNotFound(rw, "document")
+		return
+	}
+	if len(req.CustomSeparator) != 0 {
+		f.document.CustomSeparator = req.CustomSeparator
+	}
+	if v, err := strconv.Atoi(req.SentenceSize); err == nil {
+		f.document.SentenceSize = int64(v)
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) routeFiles(mux *http.ServeMux) {
+	mux.HandleFunc("POST /files", s.handleFileCreate)
+	mux.HandleFunc("GET /files", s.handleFileList)
+	mux.HandleFunc("DELETE /files/{file_id}", s.handleFileDelete)
+	mux.HandleFunc("GET /files/{file_id}/content", s.handleFileContent)
+	mux.HandleFunc("GET /document/{document_id}", s.handleDocumentGet)
+	mux.HandleFunc("PUT /document/{document_id}", s.handleDocumentEdit)
+}
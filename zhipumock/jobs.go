@@ -0,0 +1,419 @@
+package zhipumock
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yankeguo/zhipu"
+)
+
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusCompleted  = "completed"
+	BatchStatusFailed     = "failed"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// SetFineTuneJob overrides a fine tune job, e.g. to move it to a terminal status
+func (s *Server) SetFineTuneJob(item zhipu.FineTuneItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[item.ID]; !ok {
+		s.jobOrder = append(s.jobOrder, item.ID)
+	}
+	s.jobs[item.ID] = &item
+}
+
+// AddFineTuneEvents appends events to a fine tune job, empty ids and timestamps are generated
+func (s *Server) AddFineTuneEvents(jobID string, events ...zhipu.FineTuneEventItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range events {
+		if e.ID == "" {
+			e.ID = s.nextID("ftevent")
+		}
+		if e.CreatedAt == 0 {
+			e.CreatedAt = time.Now().Unix()
+		}
+		if e.Object == "" {
+			e.Object = "fine_tuning.job.event"
+		}
+		s.events[jobID] = append(s.events[jobID], e)
+	}
+}
+
+// fineTuneCreateRequest is the request of the fine tune create endpoint
+type fineTuneCreateRequest struct {
+	Model                string                              `json:"model"`
+	TrainingFile         string                              `json:"training_file"`
+	ValidationFile       string                              `json:"validation_file"`
+	RequestID            string                              `json:"request_id"`
+	Hyperparameters      zhipu.FineTuneHyperparameters       `json:"hyperparameters"`
+	Method               *zhipu.FineTuneMethod               `json:"method"`
+	ExtraHyperparameters *zhipu.FineTuneExtraHyperparameters `json:"extra_hyperparameters"`
+}
+
+func (s *Server) handleFineTuneCreate(rw http.ResponseWriter, r *http.Request) {
+	var req fineTuneCreateRequest
+	if !readJSON(r, &req) || req.Model == "" || req.TrainingFile == "" {
+		writeError(rw, http.StatusBadRequest, zhipu.APIError{Code: "1214", Message: "model and training_file are required"})
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.files[req.TrainingFile]; !ok {
+		s.mu.Unlock()
+		writeNotFound(rw, "training_file")
+		return
+	}
+	item := &zhipu.FineTuneItem{
+		ID:                   s.nextID("ftjob"),
+		RequestID:            req.RequestID,
+		Model:                req.Model,
+		Status:               zhipu.FineTuneStatusQueued,
+		Object:               "fine_tuning.job",
+		TrainingFile:         req.TrainingFile,
+		ValidationFile:       req.ValidationFile,
+		CreatedAt:            time.Now().Unix(),
+		Hyperparameters:      req.Hyperparameters,
+		Method:               req.Method,
+		ExtraHyperparameters: req.ExtraHyperparameters,
+	}
+	s.jobs[item.ID] = item
+	s.jobOrder = append(s.jobOrder, item.ID)
+	res := *item
+	s.mu.Unlock()
+
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleFineTuneList(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := zhipu.FineTuneListResponse{Object: "list", Data: []zhipu.FineTuneItem{}}
+	for _, id := range s.jobOrder {
+		res.Data = append(res.Data, *s.jobs[id])
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleFineTuneGet(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	item, ok := s.jobs[r.PathValue("job_id")]
+	var res zhipu.FineTuneItem
+	if ok {
+		res = *item
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeNotFound(rw, "job")
+		return
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+// handleFineTuneEvents lists events in chronological order, "after" is the id of the last event already seen
+func (s *Server) handleFineTuneEvents(rw http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	after := r.URL.Query().Get("after")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[r.PathValue("job_id")]; !ok {
+		writeNotFound(rw, "job")
+		return
+	}
+
+	events := s.events[r.PathValue("job_id")]
+	if after != "" {
+		for i, e := range events {
+			if e.ID == after {
+				events = events[i+1:]
+				break
+			}
+		}
+	}
+
+	res := zhipu.FineTuneEventListResponse{Object: "list", Data: []zhipu.FineTuneEventItem{}}
+	if len(events) > limit {
+		events, res.HasMore = events[:limit], true
+	}
+	res.Data = append(res.Data, events...)
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleFineTuneCancel(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	item, ok := s.jobs[r.PathValue("job_id")]
+	var res zhipu.FineTuneItem
+	if ok {
+		if !item.Status.IsTerminal() {
+			item.Status = zhipu.FineTuneStatusCancelled
+		}
+		res = *item
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeNotFound(rw, "job")
+		return
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleFineTuneDelete(rw http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("job_id")
+
+	s.mu.Lock()
+	item, ok := s.jobs[id]
+	var res zhipu.FineTuneItem
+	if ok {
+		res = *item
+		delete(s.jobs, id)
+		delete(s.events, id)
+		for i, v := range s.jobOrder {
+			if v == id {
+				s.jobOrder = append(s.jobOrder[:i], s.jobOrder[i+1:]...)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeNotFound(rw, "job")
+		return
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+// SetBatch overrides a batch, e.g. to move it to another status
+func (s *Server) SetBatch(item zhipu.BatchItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.batches[item.ID]; !ok {
+		s.batchOrder = append(s.batchOrder, item.ID)
+	}
+	s.batches[item.ID] = &item
+}
+
+// batchRequest is a line of the batch input file
+type batchRequest struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// runBatch executes the lines of a batch input file against the emulated endpoints
+func (s *Server) runBatch(batchID string, input []byte) (output []byte, counts zhipu.BatchRequestCounts) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+
+	sc := bufio.NewScanner(bytes.NewReader(input))
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		counts.Total++
+
+		var (
+			req    batchRequest
+			status = http.StatusOK
+			body   any
+		)
+		if err := json.Unmarshal(line, &req); err != nil {
+			status, body = http.StatusBadRequest, zhipu.APIErrorResponse{APIError: zhipu.APIError{Code: "1214", Message: err.Error()}}
+		} else {
+			switch req.URL {
+			case zhipu.BatchEndpointV4ChatCompletions:
+				var creq chatCompletionRequest
+				_ = json.Unmarshal(req.Body, &creq)
+				reply := s.nextChatCompletionReply(creq)
+				if reply.Error != nil {
+					status, body = http.StatusBadRequest, zhipu.APIErrorResponse{APIError: *reply.Error}
+				} else {
+					body = s.chatCompletion(creq, reply)
+				}
+			case zhipu.BatchEndpointV4Embeddings:
+				var ereq struct {
+					Model string `json:"model"`
+					Input string `json:"input"`
+				}
+				_ = json.Unmarshal(req.Body, &ereq)
+				tokens := countRunes(ereq.Input)
+				body = zhipu.EmbeddingResponse{
+					Model:  ereq.Model,
+					Object: "list",
+					Data:   []zhipu.EmbeddingData{{Embedding: s.Embedding(ereq.Input), Object: "embedding"}},
+					Usage:  zhipu.ChatCompletionUsage{PromptTokens: tokens, TotalTokens: tokens},
+				}
+			default:
+				status, body = http.StatusBadRequest, zhipu.APIErrorResponse{APIError: zhipu.APIError{Code: "1214", Message: "unsupported url: " + req.URL}}
+			}
+		}
+
+		if status == http.StatusOK {
+			counts.Completed++
+		} else {
+			counts.Failed++
+		}
+
+		_ = enc.Encode(zhipu.M{
+			"id":        batchID,
+			"custom_id": req.CustomID,
+			"response":  zhipu.M{"status_code": status, "body": body},
+		})
+	}
+	output = buf.Bytes()
+	return
+}
+
+// handleBatchCreate runs the batch immediately, the batch is completed once created
+func (s *Server) handleBatchCreate(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		InputFileID      string          `json:"input_file_id"`
+		Endpoint         string          `json:"endpoint"`
+		CompletionWindow string          `json:"completion_window"`
+		Metadata         json.RawMessage `json:"metadata"`
+	}
+	if !readJSON(r, &req) {
+		writeError(rw, http.StatusBadRequest, zhipu.APIError{Code: "1214", Message: "invalid request body"})
+		return
+	}
+
+	input, ok := s.FileContent(req.InputFileID)
+	if !ok {
+		writeNotFound(rw, "input_file")
+		return
+	}
+
+	s.mu.Lock()
+	id := s.nextID("batch")
+	s.mu.Unlock()
+
+	output, counts := s.runBatch(id, input)
+
+	now := time.Now().Unix()
+
+	s.mu.Lock()
+	item := &zhipu.BatchItem{
+		ID:               id,
+		Object:           "batch",
+		Endpoint:         req.Endpoint,
+		InputFileID:      req.InputFileID,
+		CompletionWindow: req.CompletionWindow,
+		Status:           BatchStatusCompleted,
+		OutputFileID:     s.addFile(zhipu.FilePurposeBatch, id+"_output.jsonl", output, "").id,
+		CreatedAt:        now,
+		InProgressAt:     now,
+		FinalizingAt:     now,
+		CompletedAt:      now,
+		ExpiresAt:        now + int64((24 * time.Hour).Seconds()),
+		RequestCounts:    counts,
+		Metadata:         req.Metadata,
+	}
+	s.batches[id] = item
+	s.batchOrder = append(s.batchOrder, id)
+	res := *item
+	s.mu.Unlock()
+
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleBatchGet(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	item, ok := s.batches[r.PathValue("batch_id")]
+	var res zhipu.BatchItem
+	if ok {
+		res = *item
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeNotFound(rw, "batch")
+		return
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleBatchCancel(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	item, ok := s.batches[r.PathValue("batch_id")]
+	var res zhipu.BatchItem
+	if ok {
+		if item.Status != BatchStatusCompleted && item.Status != BatchStatusFailed {
+			item.Status = BatchStatusCancelled
+			item.CancelledAt = time.Now().Unix()
+		}
+		res = *item
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeNotFound(rw, "batch")
+		return
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleBatchList(rw http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	after := r.URL.Query().Get("after")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.batchOrder
+	if after != "" {
+		for i, id := range ids {
+			if id == after {
+				ids = ids[i+1:]
+				break
+			}
+		}
+	}
+
+	res := zhipu.BatchListResponse{Object: "list", Data: []zhipu.BatchItem{}}
+	if len(ids) > limit {
+		ids, res.HasMore = ids[:limit], true
+	}
+	for _, id := range ids {
+		res.Data = append(res.Data, *s.batches[id])
+	}
+	if len(ids) != 0 {
+		res.FirstID, res.LastID = ids[0], ids[len(ids)-1]
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) routeFineTune(mux *http.ServeMux) {
+	mux.HandleFunc("POST /fine_tuning/jobs", s.handleFineTuneCreate)
+	mux.HandleFunc("GET /fine_tuning/jobs", s.handleFineTuneList)
+	mux.HandleFunc("GET /fine_tuning/jobs/{job_id}", s.handleFineTuneGet)
+	mux.HandleFunc("GET /fine_tuning/jobs/{job_id}/events", s.handleFineTuneEvents)
+	mux.HandleFunc("POST /fine_tuning/jobs/{job_id}/cancel", s.handleFineTuneCancel)
+	mux.HandleFunc("DELETE /fine_tuning/jobs/{job_id}", s.handleFineTuneDelete)
+}
+
+func (s *Server) routeBatch(mux *http.ServeMux) {
+	mux.HandleFunc("POST /batches", s.handleBatchCreate)
+	mux.HandleFunc("GET /batches", s.handleBatchList)
+	mux.HandleFunc("GET /batches/{batch_id}", s.handleBatchGet)
+	mux.HandleFunc("POST /batches/{batch_id}/cancel", s.handleBatchCancel)
+}
@@ -0,0 +1,129 @@
+package zhipumock
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/yankeguo/zhipu"
+)
+
+const (
+	knowledgeCapacityWordNum = 100_000_000
+	knowledgeCapacityLength  = 10 << 30
+)
+
+func (s *Server) handleKnowledgeCreate(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		EmbeddingID int    `json:"embedding_id"`
+	}
+	if !readJSON(r, &req) || req.Name == "" {
+		writeError(rw, http.StatusBadRequest, zhipu.APIError{Code: "1214", Message: "name is required"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID("knowledge")
+	s.knowledge[id] = &zhipu.KnowledgeItem{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		EmbeddingID: req.EmbeddingID,
+	}
+	// newest first, the same as the platform
+	s.kbOrder = append([]string{id}, s.kbOrder...)
+	writeJSON(rw, http.StatusOK, zhipu.KnowledgeCreateResponse{ID: id})
+}
+
+func (s *Server) handleKnowledgeEdit(rw http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		EmbeddingID *int    `json:"embedding_id"`
+	}
+	_ = readJSON(r, &req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kb, ok := s.knowledge[r.PathValue("knowledge_id")]
+	if !ok {
+		writeNotFound(rw, "knowledge")
+		return
+	}
+	if req.Name != nil {
+		kb.Name = *req.Name
+	}
+	if req.Description != nil {
+		kb.Description = *req.Description
+	}
+	if req.EmbeddingID != nil {
+		kb.EmbeddingID = *req.EmbeddingID
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleKnowledgeList(rw http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 10
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := zhipu.KnowledgeListResponse{List: []zhipu.KnowledgeItem{}, Total: len(s.kbOrder)}
+	for i := (page - 1) * size; i < page*size && i < len(s.kbOrder); i++ {
+		res.List = append(res.List, *s.knowledge[s.kbOrder[i]])
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) handleKnowledgeDelete(rw http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("knowledge_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.knowledge[id]; !ok {
+		writeNotFound(rw, "knowledge")
+		return
+	}
+	delete(s.knowledge, id)
+	for i, item := range s.kbOrder {
+		if item == id {
+			s.kbOrder = append(s.kbOrder[:i], s.kbOrder[i+1:]...)
+			break
+		}
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleKnowledgeCapacity(rw http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := zhipu.KnowledgeCapacityResponse{
+		Total: zhipu.KnowledgeCapacityItem{WordNum: knowledgeCapacityWordNum, Length: knowledgeCapacityLength},
+	}
+	for _, kb := range s.knowledge {
+		res.Used.WordNum += kb.WordNum
+		res.Used.Length += kb.Length
+	}
+	writeJSON(rw, http.StatusOK, res)
+}
+
+func (s *Server) routeKnowledge(mux *http.ServeMux) {
+	mux.HandleFunc("POST /knowledge", s.handleKnowledgeCreate)
+	mux.HandleFunc("GET /knowledge", s.handleKnowledgeList)
+	mux.HandleFunc("GET /knowledge/capacity", s.handleKnowledgeCapacity)
+	mux.HandleFunc("PUT /knowledge/{knowledge_id}", s.handleKnowledgeEdit)
+	mux.HandleFunc("DELETE /knowledge/{knowledge_id}", s.handleKnowledgeDelete)
+}
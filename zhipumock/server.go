@@ -0,0 +1,309 @@
+// Package zhipumock provides an in-process server emulating the Zhipu AI platform for offline tests.
+//
+// Example:
+//
+//	s := zhipumock.NewServer()
+//	defer s.Close()
+//
+//	client, _ := zhipu.NewClient(s.ClientOptions()...)
+//	s.AddChatCompletionReply(zhipumock.ChatCompletionReply{Content: "你好"})
+//	res, _ := client.ChatCompletion("glm-4-flash").AddMessage(...).Do(ctx)
+package zhipumock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yankeguo/zhipu"
+)
+
+const (
+	// DefaultAPIKey is the api key accepted by the server unless overridden with WithAPIKey
+	DefaultAPIKey = "zhipumock.secret"
+)
+
+// Request is a request recorded by the server
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+	// KeyID is the api key id carried by the jwt token
+	KeyID string
+	Time  time.Time
+}
+
+// DecodeJSON decodes the body of the request
+func (r Request) DecodeJSON(out any) error {
+	return json.Unmarshal(r.Body, out)
+}
+
+type options struct {
+	apiKey              string
+	embeddingDimensions int
+	asyncPolls          int
+	skipAuth            bool
+}
+
+// Option configures the server
+type Option func(opts *options)
+
+// WithAPIKey sets the api key accepted by the server
+func WithAPIKey(apiKey string) Option {
+	return func(opts *options) {
+		opts.apiKey = apiKey
+	}
+}
+
+// WithEmbeddingDimensions sets the dimensions of the generated embeddings, default to 8
+func WithEmbeddingDimensions(n int) Option {
+	return func(opts *options) {
+		opts.embeddingDimensions = n
+	}
+}
+
+// WithAsyncPolls sets how many times an async task reports processing before it succeeds, default to 0
+func WithAsyncPolls(n int) Option {
+	return func(opts *options) {
+		opts.asyncPolls = n
+	}
+}
+
+// WithoutAuth disables the jwt verification
+func WithoutAuth() Option {
+	return func(opts *options) {
+		opts.skipAuth = true
+	}
+}
+
+type injectedError struct {
+	status int
+	err    zhipu.APIError
+}
+
+// Server is a mock of the Zhipu AI platform
+type Server struct {
+	*httptest.Server
+
+	opts      options
+	keyID     string
+	keySecret []byte
+
+	mu sync.Mutex
+
+	seq      int64
+	requests []Request
+	handlers map[string]http.HandlerFunc
+	errors   map[string][]injectedError
+
+	chatReplies []ChatCompletionReply
+	files       map[string]*file
+	fileOrder   []string
+	knowledge   map[string]*zhipu.KnowledgeItem
+	kbOrder     []string
+	jobs        map[string]*zhipu.FineTuneItem
+	jobOrder    []string
+	events      map[string][]zhipu.FineTuneEventItem
+	batches     map[string]*zhipu.BatchItem
+	batchOrder  []string
+	tasks       map[string]*asyncTask
+}
+
+// NewServer creates and starts a new mock server
+func NewServer(optFns ...Option) *Server {
+	opts := options{
+		apiKey:              DefaultAPIKey,
+		embeddingDimensions: 8,
+	}
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	s := &Server{
+		opts:      opts,
+		handlers:  map[string]http.HandlerFunc{},
+		errors:    map[string][]injectedError{},
+		files:     map[string]*file{},
+		knowledge: map[string]*zhipu.KnowledgeItem{},
+		jobs:      map[string]*zhipu.FineTuneItem{},
+		events:    map[string][]zhipu.FineTuneEventItem{},
+		batches:   map[string]*zhipu.BatchItem{},
+		tasks:     map[string]*asyncTask{},
+	}
+	if id, secret, ok := strings.Cut(opts.apiKey, "."); ok {
+		s.keyID, s.keySecret = id, []byte(secret)
+	}
+
+	mux := http.NewServeMux()
+	s.routeChat(mux)
+	s.routeFiles(mux)
+	s.routeKnowledge(mux)
+	s.routeFineTune(mux)
+	s.routeBatch(mux)
+	s.routeAsync(mux)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// ClientOptions returns the options to connect a zhipu.Client to the server
+func (s *Server) ClientOptions() []zhipu.ClientOption {
+	return []zhipu.ClientOption{
+		zhipu.WithBaseURL(s.URL),
+		zhipu.WithAPIKey(s.opts.apiKey),
+	}
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// LastRequest returns the last request received, or false if there is none
+func (s *Server) LastRequest() (Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return Request{}, false
+	}
+	return s.requests[len(s.requests)-1], true
+}
+
+// Reset clears the recorded requests
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// Handle overrides the handler of a route, pattern is the same as http.ServeMux, e.g. "POST /chat/completions"
+func (s *Server) Handle(pattern string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[pattern] = handler
+}
+
+// InjectError makes the next request matching pattern fail with the status and api error
+func (s *Server) InjectError(pattern string, status int, err zhipu.APIError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[pattern] = append(s.errors[pattern], injectedError{status: status, err: err})
+}
+
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s-%d", prefix, s.seq)
+}
+
+// verify checks the jwt token created by zhipu.Client, or the plain api key, and returns the key id
+func (s *Server) verify(r *http.Request) (keyID string, err error) {
+	if r.Header.Get("Authorization") == "Bearer "+s.keyID+"."+string(s.keySecret) {
+		keyID = s.keyID
+		return
+	}
+
+	var token *jwt.Token
+	if token, err = jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	).Parse(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), func(t *jwt.Token) (any, error) {
+		return s.keySecret, nil
+	}); err != nil {
+		return
+	}
+	if token.Header["sign_type"] != "SIGN" {
+		err = fmt.Errorf("unexpected sign_type: %v", token.Header["sign_type"])
+		return
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	keyID, _ = claims["api_key"].(string)
+	if keyID != s.keyID {
+		err = fmt.Errorf("unexpected api_key: %s", keyID)
+		return
+	}
+	if _, ok := claims["timestamp"].(float64); !ok {
+		err = fmt.Errorf("missing timestamp")
+		return
+	}
+	// exp is in milliseconds, which the jwt library treats as seconds, check it explicitly
+	if exp, ok := claims["exp"].(float64); !ok || int64(exp) < time.Now().UnixMilli() {
+		err = fmt.Errorf("token expired")
+		return
+	}
+	return
+}
+
+func (s *Server) middleware(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		keyID, authErr := s.verify(r)
+		if s.opts.skipAuth {
+			authErr = nil
+		}
+
+		_, pattern := mux.Handler(r)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+			Body:   body,
+			KeyID:  keyID,
+			Time:   time.Now(),
+		})
+		var injected *injectedError
+		if queue := s.errors[pattern]; len(queue) != 0 {
+			injected = &queue[0]
+			s.errors[pattern] = queue[1:]
+		}
+		handler := s.handlers[pattern]
+		s.mu.Unlock()
+
+		if authErr != nil {
+			writeError(rw, http.StatusUnauthorized, zhipu.APIError{Code: "1002", Message: "Authorization Token非法，请确认Authorization Token正确传递。" + authErr.Error()})
+			return
+		}
+		if injected != nil {
+			writeError(rw, injected.status, injected.err)
+			return
+		}
+		if handler != nil {
+			handler(rw, r)
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	})
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(v)
+}
+
+func writeError(rw http.ResponseWriter, status int, err zhipu.APIError) {
+	writeJSON(rw, status, zhipu.APIErrorResponse{APIError: err})
+}
+
+func writeNotFound(rw http.ResponseWriter, what string) {
+	writeError(rw, http.StatusNotFound, zhipu.APIError{Code: "1001", Message: what + " not found"})
+}
+
+func readJSON(r *http.Request, out any) bool {
+	return json.NewDecoder(r.Body).Decode(out) == nil
+}
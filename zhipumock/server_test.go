@@ -0,0 +1,221 @@
+package zhipumock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yankeguo/zhipu"
+)
+
+func newTestClient(t *testing.T, opts ...Option) (*Server, *zhipu.Client) {
+	s := NewServer(opts...)
+	t.Cleanup(s.Close)
+	client, err := zhipu.NewClient(s.ClientOptions()...)
+	require.NoError(t, err)
+	return s, client
+}
+
+func TestServerChatCompletion(t *testing.T) {
+	s, client := newTestClient(t)
+
+	res, err := client.ChatCompletion("glm-4-flash").AddMessage(zhipu.ChatCompletionMessage{
+		Role: zhipu.RoleUser, Content: "你好呀",
+	}).Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "你好呀", res.Choices[0].Message.Content)
+	require.Equal(t, zhipu.FinishReasonStop, res.Choices[0].FinishReason)
+	require.Equal(t, int64(6), res.Usage.TotalTokens)
+
+	req, ok := s.LastRequest()
+	require.True(t, ok)
+	require.Equal(t, "/chat/completions", req.Path)
+	require.Equal(t, "zhipumock", req.KeyID)
+	var body map[string]any
+	require.NoError(t, req.DecodeJSON(&body))
+	require.Equal(t, "glm-4-flash", body["model"])
+}
+
+func TestServerChatCompletionStreamToolCalls(t *testing.T) {
+	s, client := newTestClient(t)
+
+	s.AddChatCompletionReply(ChatCompletionReply{
+		Content: "查询中",
+		Chunks:  []string{"查询", "中"},
+		ToolCalls: []zhipu.ChatCompletionToolCall{{
+			ID:   "call_1",
+			Type: zhipu.ToolTypeFunction,
+			Function: &zhipu.ChatCompletionToolCallFunction{
+				Name:      "get_weather",
+				Arguments: json.RawMessage(`"{\"city\":\"深圳\"}"`),
+			},
+		}},
+	})
+
+	var chunks []string
+	res, err := client.ChatCompletion("glm-4-flash").AddMessage(zhipu.ChatCompletionMessage{
+		Role: zhipu.RoleUser, Content: "深圳天气",
+	}).AddTool(zhipu.ChatCompletionToolFunction{
+		Name: "get_weather",
+	}).SetStreamHandler(func(chunk zhipu.ChatCompletionResponse) error {
+		chunks = append(chunks, chunk.Choices[0].Delta.Content)
+		return nil
+	}).Do(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"查询", "中", ""}, chunks)
+	require.Equal(t, "查询中", res.Choices[0].Message.Content)
+	require.Equal(t, zhipu.FinishReasonToolCalls, res.Choices[0].FinishReason)
+	require.Len(t, res.Choices[0].Message.ToolCalls, 1)
+	require.Equal(t, "get_weather", res.Choices[0].Message.ToolCalls[0].Function.Name)
+}
+
+func TestServerErrors(t *testing.T) {
+	s, client := newTestClient(t)
+
+	s.AddChatCompletionReply(ChatCompletionReply{Status: http.StatusTooManyRequests, Error: &zhipu.APIError{Code: "1302", Message: "rate limited"}})
+	_, err := client.ChatCompletion("glm-4-flash").AddMessage(zhipu.ChatCompletionMessage{Role: zhipu.RoleUser, Content: "hi"}).Do(context.Background())
+	require.Equal(t, "1302", zhipu.GetAPIErrorCode(err))
+
+	s.InjectError("POST /embeddings", http.StatusInternalServerError, zhipu.APIError{Code: "500", Message: "internal"})
+	_, err = client.Embedding("embedding-2").SetInput("hi").Do(context.Background())
+	require.Equal(t, "500", zhipu.GetAPIErrorCode(err))
+	_, err = client.Embedding("embedding-2").SetInput("hi").Do(context.Background())
+	require.NoError(t, err)
+
+	bad, err := zhipu.NewClient(zhipu.WithBaseURL(s.URL), zhipu.WithAPIKey("zhipumock.wrong"))
+	require.NoError(t, err)
+	_, err = bad.Embedding("embedding-2").SetInput("hi").Do(context.Background())
+	require.Equal(t, "1002", zhipu.GetAPIErrorCode(err))
+}
+
+func TestServerEmbedding(t *testing.T) {
+	s, client := newTestClient(t, WithEmbeddingDimensions(4))
+
+	res, err := client.Embedding("embedding-2").SetInput("你好").Do(context.Background())
+	require.NoError(t, err)
+	require.Len(t, res.Data[0].Embedding, 4)
+	require.Equal(t, s.Embedding("你好"), res.Data[0].Embedding)
+	require.NotEqual(t, s.Embedding("再见"), res.Data[0].Embedding)
+}
+
+func TestServerKnowledgeAndFiles(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx := context.Background()
+
+	kb, err := client.KnowledgeCreate().SetName("test").SetEmbeddingID(zhipu.KnowledgeEmbeddingIDEmbedding2).Do(ctx)
+	require.NoError(t, err)
+
+	res, err := client.FileCreate(zhipu.FilePurposeRetrieval).SetKnowledgeID(kb.ID).SetFile(bytes.NewReader([]byte("hello")), "a.txt").Do(ctx)
+	require.NoError(t, err)
+	require.Len(t, res.SuccessInfos, 1)
+
+	doc, err := client.FileGet(res.SuccessInfos[0].DocumentID).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a.txt", doc.Name)
+
+	list, err := client.KnowledgeList().Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), list.List[0].DocumentSize)
+
+	capacity, err := client.KnowledgeCapacity().Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), capacity.Used.Length)
+
+	require.NoError(t, client.FileDelete(doc.ID).Do(ctx))
+	require.NoError(t, client.KnowledgeDelete(kb.ID).Do(ctx))
+
+	list, err = client.KnowledgeList().Do(ctx)
+	require.NoError(t, err)
+	require.Empty(t, list.List)
+}
+
+func TestServerFineTune(t *testing.T) {
+	s, client := newTestClient(t)
+	ctx := context.Background()
+
+	file, err := client.FileCreate(zhipu.FilePurposeFineTune).SetFile(bytes.NewReader([]byte("{}\n")), "train.jsonl").Do(ctx)
+	require.NoError(t, err)
+
+	job, err := client.FineTuneCreate("chatglm3-6b").SetTrainingFile(file.ID).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, zhipu.FineTuneStatusQueued, job.Status)
+
+	s.AddFineTuneEvents(job.ID,
+		zhipu.FineTuneEventItem{Message: "started"},
+		zhipu.FineTuneEventItem{Message: "step", Data: zhipu.FineTuneEventData{Loss: 0.5}},
+		zhipu.FineTuneEventItem{Message: "done"},
+	)
+
+	events, err := client.FineTuneEventList(job.ID).SetLimit(2).Do(ctx)
+	require.NoError(t, err)
+	require.True(t, events.HasMore)
+	require.Len(t, events.Data, 2)
+
+	events, err = client.FineTuneEventList(job.ID).SetAfter(events.Data[1].ID).Do(ctx)
+	require.NoError(t, err)
+	require.False(t, events.HasMore)
+	require.Equal(t, "done", events.Data[0].Message)
+
+	job, err = client.FineTuneCancel(job.ID).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, zhipu.FineTuneStatusCancelled, job.Status)
+}
+
+func TestServerBatch(t *testing.T) {
+	_, client := newTestClient(t)
+	ctx := context.Background()
+
+	buf := &bytes.Buffer{}
+	w := zhipu.NewBatchFileWriter(buf)
+	require.NoError(t, w.Write("r1", client.ChatCompletion("glm-4-flash").AddMessage(zhipu.ChatCompletionMessage{Role: zhipu.RoleUser, Content: "a"})))
+	require.NoError(t, w.Write("r2", client.ChatCompletion("glm-4-flash").AddMessage(zhipu.ChatCompletionMessage{Role: zhipu.RoleUser, Content: "b"})))
+
+	file, err := client.FileCreate(zhipu.FilePurposeBatch).SetFile(buf, "batch.jsonl").Do(ctx)
+	require.NoError(t, err)
+
+	batch, err := client.BatchCreate().SetInputFileID(file.ID).SetEndpoint(zhipu.BatchEndpointV4ChatCompletions).SetCompletionWindow(zhipu.BatchCompletionWindow24h).Do(ctx)
+	require.NoError(t, err)
+
+	batch, err = client.BatchGet(batch.ID).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, BatchStatusCompleted, batch.Status)
+	require.Equal(t, int64(2), batch.RequestCounts.Completed)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, client.FileDownload(batch.OutputFileID).SetOutput(out).Do(ctx))
+
+	r := zhipu.NewBatchResultReader[zhipu.ChatCompletionResponse](out)
+	var contents []string
+	for {
+		var res zhipu.BatchResult[zhipu.ChatCompletionResponse]
+		if err := r.Read(&res); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		contents = append(contents, res.CustomID+":"+res.Response.Body.Choices[0].Message.Content)
+	}
+	require.Equal(t, []string{"r1:a", "r2:b"}, contents)
+}
+
+func TestServerVideoGeneration(t *testing.T) {
+	_, client := newTestClient(t, WithAsyncPolls(1))
+	ctx := context.Background()
+
+	task, err := client.VideoGeneration("cogvideox").SetPrompt("cat").Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, zhipu.VideoGenerationTaskStatusProcessing, task.TaskStatus)
+
+	res, err := client.AsyncResult(task.ID).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, zhipu.VideoGenerationTaskStatusProcessing, res.TaskStatus)
+
+	res, err = client.AsyncResult(task.ID).Do(ctx)
+	require.NoError(t, err)
+	require.Equal(t, zhipu.VideoGenerationTaskStatusSuccess, res.TaskStatus)
+	require.NotEmpty(t, res.VideoResult[0].URL)
+}
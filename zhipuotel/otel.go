@@ -0,0 +1,251 @@
+// Package zhipuotel instruments the zhipu client with OpenTelemetry, following the GenAI semantic conventions.
+//
+// Example:
+//
+//	client, err := zhipu.NewClient(zhipuotel.WithInstrumentation())
+package zhipuotel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yankeguo/zhipu"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ScopeName is the instrumentation scope name
+	ScopeName = "github.com/yankeguo/zhipu/zhipuotel"
+
+	// ProviderName is the value of the gen_ai.provider.name attribute
+	ProviderName = "zhipu"
+)
+
+const (
+	attrOperationName     = attribute.Key("gen_ai.operation.name")
+	attrProviderName      = attribute.Key("gen_ai.provider.name")
+	attrRequestModel      = attribute.Key("gen_ai.request.model")
+	attrRequestMaxTokens  = attribute.Key("gen_ai.request.max_tokens")
+	attrRequestTemp       = attribute.Key("gen_ai.request.temperature")
+	attrRequestTopP       = attribute.Key("gen_ai.request.top_p")
+	attrResponseID        = attribute.Key("gen_ai.response.id")
+	attrResponseModel     = attribute.Key("gen_ai.response.model")
+	attrFinishReasons     = attribute.Key("gen_ai.response.finish_reasons")
+	attrTimeToFirstChunk  = attribute.Key("gen_ai.response.time_to_first_chunk")
+	attrUsageInputTokens  = attribute.Key("gen_ai.usage.input_tokens")
+	attrUsageOutputTokens = attribute.Key("gen_ai.usage.output_tokens")
+	attrTokenType         = attribute.Key("gen_ai.token.type")
+	attrErrorType         = attribute.Key("error.type")
+)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// Option configures the instrumentation
+type Option func(opts *options)
+
+// WithTracerProvider sets the tracer provider, default to the global one
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(opts *options) {
+		opts.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider, default to the global one
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(opts *options) {
+		opts.meterProvider = provider
+	}
+}
+
+// instrumentation holds the tracer and instruments
+type instrumentation struct {
+	tracer trace.Tracer
+
+	duration         metric.Float64Histogram
+	tokens           metric.Int64Histogram
+	timeToFirstChunk metric.Float64Histogram
+	errors           metric.Int64Counter
+}
+
+// WithInstrumentation returns a client option creating a span and recording metrics for every service call
+func WithInstrumentation(fns ...Option) zhipu.ClientOption {
+	opts := options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, fn := range fns {
+		fn(&opts)
+	}
+
+	meter := opts.meterProvider.Meter(ScopeName)
+
+	ins := &instrumentation{tracer: opts.tracerProvider.Tracer(ScopeName)}
+
+	// instrument creation only fails on invalid names, the no-op instruments are returned anyway
+	ins.duration, _ = meter.Float64Histogram(
+		"gen_ai.client.operation.duration",
+		metric.WithDescription("Duration of GenAI operations"),
+		metric.WithUnit("s"),
+	)
+	ins.tokens, _ = meter.Int64Histogram(
+		"gen_ai.client.token.usage",
+		metric.WithDescription("Number of input and output tokens used"),
+		metric.WithUnit("{token}"),
+	)
+	ins.timeToFirstChunk, _ = meter.Float64Histogram(
+		"gen_ai.client.operation.time_to_first_chunk",
+		metric.WithDescription("Time to receive the first chunk of streams"),
+		metric.WithUnit("s"),
+	)
+	ins.errors, _ = meter.Int64Counter(
+		"gen_ai.client.operation.errors",
+		metric.WithDescription("Number of failed GenAI operations"),
+		metric.WithUnit("{error}"),
+	)
+
+	return zhipu.WithMiddleware(ins.middleware)
+}
+
+// operationName maps the service operation to the GenAI operation name
+func operationName(op *zhipu.Operation) string {
+	switch op.Name {
+	case "ChatCompletion":
+		return "chat"
+	case "Embedding":
+		return "embeddings"
+	case "ImageGeneration", "VideoGeneration":
+		return "generate_content"
+	default:
+		return op.Name
+	}
+}
+
+// requestAttributes extracts the request parameters from the body
+func requestAttributes(op *zhipu.Operation) (attrs []attribute.KeyValue) {
+	if v, ok := op.Body["max_tokens"].(int); ok {
+		attrs = append(attrs, attrRequestMaxTokens.Int(v))
+	}
+	if v, ok := op.Body["temperature"].(float64); ok {
+		attrs = append(attrs, attrRequestTemp.Float64(v))
+	}
+	if v, ok := op.Body["top_p"].(float64); ok {
+		attrs = append(attrs, attrRequestTopP.Float64(v))
+	}
+	return
+}
+
+// usage extracts the response attributes and token usage
+func usage(res any) (attrs []attribute.KeyValue, input, output int64, ok bool) {
+	switch res := res.(type) {
+	case zhipu.ChatCompletionResponse:
+		if res.ID != "" {
+			attrs = append(attrs, attrResponseID.String(res.ID))
+		}
+		if res.Model != "" {
+			attrs = append(attrs, attrResponseModel.String(res.Model))
+		}
+		var reasons []string
+		for _, choice := range res.Choices {
+			if choice.FinishReason != "" {
+				reasons = append(reasons, choice.FinishReason)
+			}
+		}
+		if len(reasons) != 0 {
+			attrs = append(attrs, attrFinishReasons.StringSlice(reasons))
+		}
+		return attrs, res.Usage.PromptTokens, res.Usage.CompletionTokens, true
+	case zhipu.EmbeddingResponse:
+		if res.Model != "" {
+			attrs = append(attrs, attrResponseModel.String(res.Model))
+		}
+		return attrs, res.Usage.PromptTokens, 0, true
+	}
+	return
+}
+
+// errorType returns the api error code, or a generic type
+func errorType(err error) string {
+	if code := zhipu.GetAPIErrorCode(err); code != "" {
+		return code
+	}
+	// resty and the sdk wrap the errors of the context
+	if errors.Is(err, context.Canceled) {
+		return context.Canceled.Error()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return context.DeadlineExceeded.Error()
+	}
+	return "_OTHER"
+}
+
+func (ins *instrumentation) middleware(next zhipu.Handler) zhipu.Handler {
+	return func(ctx context.Context, op *zhipu.Operation) (res any, err error) {
+		name := operationName(op)
+
+		common := []attribute.KeyValue{
+			attrOperationName.String(name),
+			attrProviderName.String(ProviderName),
+		}
+		if op.Model != "" {
+			common = append(common, attrRequestModel.String(op.Model))
+		}
+		// appending to common always copies
+		common = common[:len(common):len(common)]
+
+		spanName := name
+		if op.Model != "" {
+			spanName += " " + op.Model
+		}
+
+		ctx, span := ins.tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(common...),
+			trace.WithAttributes(requestAttributes(op)...),
+		)
+		defer span.End()
+
+		start := time.Now()
+
+		res, err = next(ctx, op)
+
+		elapsed := time.Since(start).Seconds()
+
+		if !op.FirstChunkAt.IsZero() {
+			ttfc := op.FirstChunkAt.Sub(start).Seconds()
+			span.SetAttributes(attrTimeToFirstChunk.Float64(ttfc))
+			ins.timeToFirstChunk.Record(ctx, ttfc, metric.WithAttributes(common...))
+		}
+
+		if err != nil {
+			typ := errorType(err)
+			span.SetAttributes(attrErrorType.String(typ))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+
+			attrs := append(common, attrErrorType.String(typ))
+			ins.duration.Record(ctx, elapsed, metric.WithAttributes(attrs...))
+			ins.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+			return
+		}
+
+		ins.duration.Record(ctx, elapsed, metric.WithAttributes(common...))
+
+		if attrs, input, output, ok := usage(res); ok {
+			span.SetAttributes(attrs...)
+			span.SetAttributes(attrUsageInputTokens.Int64(input), attrUsageOutputTokens.Int64(output))
+			ins.tokens.Record(ctx, input, metric.WithAttributes(append(common, attrTokenType.String("input"))...))
+			if output != 0 {
+				ins.tokens.Record(ctx, output, metric.WithAttributes(append(common, attrTokenType.String("output"))...))
+			}
+		}
+		return
+	}
+}
@@ -0,0 +1,100 @@
+package zhipuotel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/yankeguo/zhipu"
+	"github.com/yankeguo/zhipu/zhipumock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func attrs(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	out := map[attribute.Key]attribute.Value{}
+	for _, kv := range kvs {
+		out[kv.Key] = kv.Value
+	}
+	return out
+}
+
+func TestInstrumentation(t *testing.T) {
+	ctx := context.Background()
+
+	recorder := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	s := zhipumock.NewServer()
+	defer s.Close()
+
+	client, err := zhipu.NewClient(append(s.ClientOptions(), WithInstrumentation(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	))...)
+	require.NoError(t, err)
+
+	_, err = client.ChatCompletion("glm-4-flash").
+		SetTemperature(0.5).
+		AddMessage(zhipu.ChatCompletionMessage{Role: zhipu.RoleUser, Content: "你好"}).
+		SetStreamHandler(func(chunk zhipu.ChatCompletionResponse) error { return nil }).
+		Do(ctx)
+	require.NoError(t, err)
+
+	s.AddChatCompletionReply(zhipumock.ChatCompletionReply{Status: http.StatusTooManyRequests, Error: &zhipu.APIError{Code: "1302", Message: "rate limited"}})
+	_, err = client.ChatCompletion("glm-4-flash").AddMessage(zhipu.ChatCompletionMessage{Role: zhipu.RoleUser, Content: "你好"}).Do(ctx)
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "chat glm-4-flash", spans[0].Name())
+	a := attrs(spans[0].Attributes())
+	require.Equal(t, "chat", a[attrOperationName].AsString())
+	require.Equal(t, "zhipu", a[attrProviderName].AsString())
+	require.Equal(t, "glm-4-flash", a[attrRequestModel].AsString())
+	require.Equal(t, 0.5, a[attrRequestTemp].AsFloat64())
+	require.Equal(t, int64(2), a[attrUsageInputTokens].AsInt64())
+	require.Equal(t, int64(2), a[attrUsageOutputTokens].AsInt64())
+	require.Equal(t, []string{"stop"}, a[attrFinishReasons].AsStringSlice())
+	require.Greater(t, a[attrTimeToFirstChunk].AsFloat64(), 0.0)
+
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, "1302", attrs(spans[1].Attributes())[attrErrorType].AsString())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+
+	duration := metrics["gen_ai.client.operation.duration"].(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 2)
+
+	tokens := metrics["gen_ai.client.token.usage"].(metricdata.Histogram[int64])
+	require.Len(t, tokens.DataPoints, 2)
+	for _, dp := range tokens.DataPoints {
+		require.Equal(t, int64(2), dp.Sum)
+	}
+
+	errors := metrics["gen_ai.client.operation.errors"].(metricdata.Sum[int64])
+	require.Len(t, errors.DataPoints, 1)
+	require.Equal(t, int64(1), errors.DataPoints[0].Value)
+
+	ttfc := metrics["gen_ai.client.operation.time_to_first_chunk"].(metricdata.Histogram[float64])
+	require.Equal(t, uint64(1), ttfc.DataPoints[0].Count)
+}
+
+func TestErrorType(t *testing.T) {
+	require.Equal(t, "context canceled", errorType(fmt.Errorf("Post \"http://localhost\": %w", context.Canceled)))
+	require.Equal(t, "context deadline exceeded", errorType(fmt.Errorf("Post \"http://localhost\": %w", context.DeadlineExceeded)))
+	require.Equal(t, "_OTHER", errorType(errors.New("boom")))
+}